@@ -10,4 +10,8 @@ type App struct {
 	SyncData   controller.M2YSyncDataCmd   `cmd:"sync"   name:"sync"   help:"Sync data from MySQL to YashanDB."`
 	ExportDDLs controller.M2YExportDDLsCmd `cmd:"export" name:"export" help:"Export DDLs from MySQL."` // TODO: 暂时取名叫export;这个子命令名称有一些误导性，但是方便使用
 	CheckData  controller.M2YCheckDataCmd  `cmd:"check"  name:"check"  help:"Check data from MySQL to YashanDB."`
+	ExportData controller.M2YExportDataCmd `cmd:"export-data" name:"export-data" help:"Export table data from MySQL to compressed CSV files."`
+	LoadData   controller.M2YLoadDataCmd   `cmd:"load" name:"load" help:"Load exported data files into YashanDB."`
+	Replicate  controller.M2YReplicateCmd  `cmd:"replicate" name:"replicate" help:"Replicate binlog changes from MySQL to YashanDB after sync."`
+	Precheck   controller.M2YPrecheckCmd   `cmd:"precheck" name:"precheck" help:"Check whether MySQL data fits the YashanDB column types before migration."`
 }
@@ -3,6 +3,7 @@ package db
 import (
 	"database/sql"
 	"fmt"
+	"net/url"
 	"strings"
 
 	"m2y/defs/confdef"
@@ -16,6 +17,9 @@ const (
 	driver_yashandb = "yasdb"
 )
 
+// 连接使用utf8mb4, 由MySQL将各列声明的字符集转换为UTF-8, 5.5.3之前的版本没有utf8mb4时使用utf8
+const mysql_charset = "utf8mb4,utf8"
+
 const (
 	MYSQL_VERSION_5 = "5"
 	MYSQL_VERSION_8 = "8"
@@ -28,7 +32,13 @@ var (
 )
 
 func LoadMySQLDB(mysql *confdef.MySQLConfig) (err error) {
-	mysqlDsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s", mysql.UserName, mysql.Password, mysql.Host, mysql.Port, mysql.Database)
+	params := url.Values{}
+	params.Set("charset", mysql_charset)
+	if len(mysql.SourceTimeZone) != 0 {
+		// TIMESTAMP按会话的时区返回, 与source_time_zone保持一致
+		params.Set("time_zone", "'"+formatMySQLTimeZone(mysql.SourceTimeZone)+"'")
+	}
+	mysqlDsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?%s", mysql.UserName, mysql.Password, mysql.Host, mysql.Port, mysql.Database, params.Encode())
 	mysqlDB, err := sql.Open(driver_mysql, mysqlDsn)
 	if err != nil {
 		err = fmt.Errorf("连接mysql时出错: %s", err.Error())
@@ -71,6 +81,14 @@ func queryVersion() (err error) {
 	return
 }
 
+// formatMySQLTimeZone MySQL没有加载时区表时不识别UTC, 转换为偏移的形式
+func formatMySQLTimeZone(timeZone string) string {
+	if strings.EqualFold(strings.TrimSpace(timeZone), "UTC") {
+		return "+00:00"
+	}
+	return strings.TrimSpace(timeZone)
+}
+
 func formatPassword(password string) (newPassword string) {
 	var newPwd strings.Builder
 	for _, r := range password {
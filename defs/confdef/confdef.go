@@ -16,6 +16,8 @@ var (
 	ErrSchemasAndTablesAtLeastOne = errors.New("schemas 和 tables 这两个参数至少需要配置一个, 请检查配置文件")
 	ErrNeedRemapSchemas           = errors.New("需要配置remap_schemas, 指定在崖山要导入的用户, 请检查配置文件")
 	ErrSampleLines                = errors.New("需要配置sample_lines, 指定数据校验时单表的随机采样行数, 参数大于等于0, 为0表示全表校验")
+	ErrDecimalOverflow            = errors.New("decimal_overflow 只能配置为clamp、varchar或fail, 请检查配置文件")
+	ErrCICollation                = errors.New("ci_collation 只能配置为binary或upper, 请检查配置文件")
 )
 
 var (
@@ -23,27 +25,49 @@ var (
 	DefaultParallelPerTable = 1
 	DefaultBatchSize        = 1000
 	DefaultSampleLine       = 1000
+	DefaultServerID         = 1001
+	DefaultFileRows         = 1000000
+
+	DefaultIndexNameTemplate = "{name}"
+	DefaultDecimalOverflow   = DECIMAL_OVERFLOW_CLAMP
+	DefaultCICollation       = CI_COLLATION_BINARY
 
 	MaxParallel = 8
 )
 
+// DECIMAL精度超过YashanDB上限时的处理方式
+const (
+	DECIMAL_OVERFLOW_CLAMP   = "clamp"   // 精度截断为YashanDB的上限, 并输出警告
+	DECIMAL_OVERFLOW_VARCHAR = "varchar" // 转换为VARCHAR, 按文本保存原值
+	DECIMAL_OVERFLOW_FAIL    = "fail"    // 导出DDL失败
+)
+
+// MySQL大小写不敏感的排序规则(如utf8mb4_general_ci)在YashanDB中的处理方式
+const (
+	CI_COLLATION_BINARY = "binary" // 按二进制比较, 唯一索引和主键区分大小写
+	CI_COLLATION_UPPER  = "upper"  // 唯一索引中的列转换为UPPER函数索引, 主键额外创建UPPER唯一索引
+)
+
 var _config M2YConfig
 
 type MySQLConfig struct {
-	Host             string   `toml:"host"`
-	Port             int      `toml:"port"`
-	Database         string   `toml:"database"`
-	UserName         string   `toml:"username"`
-	Password         string   `toml:"password"`
-	Schemas          []string `toml:"schemas"`
-	Tables           []string `toml:"tables"`
-	ExcludeTables    []string `toml:"exclude_tables"`
-	QueryStr         string   `toml:"query_str"`
-	Parallel         int      `toml:"parallel"           default:"1"`
-	ParallelPerTable int      `toml:"parallel_per_table" default:"1"`
-	BatchSize        int      `toml:"batch_size"         default:"1000"`
-	SampleLines      int      `toml:"sample_lines"       default:"1000"`
-	RowsOnly         bool     `toml:"rows_only"`
+	Host               string   `toml:"host"`
+	Port               int      `toml:"port"`
+	Database           string   `toml:"database"`
+	UserName           string   `toml:"username"`
+	Password           string   `toml:"password"`
+	Schemas            []string `toml:"schemas"`
+	Tables             []string `toml:"tables"`
+	ExcludeTables      []string `toml:"exclude_tables"`
+	QueryStr           string   `toml:"query_str"`
+	Parallel           int      `toml:"parallel"           default:"1"`
+	ParallelPerTable   int      `toml:"parallel_per_table" default:"1"`
+	BatchSize          int      `toml:"batch_size"         default:"1000"`
+	SampleLines        int      `toml:"sample_lines"       default:"1000"`
+	RowsOnly           bool     `toml:"rows_only"`
+	ServerID           uint32   `toml:"server_id"          default:"1001"`
+	ConsistentSnapshot bool     `toml:"consistent_snapshot"`
+	SourceTimeZone     string   `toml:"source_time_zone"` // MySQL中时间值所在的时区, 配置后同时设置为MySQL会话的time_zone
 }
 
 type YashanConfig struct {
@@ -55,12 +79,20 @@ type YashanConfig struct {
 	RemapSchemas      []string `toml:"remap_schemas"`
 	CaseSensitive     bool     `toml:"case_sensitive"`
 	AddtionalKeywords []string `toml:"additional_keywords"`
+	IndexNameTemplate string   `toml:"index_name_template"`
+	UnsignedCheck     bool     `toml:"unsigned_check"`
+	DecimalOverflow   string   `toml:"decimal_overflow"` // DECIMAL的精度超过YashanDB上限时的处理方式
+	TargetTimeZone    string   `toml:"target_time_zone"` // 写入YashanDB的时间所在的时区, 默认Asia/Shanghai
+	ZeroDate          string   `toml:"zero_date"`        // MySQL零值日期的处理方式, 可选null、min、fail, 默认null
+	CICollation       string   `toml:"ci_collation"`     // 大小写不敏感的排序规则的处理方式, 可选binary、upper, 默认binary
 }
 
 type M2YConfig struct {
 	LogLevel string        `toml:"log_level"`
 	MySQL    *MySQLConfig  `toml:"mysql"`
 	Yashan   *YashanConfig `toml:"yashandb"`
+	// 类型映射, 不配置时使用内置的映射
+	TypeMapping *TypeMappingConfig `toml:"type_mapping"`
 }
 
 func InitM2YConfig(config string) error {
@@ -79,6 +111,8 @@ func InitM2YConfig(config string) error {
 	}
 	_config = conf
 	initKeywords(_config)
+	initTypeMappings(_config)
+	initTemporal(_config)
 	return nil
 }
 
@@ -96,5 +130,27 @@ func (c *M2YConfig) validate() error {
 	if len(c.MySQL.Schemas) > 0 && len(c.MySQL.Tables) > 0 {
 		return ErrSchemasAndTablesAllExist
 	}
+	switch c.Yashan.DecimalOverflow {
+	case "":
+		c.Yashan.DecimalOverflow = DefaultDecimalOverflow
+	case DECIMAL_OVERFLOW_CLAMP, DECIMAL_OVERFLOW_VARCHAR, DECIMAL_OVERFLOW_FAIL:
+	default:
+		return ErrDecimalOverflow
+	}
+	switch c.Yashan.CICollation {
+	case "":
+		c.Yashan.CICollation = DefaultCICollation
+	case CI_COLLATION_BINARY, CI_COLLATION_UPPER:
+	default:
+		return ErrCICollation
+	}
+	if err := c.validateTemporal(); err != nil {
+		return err
+	}
+	if c.TypeMapping != nil {
+		if err := c.TypeMapping.validate(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
@@ -0,0 +1,84 @@
+package confdef
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	// 内置时区数据, 运行环境没有安装tzdata时也可以使用Asia/Shanghai等时区名
+	_ "time/tzdata"
+)
+
+// MySQL零值日期(如0000-00-00)写入YashanDB时的处理方式
+const (
+	ZERO_DATE_NULL = "null" // 写入NULL
+	ZERO_DATE_MIN  = "min"  // 写入0001-01-01 00:00:00
+	ZERO_DATE_FAIL = "fail" // 同步失败
+)
+
+var (
+	DefaultTargetTimeZone = "Asia/Shanghai"
+	DefaultZeroDate       = ZERO_DATE_NULL
+
+	_sourceLocation *time.Location
+	_targetLocation *time.Location
+
+	timeZoneOffsetRegexp = regexp.MustCompile(`^([+-])(\d{1,2}):(\d{2})$`)
+)
+
+// parseTimeZone 解析时区, 支持IANA时区名(如Asia/Shanghai)、UTC和+08:00形式的偏移
+func parseTimeZone(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if matches := timeZoneOffsetRegexp.FindStringSubmatch(name); matches != nil {
+		hours, _ := strconv.Atoi(matches[2])
+		minutes, _ := strconv.Atoi(matches[3])
+		offset := hours*3600 + minutes*60
+		if matches[1] == "-" {
+			offset = -offset
+		}
+		return time.FixedZone(name, offset), nil
+	}
+	return time.LoadLocation(name)
+}
+
+func (c *M2YConfig) validateTemporal() error {
+	if len(c.MySQL.SourceTimeZone) != 0 {
+		if _, err := parseTimeZone(c.MySQL.SourceTimeZone); err != nil {
+			return fmt.Errorf("source_time_zone %q 无效: %v, 请检查配置文件", c.MySQL.SourceTimeZone, err)
+		}
+	}
+	if len(c.Yashan.TargetTimeZone) == 0 {
+		c.Yashan.TargetTimeZone = DefaultTargetTimeZone
+	}
+	if _, err := parseTimeZone(c.Yashan.TargetTimeZone); err != nil {
+		return fmt.Errorf("target_time_zone %q 无效: %v, 请检查配置文件", c.Yashan.TargetTimeZone, err)
+	}
+	switch c.Yashan.ZeroDate {
+	case "":
+		c.Yashan.ZeroDate = DefaultZeroDate
+	case ZERO_DATE_NULL, ZERO_DATE_MIN, ZERO_DATE_FAIL:
+	default:
+		return fmt.Errorf("zero_date 只能配置为null、min或fail, 请检查配置文件")
+	}
+	return nil
+}
+
+func initTemporal(conf M2YConfig) {
+	_targetLocation, _ = parseTimeZone(conf.Yashan.TargetTimeZone)
+	_sourceLocation = _targetLocation
+	if len(conf.MySQL.SourceTimeZone) != 0 {
+		_sourceLocation, _ = parseTimeZone(conf.MySQL.SourceTimeZone)
+	}
+}
+
+// GetSourceLocation MySQL中DATETIME和TIMESTAMP的值所在的时区, 没有配置时与target_time_zone相同, 即不转换时区
+func GetSourceLocation() *time.Location {
+	return _sourceLocation
+}
+
+// GetTargetLocation 写入YashanDB的时间所在的时区
+func GetTargetLocation() *time.Location {
+	return _targetLocation
+}
@@ -0,0 +1,116 @@
+package confdef
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// TypeMappingConfig 覆盖内置的MySQL到YashanDB的类型映射
+type TypeMappingConfig struct {
+	// key为MySQL的类型, 可以是data_type(如int)、去掉长度的column_type(如int unsigned)或完整的column_type(如tinyint(1))
+	Types map[string]string `toml:"types"`
+	// 按列覆盖, 优先于Types, 按配置的顺序使用第一个匹配的规则
+	Columns []ColumnTypeMapping `toml:"columns"`
+}
+
+type ColumnTypeMapping struct {
+	Pattern string `toml:"pattern"` // schema.table.column, 每一段都支持*和?通配符
+	Type    string `toml:"type"`
+}
+
+var (
+	_typeMappings    map[string]string
+	_columnTypeRules []ColumnTypeMapping
+	typeLengthRegexp = regexp.MustCompile(`\([^)]*\)`)
+	typeSpaceRegexp  = regexp.MustCompile(`\s+`)
+	typeCommaRegexp  = regexp.MustCompile(`\s*,\s*`)
+)
+
+func (c *TypeMappingConfig) validate() error {
+	for mysqlType, yasType := range c.Types {
+		if len(strings.TrimSpace(mysqlType)) == 0 || len(strings.TrimSpace(yasType)) == 0 {
+			return fmt.Errorf("type_mapping.types 中的类型不能为空: %q = %q, 请检查配置文件", mysqlType, yasType)
+		}
+	}
+	for _, rule := range c.Columns {
+		parts := strings.Split(rule.Pattern, ".")
+		if len(parts) != 3 {
+			return fmt.Errorf("type_mapping.columns 的pattern %q 格式错误, 应为schema.table.column, 请检查配置文件", rule.Pattern)
+		}
+		for _, part := range parts {
+			if _, err := path.Match(part, ""); err != nil {
+				return fmt.Errorf("type_mapping.columns 的pattern %q 格式错误: %v, 请检查配置文件", rule.Pattern, err)
+			}
+		}
+		if len(strings.TrimSpace(rule.Type)) == 0 {
+			return fmt.Errorf("type_mapping.columns 的pattern %q 没有配置type, 请检查配置文件", rule.Pattern)
+		}
+	}
+	return nil
+}
+
+func initTypeMappings(conf M2YConfig) {
+	_typeMappings = make(map[string]string)
+	_columnTypeRules = nil
+	if conf.TypeMapping == nil {
+		return
+	}
+	for mysqlType, yasType := range conf.TypeMapping.Types {
+		_typeMappings[normalizeMySQLType(mysqlType)] = normalizeYashanType(yasType)
+	}
+	for _, rule := range conf.TypeMapping.Columns {
+		_columnTypeRules = append(_columnTypeRules, ColumnTypeMapping{Pattern: rule.Pattern, Type: normalizeYashanType(rule.Type)})
+	}
+}
+
+// HasTypeMapping 是否配置了类型映射
+func HasTypeMapping() bool {
+	return len(_typeMappings) != 0 || len(_columnTypeRules) != 0
+}
+
+// GetColumnTypeMapping 返回列配置的YashanDB类型, 先匹配按列的规则, 再依次按完整的column_type、
+// 去掉长度的column_type和data_type匹配全局的类型映射, 没有配置时返回false
+func GetColumnTypeMapping(schema, table, column, dataType, columnType string) (string, bool) {
+	for _, rule := range _columnTypeRules {
+		parts := strings.Split(rule.Pattern, ".")
+		if matchTypeMappingPattern(parts[0], schema) && matchTypeMappingPattern(parts[1], table) && matchTypeMappingPattern(parts[2], column) {
+			return rule.Type, true
+		}
+	}
+	return GetTypeMapping(dataType, columnType)
+}
+
+// GetTypeMapping 按类型匹配全局的类型映射, 用于没有列信息的场景, 如存储过程的参数和变量
+func GetTypeMapping(dataType, columnType string) (string, bool) {
+	if len(_typeMappings) == 0 {
+		return "", false
+	}
+	columnType = normalizeMySQLType(columnType)
+	for _, key := range []string{columnType, normalizeMySQLType(typeLengthRegexp.ReplaceAllString(columnType, "")), normalizeMySQLType(dataType)} {
+		if yasType, ok := _typeMappings[key]; ok && len(key) != 0 {
+			return yasType, true
+		}
+	}
+	return "", false
+}
+
+// matchTypeMappingPattern 按通配符匹配对象名, 大小写不敏感
+func matchTypeMappingPattern(pattern, name string) bool {
+	ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(name))
+	return ok
+}
+
+// normalizeMySQLType 统一类型的写法, 如"INT(10)  UNSIGNED"转换为"int(10) unsigned", "decimal(10, 2)"转换为"decimal(10,2)"
+func normalizeMySQLType(t string) string {
+	t = typeSpaceRegexp.ReplaceAllString(strings.TrimSpace(strings.ToLower(t)), " ")
+	t = strings.ReplaceAll(strings.ReplaceAll(t, " (", "("), "( ", "(")
+	t = strings.ReplaceAll(t, " )", ")")
+	return typeCommaRegexp.ReplaceAllString(t, ",")
+}
+
+// normalizeYashanType YashanDB的类型统一为小写, 与typedef中的类型一致
+func normalizeYashanType(t string) string {
+	return typeSpaceRegexp.ReplaceAllString(strings.TrimSpace(strings.ToLower(t)), " ")
+}
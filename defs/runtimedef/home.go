@@ -14,9 +14,10 @@ const (
 )
 
 const (
-	_DIR_NAME_LOG    = "log"
-	_DIR_NAME_CONFIG = "config"
-	_DIR_NAME_EXPORT = "export"
+	_DIR_NAME_LOG        = "log"
+	_DIR_NAME_CONFIG     = "config"
+	_DIR_NAME_EXPORT     = "export"
+	_DIR_NAME_CHECKPOINT = "checkpoint"
 )
 
 var _m2yHome string
@@ -37,6 +38,10 @@ func GetConfigPath() string {
 	return path.Join(_m2yHome, _DIR_NAME_CONFIG)
 }
 
+func GetCheckpointPath() string {
+	return path.Join(_m2yHome, _DIR_NAME_CHECKPOINT)
+}
+
 func genHomeFromRelativePath() (home string, err error) {
 	executeable, err := getExecutable()
 	if err != nil {
@@ -2,12 +2,65 @@ package sqldef
 
 const (
 	M_SQL_QUERY_COLUMNS = `
-	SELECT table_name, column_name, data_type, character_maximum_length, numeric_precision, numeric_scale, column_comment,
+	SELECT column_name, data_type, character_maximum_length, numeric_precision, numeric_scale, column_comment,
 	substring(column_type,instr(column_type,'(')+1,instr(column_type,')')-instr(column_type,'(')-1) as column_type_length,
-	is_nullable,ifnull(column_default,"")
+	is_nullable,ifnull(column_default,""),extra,column_type,ifnull(character_set_name,""),ifnull(collation_name,""),
+	ifnull(generation_expression,"")
 	FROM information_schema.columns
 	WHERE table_schema = ? 
 	and table_name = ? order by  ORDINAL_POSITION`
+	// 生成列的EXTRA为VIRTUAL GENERATED或STORED GENERATED, 8.0中表达式默认值的EXTRA为DEFAULT_GENERATED, 不是生成列
+	M_SQL_QUERY_GENERATED_COLUMNS = `
+	SELECT column_name
+	FROM information_schema.columns
+	WHERE table_schema = ? AND table_name = ?
+	AND (extra LIKE '%VIRTUAL GENERATED%' OR extra LIKE '%STORED GENERATED%')
+	ORDER BY ordinal_position`
+	M_SQL_QUERY_CHECK_CONSTRAINTS = `
+	SELECT tc.constraint_name, cc.check_clause, tc.enforced
+	FROM information_schema.table_constraints tc
+	JOIN information_schema.check_constraints cc
+	ON cc.constraint_schema = tc.constraint_schema AND cc.constraint_name = tc.constraint_name
+	WHERE tc.table_schema = ? AND tc.table_name = ? AND tc.constraint_type = 'CHECK'
+	ORDER BY tc.constraint_name`
+	M_SQL_QUERY_UNSIGNED_COLUMNS = `
+	SELECT column_name
+	FROM information_schema.columns
+	WHERE table_schema = ? AND table_name = ? AND column_type LIKE '%unsigned%'
+	ORDER BY ordinal_position`
+	M_SQL_QUERY_ENUM_COLUMNS = `
+	SELECT column_name, data_type, column_type
+	FROM information_schema.columns
+	WHERE table_schema = ? AND table_name = ? AND data_type IN ('enum', 'set')
+	ORDER BY ordinal_position`
+	M_SQL_QUERY_NUMERIC_COLUMNS = `
+	SELECT column_name, data_type, column_type, numeric_precision, numeric_scale
+	FROM information_schema.columns
+	WHERE table_schema = ? AND table_name = ?
+	AND data_type IN ('tinyint', 'smallint', 'mediumint', 'int', 'integer', 'bigint', 'decimal', 'numeric', 'float', 'double', 'real')
+	ORDER BY ordinal_position`
+	M_SQL_QUERY_ZERO_DATE_COUNT     = "SELECT COUNT(*) FROM `%s`.`%s` WHERE MONTH(`%s`) = 0 OR DAYOFMONTH(`%s`) = 0"
+	M_SQL_QUERY_TIME_OVERFLOW_COUNT = "SELECT COUNT(*) FROM `%s`.`%s` WHERE `%s` < '00:00:00' OR `%s` >= '24:00:00'"
+	M_SQL_QUERY_TEMPORAL_COLUMNS    = `
+	SELECT column_name, data_type, column_type
+	FROM information_schema.columns
+	WHERE table_schema = ? AND table_name = ? AND data_type IN ('date', 'datetime', 'timestamp', 'time')
+	ORDER BY ordinal_position`
+	// enum和set的值来自列定义, json的字符集固定为utf8mb4, 都不需要检查
+	M_SQL_QUERY_CHARSET_COLUMNS = `
+	SELECT column_name, data_type, column_type, character_set_name
+	FROM information_schema.columns
+	WHERE table_schema = ? AND table_name = ? AND character_set_name IS NOT NULL
+	AND data_type NOT IN ('enum', 'set', 'json')
+	ORDER BY ordinal_position`
+	M_SQL_QUERY_CI_COLUMNS = `
+	SELECT column_name
+	FROM information_schema.columns
+	WHERE table_schema = ? AND table_name = ? AND collation_name LIKE '%\_ci'
+	ORDER BY ordinal_position`
+	// 按原始字节读取字符列, 不经过连接字符集的转换
+	M_SQL_QUERY_BINARY_VALUES  = "SELECT %s FROM `%s`.`%s`"
+	M_SQL_CAST_BINARY_FORMAT   = "CAST(`%s` AS BINARY)"
 	M_SQL_QUERY_TABLE_COMMENTS = `
     SELECT table_comment
     FROM information_schema.tables
@@ -17,22 +70,55 @@ const (
 	M_SQL_SHOW_DATABASES    = "SHOW DATABASES"
 	M_SQL_QUERY_FOREIGN_KEY = `
 	SELECT
-	constraint_name,
-	group_concat(column_name),
-	referenced_table_name,
-	group_concat(referenced_column_name)
+	k.constraint_name,
+	k.column_name,
+	k.referenced_table_schema,
+	k.referenced_table_name,
+	k.referenced_column_name,
+	r.delete_rule,
+	r.update_rule
 	FROM
-	information_schema.key_column_usage
+	information_schema.key_column_usage k
+	JOIN information_schema.referential_constraints r
+	ON r.constraint_schema = k.constraint_schema
+	AND r.table_name = k.table_name
+	AND r.constraint_name = k.constraint_name
 	WHERE
-	table_schema = ?
-	AND table_name = ?
-	AND referenced_table_name IS NOT NULL
-	group by constraint_name,referenced_table_name`
+	k.table_schema = ?
+	AND k.table_name = ?
+	AND k.referenced_table_name IS NOT NULL
+	order by k.constraint_name, k.ordinal_position`
 	M_SQL_QUERY_TABLES = `select table_name 
     from information_schema.TABLES 
-    where table_schema=? and table_type = 'BASE TABLE';`
-	M_SQL_QUERY_VIEW           = "SELECT TABLE_NAME,VIEW_DEFINITION FROM INFORMATION_SCHEMA.VIEWS WHERE TABLE_SCHEMA = '%s'"
+    where table_schema=? and table_type = 'BASE TABLE' order by table_name;`
+	M_SQL_QUERY_PARTITIONS = `
+	SELECT partition_name, subpartition_name, partition_method, subpartition_method,
+	partition_expression, subpartition_expression, partition_description
+	FROM information_schema.partitions
+	WHERE table_schema = ? AND table_name = ? AND partition_name IS NOT NULL
+	ORDER BY partition_ordinal_position, subpartition_ordinal_position`
+	M_SQL_QUERY_TRIGGERS = `
+	SELECT trigger_name, event_manipulation, event_object_table, action_timing, action_statement
+	FROM information_schema.triggers
+	WHERE trigger_schema = ?
+	ORDER BY event_object_table, action_timing, event_manipulation, action_order, trigger_name`
+	M_SQL_QUERY_ROUTINES = `
+	SELECT routine_name, routine_type
+	FROM information_schema.routines
+	WHERE routine_schema = ?
+	ORDER BY routine_type, routine_name`
+	M_SQL_QUERY_EVENTS = `
+	SELECT event_name, event_definition, event_type,
+	DATE_FORMAT(execute_at, '%Y-%m-%d %H:%i:%s'), interval_value, interval_field,
+	DATE_FORMAT(starts, '%Y-%m-%d %H:%i:%s'), DATE_FORMAT(ends, '%Y-%m-%d %H:%i:%s'),
+	status, on_completion, event_comment, time_zone
+	FROM information_schema.events
+	WHERE event_schema = ?
+	ORDER BY event_name`
+	M_SQL_SHOW_CREATE_ROUTINE  = "SHOW CREATE %s `%s`.`%s`"
+	M_SQL_QUERY_VIEW           = "SELECT TABLE_NAME,VIEW_DEFINITION FROM INFORMATION_SCHEMA.VIEWS WHERE TABLE_SCHEMA = '%s' ORDER BY TABLE_NAME"
 	M_SQL_QUERY_TABLE_COUNT    = "SELECT COUNT(*) FROM `%s`.`%s` "
+	M_SQL_QUERY_OVERFLOW_COUNT = "SELECT COUNT(*) FROM `%s`.`%s` WHERE `%s` < %s OR `%s` > %s"
 	M_SQL_QUERY_TABLE_DATA     = "SELECT * FROM `%s`.`%s` LIMIT %d OFFSET %d"
 	M_SQL_QUERY_AUTO_INCREMENT = `SELECT COLUMN_NAME FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND EXTRA = 'auto_increment'`
 	M_SQL_QUERY_PRIMARY_KEY    = `
@@ -41,6 +127,39 @@ const (
     WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND COLUMN_KEY = 'PRI'`
 	M_SQL_QUERY_TABLE_ALL_DATA   = "SELECT * FROM `%s`.`%s`"
 	M_SQL_QUERY_ORDER_RAND_LIMIT = "%s order by rand() limit %d"
+	// 函数索引的column_name为NULL, 使用LEFT JOIN保留这些索引列, 以排除包含函数索引列的唯一索引
+	M_SQL_QUERY_UNIQUE_KEYS = `
+	SELECT s.index_name, s.column_name, c.data_type, c.is_nullable, s.expression
+	FROM information_schema.statistics s
+	LEFT JOIN information_schema.columns c
+	ON c.table_schema = s.table_schema AND c.table_name = s.table_name AND c.column_name = s.column_name
+	WHERE s.table_schema = ? AND s.table_name = ? AND s.non_unique = 0
+	ORDER BY s.index_name = 'PRIMARY' DESC, s.index_name, s.seq_in_index`
+	// 8.0.13之前的版本和MariaDB没有函数索引, statistics中没有expression列
+	M_SQL_QUERY_UNIQUE_KEYS_5 = `
+	SELECT s.index_name, s.column_name, c.data_type, c.is_nullable, NULL AS expression
+	FROM information_schema.statistics s
+	LEFT JOIN information_schema.columns c
+	ON c.table_schema = s.table_schema AND c.table_name = s.table_name AND c.column_name = s.column_name
+	WHERE s.table_schema = ? AND s.table_name = ? AND s.non_unique = 0
+	ORDER BY s.index_name = 'PRIMARY' DESC, s.index_name, s.seq_in_index`
+	M_SQL_QUERY_KEY_MIN_MAX           = "SELECT MIN(%s), MAX(%s) FROM `%s`.`%s`"
+	M_SQL_QUERY_KEY_BOUNDARY          = "SELECT %s FROM `%s`.`%s` ORDER BY %s LIMIT 1 OFFSET %d"
+	M_SQL_QUERY_TABLE_KEY_RANGE       = "SELECT * FROM `%s`.`%s`%s ORDER BY %s"
+	M_SQL_SHOW_MASTER_STATUS          = "SHOW MASTER STATUS"
+	M_SQL_SHOW_BINARY_LOG_STATUS      = "SHOW BINARY LOG STATUS"
+	M_SQL_QUERY_BINLOG_FORMAT         = "SELECT @@GLOBAL.binlog_format, @@GLOBAL.binlog_row_image"
+	M_SQL_QUERY_TIME_ZONE_OFFSET      = "SELECT TIMESTAMPDIFF(SECOND, UTC_TIMESTAMP(), NOW())"
+	M_SQL_FLUSH_TABLES_WITH_READ_LOCK = "FLUSH TABLES WITH READ LOCK"
+	M_SQL_UNLOCK_TABLES               = "UNLOCK TABLES"
+	M_SQL_SET_REPEATABLE_READ         = "SET SESSION TRANSACTION ISOLATION LEVEL REPEATABLE READ"
+	M_SQL_START_CONSISTENT_SNAPSHOT   = "START TRANSACTION WITH CONSISTENT SNAPSHOT"
+	M_SQL_COMMIT                      = "COMMIT"
+	M_SQL_QUERY_BINLOG_COLUMNS        = `
+	SELECT column_name, data_type, column_type, extra, ifnull(character_set_name, '')
+	FROM information_schema.columns
+	WHERE table_schema = ? AND table_name = ?
+	ORDER BY ordinal_position`
 )
 
 const (
@@ -53,11 +172,15 @@ const (
 	Y_SQL_QUERY_COLUMN   = "select DATA_TYPE,COLUMN_NAME from all_tab_columns where owner='%s' and TABLE_NAME='%s' order by COLUMN_ID"
 	Y_SQL_SET_DEFINE_OFF = "SET DEFINE OFF;\n"
 
+	// 空间数据以WKT和SRID两个参数插入
+	Y_SQL_GEOMETRY_PLACEHOLDER = "ST_GEOMFROMTEXT(?, ?)"
+
 	Y_SQL_ALTER_COLUMN_NOT_NULL                = "ALTER TABLE %s.%s modify %s NOT NULL;\n"
 	Y_SQL_ALTER_COLUMN_NOT_NULL_CASE_SENSITIVE = "ALTER TABLE \"%s\".\"%s\" modify \"%s\" NOT NULL;\n"
 
 	Y_SQL_COLUMN_STMT_FORMAT                = "%s %s%s"
 	Y_SQL_COLUMN_STMT_FORMAT_CASE_SENSITIVE = "\"%s\" %s%s"
+	Y_SQL_GENERATED_COLUMN_FORMAT           = " GENERATED ALWAYS AS (%s) VIRTUAL"
 
 	Y_SQL_COLUMN_COMMENT_FORMAT                = "COMMENT ON COLUMN %s.%s.%s IS '%s';\n"
 	Y_SQL_COLUMN_COMMENT_FORMAT_CASE_SENSITIVE = "COMMENT ON COLUMN \"%s\".\"%s\".\"%s\" IS '%s';\n"
@@ -68,8 +191,19 @@ const (
 	Y_SQL_CREATE_SEQUENCE_FORMAT                = "CREATE SEQUENCE %s.%s START WITH %s INCREMENT BY 1;\n"
 	Y_SQL_CREATE_SEQUENCE_FORMAT_CASE_SENSITIVE = "CREATE SEQUENCE \"%s\".\"%s\" START WITH %s INCREMENT BY 1;\n"
 
-	Y_SQL_CREATE_TABLE                = "CREATE TABLE %s.%s (\n\t%s\n);"
-	Y_SQL_CREATE_TABLE_CASE_SENSITIVE = "CREATE TABLE \"%s\".\"%s\" (\n\t%s\n);"
+	Y_SQL_CREATE_TABLE                = "CREATE TABLE %s.%s (\n\t%s\n)%s;"
+	Y_SQL_CREATE_TABLE_CASE_SENSITIVE = "CREATE TABLE \"%s\".\"%s\" (\n\t%s\n)%s;"
+
+	Y_SQL_PARTITION_BY      = "\nPARTITION BY %s (%s)"
+	Y_SQL_SUBPARTITION_BY   = "\nSUBPARTITION BY HASH (%s)"
+	Y_SQL_PARTITIONS        = "\n(\n\t%s\n)"
+	Y_SQL_RANGE_PARTITION   = "PARTITION %s VALUES LESS THAN (%s)"
+	Y_SQL_LIST_PARTITION    = "PARTITION %s VALUES (%s)"
+	Y_SQL_HASH_PARTITION    = "PARTITION %s"
+	Y_SQL_SUBPARTITION      = "SUBPARTITION %s"
+	Y_SQL_SUBPARTITIONS     = " (%s)"
+	Y_SQL_DATE_LITERAL      = "DATE '%s'"
+	Y_SQL_TIMESTAMP_LITERAL = "TIMESTAMP '%s'"
 
 	Y_SQL_SET_COLUMN_DEFAULT_VALUE_FORMAT                = "ALTER TABLE %s.%s MODIFY %s DEFAULT %s.%s.NEXTVAL;\n"
 	Y_SQL_SET_COLUMN_DEFAULT_VALUE_FORMAT_CASE_SENSITIVE = "ALTER TABLE \"%s\".\"%s\" MODIFY \"%s\" DEFAULT \"%s\".\"%s\".NEXTVAL;\n"
@@ -80,20 +214,79 @@ const (
 	Y_SQL_CREATE_UNIQUE_INDEX                = "CREATE UNIQUE INDEX %s.%s ON %s.%s (%s);\n"
 	Y_SQL_CREATE_UNIQUE_INDEX_CASE_SENSITIVE = "CREATE UNIQUE INDEX \"%s\".\"%s\" ON \"%s\".\"%s\" (%s);\n"
 
+	Y_SQL_ADD_CHECK                        = "ALTER TABLE %s.%s ADD CONSTRAINT %s CHECK (%s);\n"
+	Y_SQL_ADD_CHECK_CASE_SENSITIVE         = "ALTER TABLE \"%s\".\"%s\" ADD CONSTRAINT %s CHECK (%s);\n"
+	Y_SQL_ADD_UNNAMED_CHECK                = "ALTER TABLE %s.%s ADD CHECK (%s);\n"
+	Y_SQL_ADD_UNNAMED_CHECK_CASE_SENSITIVE = "ALTER TABLE \"%s\".\"%s\" ADD CHECK (%s);\n"
+	Y_SQL_UNSIGNED_CHECK_FORMAT            = "%s >= 0"
+	Y_SQL_ENUM_CHECK_FORMAT                = "%s IN (%s)"
+	// SET的值为逗号分隔的成员, 空字符串在YashanDB中为NULL, 不受CHECK约束限制
+	Y_SQL_SET_CHECK_FORMAT = "REGEXP_LIKE(%s, '^(%s)(,(%s))*$')"
+
 	Y_SQL_ADD_UNIQUE_CONSTRAINT                = "ALTER TABLE %s.%s ADD CONSTRAINT %s UNIQUE (%s);\n"
 	Y_SQL_ADD_UNIQUE_CONSTRAINT_CASE_SENSITIVE = "ALTER TABLE \"%s\".\"%s\" ADD CONSTRAINT %s UNIQUE (%s);\n"
 
 	Y_SQL_CREATE_INDEX                = "CREATE INDEX %s.%s ON %s.%s (%s);\n"
 	Y_SQL_CREATE_INDEX_CASE_SENSITIVE = "CREATE INDEX \"%s\".\"%s\" ON \"%s\".\"%s\" (%s);\n"
 
-	Y_SQL_ADD_FOREIGN_KEY                = "ALTER TABLE %s.%s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s.%s(%s);\n"
-	Y_SQL_ADD_FOREIGN_KEY_CASE_SENSITIVE = "ALTER TABLE \"%s\".\"%s\" ADD CONSTRAINT %s FOREIGN KEY (\"%s\") REFERENCES \"%s\".\"%s\"(\"%s\");\n"
+	// 前缀索引转换为函数索引
+	Y_SQL_INDEX_PREFIX_FORMAT = "SUBSTR(%s, 1, %d)"
+	// 大小写不敏感的列转换为UPPER函数索引
+	Y_SQL_INDEX_UPPER_FORMAT = "UPPER(%s)"
+
+	Y_SQL_ADD_FOREIGN_KEY                = "ALTER TABLE %s.%s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s.%s(%s)%s;\n"
+	Y_SQL_ADD_FOREIGN_KEY_CASE_SENSITIVE = "ALTER TABLE \"%s\".\"%s\" ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES \"%s\".\"%s\"(%s)%s;\n"
+	Y_SQL_ON_DELETE_FORMAT               = " ON DELETE %s"
 
 	Y_SQL_CREATE_VIEW                = "CREATE VIEW %s.%s AS %s ;\n"
 	Y_SQL_CREATE_VIEW_CASE_SENSITIVE = "CREATE VIEW \"%s\".\"%s\" AS %s ;\n"
 
+	// PL对象之间使用单独一行的/分隔
+	Y_SQL_PL_TERMINATOR = "\n/\n"
+
+	Y_SQL_CREATE_TRIGGER                = "CREATE OR REPLACE TRIGGER %s.%s\n%s %s ON %s.%s\nFOR EACH ROW\n"
+	Y_SQL_CREATE_TRIGGER_CASE_SENSITIVE = "CREATE OR REPLACE TRIGGER \"%s\".\"%s\"\n%s %s ON \"%s\".\"%s\"\nFOR EACH ROW\n"
+
+	Y_SQL_CREATE_PROCEDURE                = "CREATE OR REPLACE PROCEDURE %s.%s%s AS\n"
+	Y_SQL_CREATE_PROCEDURE_CASE_SENSITIVE = "CREATE OR REPLACE PROCEDURE \"%s\".\"%s\"%s AS\n"
+
+	Y_SQL_CREATE_FUNCTION                = "CREATE OR REPLACE FUNCTION %s.%s%s RETURN %s AS\n"
+	Y_SQL_CREATE_FUNCTION_CASE_SENSITIVE = "CREATE OR REPLACE FUNCTION \"%s\".\"%s\"%s RETURN %s AS\n"
+
+	Y_SQL_JOB_NAME                = "%s.%s"
+	Y_SQL_JOB_NAME_CASE_SENSITIVE = "\"%s\".\"%s\""
+	Y_SQL_REPEAT_INTERVAL_FORMAT  = "'FREQ=%s;INTERVAL=%d'"
+
+	// 先删除同名的作业, 重复执行时与CREATE OR REPLACE的效果相同
+	Y_SQL_CREATE_JOB = `BEGIN
+    BEGIN
+        DBMS_SCHEDULER.DROP_JOB('%[1]s');
+    EXCEPTION
+        WHEN OTHERS THEN NULL;
+    END;
+    DBMS_SCHEDULER.CREATE_JOB(
+        job_name        => '%[1]s',
+        job_type        => 'PLSQL_BLOCK',
+        job_action      => '%[2]s',
+        start_date      => %[3]s,
+        repeat_interval => %[4]s,
+        end_date        => %[5]s,
+        enabled         => %[6]s,
+        auto_drop       => %[7]s,
+        comments        => '%[8]s');
+END;`
+
 	Y_SQL_INSERT_DATA                = "INSERT INTO %s.%s ( %s ) VALUES (%s)"
 	Y_SQL_INSERT_DATA_CASE_SENSITIVE = "INSERT INTO \"%s\".\"%s\" ( %s ) VALUES (%s)"
+
+	Y_SQL_TRUNCATE_TABLE                = "TRUNCATE TABLE %s.%s"
+	Y_SQL_TRUNCATE_TABLE_CASE_SENSITIVE = "TRUNCATE TABLE \"%s\".\"%s\""
+
+	Y_SQL_UPDATE_DATA                = "UPDATE %s.%s SET %s WHERE %s"
+	Y_SQL_UPDATE_DATA_CASE_SENSITIVE = "UPDATE \"%s\".\"%s\" SET %s WHERE %s"
+
+	Y_SQL_DELETE_DATA                = "DELETE FROM %s.%s WHERE %s"
+	Y_SQL_DELETE_DATA_CASE_SENSITIVE = "DELETE FROM \"%s\".\"%s\" WHERE %s"
 )
 
 const (
@@ -117,4 +310,5 @@ const (
 
 const (
 	Y_MAX_NUMERIC_PRECISION = 38
+	Y_MAX_IDENTIFIER_LENGTH = 64
 )
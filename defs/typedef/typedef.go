@@ -1,6 +1,10 @@
 package typedef
 
-import "m2y/defs/errdef"
+import (
+	"strings"
+
+	"m2y/defs/errdef"
+)
 
 // mysql type
 const (
@@ -41,6 +45,45 @@ const (
 	M_INT_UNSIGNED       string = "int unsigned"
 	M_BIGINT_UNSIGNED    string = "bigint unsigned"
 	M_GEOMETRY           string = "geometry"
+
+	// 空间类型的子类型, MySQL 8.0的geomcollection与geometrycollection相同
+	M_POINT              string = "point"
+	M_LINESTRING         string = "linestring"
+	M_POLYGON            string = "polygon"
+	M_MULTIPOINT         string = "multipoint"
+	M_MULTILINESTRING    string = "multilinestring"
+	M_MULTIPOLYGON       string = "multipolygon"
+	M_GEOMETRYCOLLECTION string = "geometrycollection"
+	M_GEOMCOLLECTION     string = "geomcollection"
+
+	// 无符号的浮点数和定点数, MySQL 8.0.17已废弃, 取值范围与有符号的类型相同
+	M_DECIMAL_UNSIGNED string = "decimal unsigned"
+	M_FLOAT_UNSIGNED   string = "float unsigned"
+	M_DOUBLE_UNSIGNED  string = "double unsigned"
+
+	// 类型的别名, information_schema中保存的是别名对应的类型, 存储过程和函数中可以使用别名
+	M_BOOL             string = "bool"
+	M_BOOLEAN          string = "boolean"
+	M_SERIAL           string = "serial"
+	M_INTEGER          string = "integer"
+	M_INTEGER_UNSIGNED string = "integer unsigned"
+	M_DEC              string = "dec"
+	M_NUMERIC          string = "numeric"
+	M_FIXED            string = "fixed"
+	M_REAL             string = "real"
+	M_DOUBLE_PRECISION string = "double precision"
+	M_CHARACTER        string = "character"
+	M_CHARACTER_VARY   string = "character varying"
+	M_NATIONAL_CHAR    string = "national char"
+	M_NATIONAL_VARCHAR string = "national varchar"
+	M_LONG             string = "long"
+	M_LONG_VARCHAR     string = "long varchar"
+	M_LONG_VARBINARY   string = "long varbinary"
+
+	// MariaDB的类型
+	M_INET4 string = "inet4"
+	M_INET6 string = "inet6"
+	M_UUID  string = "uuid"
 )
 
 // yashandb type
@@ -64,6 +107,7 @@ const (
 	Y_CLOB      string = "clob"
 	Y_JSON      string = "json"
 	Y_GEOMETRY  string = "geometry"
+	Y_BOOLEAN   string = "boolean"
 )
 
 // mysql to yashan map
@@ -106,14 +150,87 @@ var (
 		M_INT_UNSIGNED:       Y_BIGINT,
 		M_BIGINT_UNSIGNED:    Y_NUMBER,
 		M_GEOMETRY:           Y_GEOMETRY,
+		M_POINT:              Y_GEOMETRY,
+		M_LINESTRING:         Y_GEOMETRY,
+		M_POLYGON:            Y_GEOMETRY,
+		M_MULTIPOINT:         Y_GEOMETRY,
+		M_MULTILINESTRING:    Y_GEOMETRY,
+		M_MULTIPOLYGON:       Y_GEOMETRY,
+		M_GEOMETRYCOLLECTION: Y_GEOMETRY,
+		M_GEOMCOLLECTION:     Y_GEOMETRY,
+		M_DECIMAL_UNSIGNED:   Y_NUMBER,
+		M_FLOAT_UNSIGNED:     Y_FLOAT,
+		M_DOUBLE_UNSIGNED:    Y_DOUBLE,
+		M_BOOL:               Y_SMALLINT,
+		M_BOOLEAN:            Y_SMALLINT,
+		M_SERIAL:             Y_NUMBER,
+		M_INTEGER:            Y_BIGINT,
+		M_INTEGER_UNSIGNED:   Y_BIGINT,
+		M_DEC:                Y_NUMBER,
+		M_NUMERIC:            Y_NUMBER,
+		M_FIXED:              Y_NUMBER,
+		M_REAL:               Y_DOUBLE,
+		M_DOUBLE_PRECISION:   Y_DOUBLE,
+		M_CHARACTER:          Y_CHAR,
+		M_CHARACTER_VARY:     Y_VARCHAR,
+		M_NATIONAL_CHAR:      Y_NCHAR,
+		M_NATIONAL_VARCHAR:   Y_NVARCHAR,
+		M_LONG:               Y_CLOB,
+		M_LONG_VARCHAR:       Y_CLOB,
+		M_LONG_VARBINARY:     Y_BLOB,
+		M_INET4:              Y_VARCHAR,
+		M_INET6:              Y_VARCHAR,
+		M_UUID:               Y_CHAR,
+	}
+
+	// information_schema中没有长度的字符类型的默认长度, inet6最长为39个字符, uuid为36个字符
+	_DefaultCharLength = map[string]int64{
+		M_INET4: 15,
+		M_INET6: 39,
+		M_UUID:  36,
 	}
 )
 
+// MySQLToYasType 返回MySQL类型对应的YashanDB类型, t可以是data_type或column_type,
+// 长度、zerofill和字符集等属性不影响映射, 没有单独映射的unsigned类型按有符号的类型映射
 func MySQLToYasType(t string) (yas string, err error) {
+	t = NormalizeMySQLType(t)
 	yas, ok := _DataTypeMap[t]
+	if !ok {
+		yas, ok = _DataTypeMap[strings.TrimSuffix(t, " unsigned")]
+	}
 	if !ok {
 		err = errdef.NewTransUnSupportTypeErr(t)
 		return
 	}
 	return
 }
+
+// NormalizeMySQLType 去掉类型的长度、zerofill和字符集等属性, 如"INT(10) UNSIGNED ZEROFILL"返回"int unsigned"
+func NormalizeMySQLType(t string) string {
+	t = strings.ToLower(t)
+	// enum和set的取值中可能有括号, 去掉第一个左括号到最后一个右括号之间的内容
+	if start := strings.Index(t, "("); start >= 0 {
+		if end := strings.LastIndex(t, ")"); end > start {
+			t = t[:start] + " " + t[end+1:]
+		}
+	}
+	var words []string
+	for _, word := range strings.Fields(t) {
+		// character set和collate之后的属性都不影响类型
+		if word == "character" && len(words) != 0 || word == "charset" || word == "collate" {
+			break
+		}
+		if word == "zerofill" || word == "signed" {
+			continue
+		}
+		words = append(words, word)
+	}
+	return strings.Join(words, " ")
+}
+
+// DefaultCharLength 返回information_schema中没有长度的字符类型的长度
+func DefaultCharLength(t string) (int64, bool) {
+	length, ok := _DefaultCharLength[NormalizeMySQLType(t)]
+	return length, ok
+}
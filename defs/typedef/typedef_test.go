@@ -0,0 +1,213 @@
+package typedef
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fixtureTypes test/sql中建表语句的列类型及其在information_schema中的column_type和data_type对应的YashanDB类型
+var fixtureTypes = []struct {
+	declared   string
+	columnType string
+	dataType   string
+	yasType    string
+}{
+	// mysql_all_types.sql
+	{declared: "SERIAL", columnType: "bigint unsigned", dataType: "bigint", yasType: Y_NUMBER},
+	{declared: "BOOL", columnType: "tinyint(1)", dataType: "tinyint", yasType: Y_SMALLINT},
+	{declared: "TINYINT", columnType: "tinyint", dataType: "tinyint", yasType: Y_SMALLINT},
+	{declared: "TINYINT UNSIGNED", columnType: "tinyint unsigned", dataType: "tinyint", yasType: Y_SMALLINT},
+	{declared: "SMALLINT", columnType: "smallint", dataType: "smallint", yasType: Y_INTEGER},
+	{declared: "SMALLINT UNSIGNED", columnType: "smallint unsigned", dataType: "smallint", yasType: Y_INTEGER},
+	{declared: "MEDIUMINT", columnType: "mediumint", dataType: "mediumint", yasType: Y_INTEGER},
+	{declared: "MEDIUMINT UNSIGNED", columnType: "mediumint unsigned", dataType: "mediumint", yasType: Y_INTEGER},
+	{declared: "MEDIUMINT(8) UNSIGNED ZEROFILL", columnType: "mediumint(8) unsigned zerofill", dataType: "mediumint", yasType: Y_INTEGER},
+	{declared: "INT", columnType: "int", dataType: "int", yasType: Y_BIGINT},
+	{declared: "INT UNSIGNED", columnType: "int unsigned", dataType: "int", yasType: Y_BIGINT},
+	{declared: "INTEGER(10) ZEROFILL", columnType: "int(10) unsigned zerofill", dataType: "int", yasType: Y_BIGINT},
+	{declared: "BIGINT", columnType: "bigint", dataType: "bigint", yasType: Y_BIGINT},
+	{declared: "BIGINT UNSIGNED", columnType: "bigint unsigned", dataType: "bigint", yasType: Y_NUMBER},
+	{declared: "DECIMAL(18, 4)", columnType: "decimal(18,4)", dataType: "decimal", yasType: Y_NUMBER},
+	{declared: "DECIMAL(10, 2) UNSIGNED", columnType: "decimal(10,2) unsigned", dataType: "decimal", yasType: Y_NUMBER},
+	{declared: "NUMERIC(12, 3)", columnType: "decimal(12,3)", dataType: "decimal", yasType: Y_NUMBER},
+	{declared: "FLOAT", columnType: "float", dataType: "float", yasType: Y_FLOAT},
+	{declared: "FLOAT UNSIGNED", columnType: "float unsigned", dataType: "float", yasType: Y_FLOAT},
+	{declared: "DOUBLE", columnType: "double", dataType: "double", yasType: Y_DOUBLE},
+	{declared: "DOUBLE UNSIGNED", columnType: "double unsigned", dataType: "double", yasType: Y_DOUBLE},
+	{declared: "REAL", columnType: "double", dataType: "double", yasType: Y_DOUBLE},
+	{declared: "BIT(10)", columnType: "bit(10)", dataType: "bit", yasType: Y_BIT},
+	{declared: "DATE", columnType: "date", dataType: "date", yasType: Y_DATE},
+	{declared: "DATETIME", columnType: "datetime", dataType: "datetime", yasType: Y_TIMESTAMP},
+	{declared: "TIMESTAMP", columnType: "timestamp", dataType: "timestamp", yasType: Y_TIMESTAMP},
+	{declared: "TIME", columnType: "time", dataType: "time", yasType: Y_TIME},
+	{declared: "YEAR", columnType: "year", dataType: "year", yasType: Y_SMALLINT},
+	{declared: "CHAR(10)", columnType: "char(10)", dataType: "char", yasType: Y_CHAR},
+	{declared: "VARCHAR(100)", columnType: "varchar(100)", dataType: "varchar", yasType: Y_VARCHAR},
+	{declared: "NATIONAL CHAR(10)", columnType: "char(10)", dataType: "char", yasType: Y_NCHAR},
+	{declared: "NATIONAL VARCHAR(100)", columnType: "varchar(100)", dataType: "varchar", yasType: Y_NVARCHAR},
+	{declared: "BINARY(8)", columnType: "binary(8)", dataType: "binary", yasType: Y_RAW},
+	{declared: "VARBINARY(64)", columnType: "varbinary(64)", dataType: "varbinary", yasType: Y_RAW},
+	{declared: "TINYBLOB", columnType: "tinyblob", dataType: "tinyblob", yasType: Y_BLOB},
+	{declared: "TINYTEXT", columnType: "tinytext", dataType: "tinytext", yasType: Y_CLOB},
+	{declared: "BLOB", columnType: "blob", dataType: "blob", yasType: Y_BLOB},
+	{declared: "TEXT", columnType: "text", dataType: "text", yasType: Y_CLOB},
+	{declared: "MEDIUMBLOB", columnType: "mediumblob", dataType: "mediumblob", yasType: Y_BLOB},
+	{declared: "MEDIUMTEXT", columnType: "mediumtext", dataType: "mediumtext", yasType: Y_CLOB},
+	{declared: "LONGBLOB", columnType: "longblob", dataType: "longblob", yasType: Y_BLOB},
+	{declared: "LONGTEXT", columnType: "longtext", dataType: "longtext", yasType: Y_CLOB},
+	{declared: "JSON", columnType: "json", dataType: "json", yasType: Y_JSON},
+	{declared: "ENUM('small', 'medium', 'large')", columnType: "enum('small','medium','large')", dataType: "enum", yasType: Y_VARCHAR},
+	{declared: "SET('a', 'b', 'c')", columnType: "set('a','b','c')", dataType: "set", yasType: Y_VARCHAR},
+	{declared: "GEOMETRY", columnType: "geometry", dataType: "geometry", yasType: Y_GEOMETRY},
+	{declared: "POINT", columnType: "point", dataType: "point", yasType: Y_GEOMETRY},
+	{declared: "LINESTRING", columnType: "linestring", dataType: "linestring", yasType: Y_GEOMETRY},
+	{declared: "POLYGON", columnType: "polygon", dataType: "polygon", yasType: Y_GEOMETRY},
+	{declared: "MULTIPOINT", columnType: "multipoint", dataType: "multipoint", yasType: Y_GEOMETRY},
+	{declared: "MULTILINESTRING", columnType: "multilinestring", dataType: "multilinestring", yasType: Y_GEOMETRY},
+	{declared: "MULTIPOLYGON", columnType: "multipolygon", dataType: "multipolygon", yasType: Y_GEOMETRY},
+	// MySQL 8.0的information_schema中为geomcollection
+	{declared: "GEOMETRYCOLLECTION", columnType: "geomcollection", dataType: "geomcollection", yasType: Y_GEOMETRY},
+	// mariadb_types.sql
+	{declared: "INET4", columnType: "inet4", dataType: "inet4", yasType: Y_VARCHAR},
+	{declared: "INET6", columnType: "inet6", dataType: "inet6", yasType: Y_VARCHAR},
+	{declared: "UUID", columnType: "uuid", dataType: "uuid", yasType: Y_CHAR},
+}
+
+// TestMySQLToYasType 测试表中每种列类型的声明、column_type和data_type都映射为相同的YashanDB类型,
+// NATIONAL CHAR在information_schema中为char, 按char映射
+func TestMySQLToYasType(t *testing.T) {
+	for _, c := range fixtureTypes {
+		yas, err := MySQLToYasType(c.declared)
+		if err != nil || yas != c.yasType {
+			t.Errorf("MySQLToYasType(%q) = %q, %v, 期望 %q", c.declared, yas, err, c.yasType)
+		}
+		expected := c.yasType
+		switch c.yasType {
+		case Y_NCHAR:
+			expected = Y_CHAR
+		case Y_NVARCHAR:
+			expected = Y_VARCHAR
+		}
+		for _, mysqlType := range []string{c.columnType, c.dataType} {
+			// information_schema中unsigned bigint的data_type为bigint, 只有column_type能区分
+			if c.yasType == Y_NUMBER && mysqlType == M_BIGINT {
+				expected = Y_BIGINT
+			}
+			yas, err := MySQLToYasType(mysqlType)
+			if err != nil || yas != expected {
+				t.Errorf("MySQLToYasType(%q) = %q, %v, 期望 %q", mysqlType, yas, err, expected)
+			}
+		}
+	}
+}
+
+func TestMySQLToYasTypeUnsupported(t *testing.T) {
+	for _, mysqlType := range []string{"", "vector(3)", "int4 unsigned", "xml"} {
+		if yas, err := MySQLToYasType(mysqlType); err == nil {
+			t.Errorf("MySQLToYasType(%q) = %q, 期望返回错误", mysqlType, yas)
+		}
+	}
+}
+
+func TestNormalizeMySQLType(t *testing.T) {
+	cases := []struct {
+		mysqlType string
+		expected  string
+	}{
+		{mysqlType: "INT(10) UNSIGNED ZEROFILL", expected: "int unsigned"},
+		{mysqlType: "MEDIUMINT(8) UNSIGNED ZEROFILL", expected: "mediumint unsigned"},
+		{mysqlType: "INTEGER(10) ZEROFILL", expected: "integer"},
+		{mysqlType: "bigint signed", expected: "bigint"},
+		{mysqlType: "DECIMAL(18, 4)", expected: "decimal"},
+		{mysqlType: "decimal(10,2) unsigned", expected: "decimal unsigned"},
+		{mysqlType: "NATIONAL VARCHAR(100)", expected: "national varchar"},
+		{mysqlType: "character varying(20)", expected: "character varying"},
+		{mysqlType: "varchar(64) character set gbk collate gbk_chinese_ci", expected: "varchar"},
+		{mysqlType: "text charset utf8mb4", expected: "text"},
+		{mysqlType: "enum('a(1)','b)')", expected: "enum"},
+		{mysqlType: "set('a','b','c')", expected: "set"},
+		{mysqlType: "  Double   Precision ", expected: "double precision"},
+	}
+	for _, c := range cases {
+		if actual := NormalizeMySQLType(c.mysqlType); actual != c.expected {
+			t.Errorf("NormalizeMySQLType(%q) = %q, 期望 %q", c.mysqlType, actual, c.expected)
+		}
+	}
+}
+
+func TestDefaultCharLength(t *testing.T) {
+	cases := map[string]int64{"inet4": 15, "INET6": 39, "uuid": 36}
+	for mysqlType, expected := range cases {
+		if length, ok := DefaultCharLength(mysqlType); !ok || length != expected {
+			t.Errorf("DefaultCharLength(%q) = %d, %v, 期望 %d", mysqlType, length, ok, expected)
+		}
+	}
+	if length, ok := DefaultCharLength("varchar"); ok {
+		t.Errorf("DefaultCharLength(\"varchar\") = %d, 期望没有默认长度", length)
+	}
+}
+
+// TestFixtureTypesCovered test/sql中新增的列类型需要加入fixtureTypes
+func TestFixtureTypesCovered(t *testing.T) {
+	covered := make(map[string]bool)
+	for _, c := range fixtureTypes {
+		covered[c.declared] = true
+	}
+	files, err := filepath.Glob(filepath.Join("..", "..", "test", "sql", "*.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("test/sql中没有建表语句")
+	}
+	for _, file := range files {
+		for _, declared := range readDeclaredTypes(t, file) {
+			if !covered[declared] {
+				t.Errorf("%s 中的列类型 %s 没有测试", filepath.Base(file), declared)
+			}
+		}
+	}
+}
+
+// readDeclaredTypes 返回文件中CREATE TABLE语句声明的列类型, 去掉PRIMARY KEY和NULL等列属性
+func readDeclaredTypes(t *testing.T, file string) []string {
+	t.Helper()
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var types []string
+	inTable := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "CREATE TABLE") {
+			inTable = true
+			continue
+		}
+		if !inTable {
+			continue
+		}
+		if strings.HasPrefix(line, ")") {
+			inTable = false
+			continue
+		}
+		fields := strings.Fields(strings.TrimSuffix(line, ","))
+		if len(fields) < 2 {
+			continue
+		}
+		declared := strings.Join(fields[1:], " ")
+		for _, suffix := range []string{" PRIMARY KEY", " NOT NULL", " NULL"} {
+			declared = strings.TrimSuffix(declared, suffix)
+		}
+		types = append(types, declared)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return types
+}
@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"m2y/db"
+	"m2y/defs/confdef"
+	"m2y/internal/api/handler"
+)
+
+type M2YExportDataCmd struct {
+	Parallel      int `name:"parallel"       short:"p" help:"Parallel number of export data."`
+	TableParallel int `name:"table-parallel" short:"t" help:"Parallel number of export data per table."`
+	FileRows      int `name:"file-rows"      short:"r" help:"Max rows of each exported file."`
+}
+
+func (c *M2YExportDataCmd) Run() error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+	if err := c.initDB(); err != nil {
+		return err
+	}
+	parallel, tableParallel, fileRows := c.getExportArgs()
+	return handler.NewExportDataHandler(parallel, tableParallel, fileRows).ExportData()
+}
+
+func (c *M2YExportDataCmd) validate() error {
+	return nil
+}
+
+func (c *M2YExportDataCmd) initDB() error {
+	if err := db.LoadMySQLDB(confdef.GetM2YConfig().MySQL); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *M2YExportDataCmd) getExportArgs() (parallel, tableParallel, fileRows int) {
+	conf := confdef.GetM2YConfig().MySQL
+	parallel = getArgs(c.Parallel, conf.Parallel, confdef.DefaultParallel, confdef.MaxParallel)
+	tableParallel = getArgs(c.TableParallel, conf.ParallelPerTable, confdef.DefaultParallelPerTable, confdef.MaxParallel)
+	fileRows = getArgs(c.FileRows, 0, confdef.DefaultFileRows, 0)
+	return
+}
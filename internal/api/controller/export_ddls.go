@@ -6,7 +6,9 @@ import (
 	"m2y/internal/api/handler"
 )
 
-type M2YExportDDLsCmd struct{}
+type M2YExportDDLsCmd struct {
+	Apply bool `name:"apply" help:"Execute the exported DDLs in YashanDB after exporting."`
+}
 
 func (c *M2YExportDDLsCmd) Run() error {
 	if err := c.validate(); err != nil {
@@ -15,7 +17,7 @@ func (c *M2YExportDDLsCmd) Run() error {
 	if err := c.initDB(); err != nil {
 		return err
 	}
-	return handler.NewExportDDLsHandler().ExportDDLs()
+	return handler.NewExportDDLsHandler(c.Apply).ExportDDLs()
 }
 
 func (c *M2YExportDDLsCmd) validate() error {
@@ -26,5 +28,10 @@ func (c *M2YExportDDLsCmd) initDB() error {
 	if err := db.LoadMySQLDB(confdef.GetM2YConfig().MySQL); err != nil {
 		return err
 	}
+	if c.Apply {
+		if err := db.LoadYashanDB(confdef.GetM2YConfig().Yashan); err != nil {
+			return err
+		}
+	}
 	return nil
 }
@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"m2y/db"
+	"m2y/defs/confdef"
+	"m2y/internal/api/handler"
+)
+
+type M2YLoadDataCmd struct {
+	Parallel      int `name:"parallel"       short:"p" help:"Parallel number of load data."`
+	BatchSize     int `name:"batch-size"     short:"b" help:"Batch size of load data."`
+	TableParallel int `name:"table-parallel" short:"t" help:"Parallel number of load data per table."`
+}
+
+func (c *M2YLoadDataCmd) Run() error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+	if err := c.initDB(); err != nil {
+		return err
+	}
+	parallel, tableParallel, batchSize := c.getLoadArgs()
+	return handler.NewLoadDataHandler(parallel, tableParallel, batchSize).LoadData()
+}
+
+func (c *M2YLoadDataCmd) validate() error {
+	if len(confdef.GetM2YConfig().Yashan.RemapSchemas) == 0 {
+		return confdef.ErrNeedRemapSchemas
+	}
+	return nil
+}
+
+func (c *M2YLoadDataCmd) initDB() error {
+	if err := db.LoadYashanDB(confdef.GetM2YConfig().Yashan); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *M2YLoadDataCmd) getLoadArgs() (parallel, tableParallel, batchSize int) {
+	conf := confdef.GetM2YConfig().MySQL
+	parallel = getArgs(c.Parallel, conf.Parallel, confdef.DefaultParallel, confdef.MaxParallel)
+	tableParallel = getArgs(c.TableParallel, conf.ParallelPerTable, confdef.DefaultParallelPerTable, confdef.MaxParallel)
+	batchSize = getArgs(c.BatchSize, conf.BatchSize, confdef.DefaultBatchSize, 0)
+	return
+}
@@ -0,0 +1,30 @@
+package controller
+
+import (
+	"m2y/db"
+	"m2y/defs/confdef"
+	"m2y/internal/api/handler"
+)
+
+type M2YPrecheckCmd struct {
+	Parallel int `name:"parallel" short:"p" help:"Parallel number of precheck."`
+}
+
+func (c *M2YPrecheckCmd) Run() error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+	if err := c.initDB(); err != nil {
+		return err
+	}
+	parallel := getArgs(c.Parallel, confdef.GetM2YConfig().MySQL.Parallel, confdef.DefaultParallel, confdef.MaxParallel)
+	return handler.NewPrecheckHandler(parallel).Precheck()
+}
+
+func (c *M2YPrecheckCmd) validate() error {
+	return nil
+}
+
+func (c *M2YPrecheckCmd) initDB() error {
+	return db.LoadMySQLDB(confdef.GetM2YConfig().MySQL)
+}
@@ -0,0 +1,44 @@
+package controller
+
+import (
+	"m2y/db"
+	"m2y/defs/confdef"
+	"m2y/internal/api/handler"
+)
+
+type M2YReplicateCmd struct {
+	ServerID   uint32 `name:"server-id"   help:"Server id used when connecting to MySQL as a replication client."`
+	GTID       bool   `name:"gtid"        help:"Start from the recorded GTID set instead of binlog file and position."`
+	BinlogFile string `name:"binlog-file" help:"Apply a local binlog file instead of connecting to MySQL."`
+}
+
+func (c *M2YReplicateCmd) Run() error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+	if err := c.initDB(); err != nil {
+		return err
+	}
+	return handler.NewReplicateHandler(c.getServerID(), c.GTID, c.BinlogFile).Replicate()
+}
+
+func (c *M2YReplicateCmd) validate() error {
+	if len(confdef.GetM2YConfig().Yashan.RemapSchemas) == 0 {
+		return confdef.ErrNeedRemapSchemas
+	}
+	return nil
+}
+
+func (c *M2YReplicateCmd) initDB() error {
+	if err := db.LoadMySQLDB(confdef.GetM2YConfig().MySQL); err != nil {
+		return err
+	}
+	if err := db.LoadYashanDB(confdef.GetM2YConfig().Yashan); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *M2YReplicateCmd) getServerID() uint32 {
+	return uint32(getArgs(int(c.ServerID), int(confdef.GetM2YConfig().MySQL.ServerID), confdef.DefaultServerID, 0))
+}
@@ -7,9 +7,11 @@ import (
 )
 
 type M2YSyncDataCmd struct {
-	Parallel      int `name:"parallel"       short:"p" help:"Parallel number of sync data."`
-	BatchSize     int `name:"batch-size"     short:"b" help:"Batch size of sync data."`
-	TableParallel int `name:"table-parallel" short:"t" help:"Parallel number of sync data per table."`
+	Parallel      int  `name:"parallel"       short:"p" help:"Parallel number of sync data."`
+	BatchSize     int  `name:"batch-size"     short:"b" help:"Batch size of sync data."`
+	TableParallel int  `name:"table-parallel" short:"t" help:"Parallel number of sync data per table."`
+	Resume        bool `name:"resume"                   help:"Resume sync from the last checkpoint, skip finished tables."`
+	Snapshot      bool `name:"consistent-snapshot"      help:"Read all tables from one consistent snapshot, requires the RELOAD privilege."`
 }
 
 func (c *M2YSyncDataCmd) Run() error {
@@ -19,7 +21,9 @@ func (c *M2YSyncDataCmd) Run() error {
 	if err := c.initDB(); err != nil {
 		return err
 	}
-	return handler.NewSyncDataHandler(c.getSyncArgs()).SyncData()
+	parallel, tableParallel, batchSize := c.getSyncArgs()
+	snapshot := c.Snapshot || confdef.GetM2YConfig().MySQL.ConsistentSnapshot
+	return handler.NewSyncDataHandler(parallel, tableParallel, batchSize, c.Resume, snapshot).SyncData()
 }
 
 func (c *M2YSyncDataCmd) validate() error {
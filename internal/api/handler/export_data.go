@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"m2y/db"
+	"m2y/defs/confdef"
+	"m2y/internal/modules"
+	"m2y/log"
+)
+
+type ExportDataHandler struct {
+	parallel      int
+	tableParallel int
+	fileRows      int
+}
+
+func NewExportDataHandler(parallel, tableParallel, fileRows int) *ExportDataHandler {
+	return &ExportDataHandler{parallel: parallel, tableParallel: tableParallel, fileRows: fileRows}
+}
+
+func (c *ExportDataHandler) ExportData() error {
+	log.Logger.Infof("parallel: %d\ttableParallel: %d\tfileRows: %d\t", c.parallel, c.tableParallel, c.fileRows)
+	conf := confdef.GetM2YConfig()
+	if len(conf.MySQL.Tables) != 0 {
+		return modules.ExportTableData(db.MySQLDB, conf.MySQL.Database, conf.MySQL.Tables, c.parallel, c.tableParallel, c.fileRows)
+	}
+	return modules.ExportSchemasData(db.MySQLDB, conf.MySQL.Schemas, conf.Yashan.RemapSchemas, conf.MySQL.ExcludeTables, c.parallel, c.tableParallel, c.fileRows)
+}
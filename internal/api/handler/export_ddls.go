@@ -1,21 +1,29 @@
 package handler
 
 import (
+	"database/sql"
+
 	"m2y/db"
 	"m2y/defs/confdef"
 	"m2y/internal/modules"
 )
 
-type ExportDDLsHandler struct{}
+type ExportDDLsHandler struct {
+	apply bool
+}
 
-func NewExportDDLsHandler() *ExportDDLsHandler {
-	return &ExportDDLsHandler{}
+func NewExportDDLsHandler(apply bool) *ExportDDLsHandler {
+	return &ExportDDLsHandler{apply: apply}
 }
 
 func (c *ExportDDLsHandler) ExportDDLs() error {
 	config := confdef.GetM2YConfig()
+	var yasdb *sql.DB
+	if c.apply {
+		yasdb = db.YashanDB
+	}
 	if len(config.MySQL.Tables) != 0 {
-		return modules.DealTablesDDLs(db.MySQLDB, config.MySQL.Database, config.Yashan.RemapSchemas[0], config.MySQL.Schemas, false)
+		return modules.DealTablesDDLs(db.MySQLDB, yasdb, config.MySQL.Database, config.Yashan.RemapSchemas[0], config.MySQL.Tables, false)
 	}
-	return modules.DealSchemasDDL(db.MySQLDB, config.MySQL.Schemas, config.Yashan.RemapSchemas, config.MySQL.ExcludeTables)
+	return modules.DealSchemasDDL(db.MySQLDB, yasdb, config.MySQL.Schemas, config.Yashan.RemapSchemas, config.MySQL.ExcludeTables)
 }
@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"m2y/db"
+	"m2y/defs/confdef"
+	"m2y/internal/modules"
+	"m2y/log"
+)
+
+type LoadDataHandler struct {
+	parallel      int
+	tableParallel int
+	batchSize     int
+}
+
+func NewLoadDataHandler(parallel, tableParallel, batchSize int) *LoadDataHandler {
+	return &LoadDataHandler{parallel: parallel, tableParallel: tableParallel, batchSize: batchSize}
+}
+
+func (c *LoadDataHandler) LoadData() error {
+	log.Logger.Infof("parallel: %d\ttableParallel: %d\tbatchSize: %d\t", c.parallel, c.tableParallel, c.batchSize)
+	conf := confdef.GetM2YConfig()
+	if len(conf.MySQL.Tables) != 0 {
+		return modules.LoadTableData(db.YashanDB, conf.MySQL.Database, conf.Yashan.RemapSchemas[0], conf.MySQL.Tables, c.parallel, c.tableParallel, c.batchSize)
+	}
+	return modules.LoadSchemasData(db.YashanDB, conf.MySQL.Schemas, conf.Yashan.RemapSchemas, conf.MySQL.ExcludeTables, c.parallel, c.tableParallel, c.batchSize)
+}
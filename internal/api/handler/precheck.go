@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"m2y/db"
+	"m2y/defs/confdef"
+	"m2y/internal/modules"
+)
+
+type PrecheckHandler struct {
+	parallel int
+}
+
+func NewPrecheckHandler(parallel int) *PrecheckHandler {
+	return &PrecheckHandler{parallel: parallel}
+}
+
+func (c *PrecheckHandler) Precheck() error {
+	conf := confdef.GetM2YConfig()
+	var res [][]string
+	var err error
+	if len(conf.MySQL.Tables) != 0 {
+		res, err = modules.PrecheckTables(db.MySQLDB, conf.MySQL.Database, conf.MySQL.Tables, c.parallel)
+	} else {
+		res, err = modules.PrecheckSchemas(db.MySQLDB, conf.MySQL.Schemas, conf.MySQL.ExcludeTables, c.parallel)
+	}
+	if err != nil {
+		return err
+	}
+	modules.PrintPrecheckResults(res)
+	return nil
+}
@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"m2y/db"
+	"m2y/defs/confdef"
+	"m2y/internal/modules"
+	"m2y/log"
+)
+
+type ReplicateHandler struct {
+	opts modules.ReplicateOptions
+}
+
+func NewReplicateHandler(serverID uint32, useGTID bool, binlogFile string) *ReplicateHandler {
+	return &ReplicateHandler{opts: modules.ReplicateOptions{ServerID: serverID, UseGTID: useGTID, BinlogFile: binlogFile}}
+}
+
+func (c *ReplicateHandler) Replicate() error {
+	log.Logger.Infof("serverID: %d\tgtid: %t\tbinlogFile: %s\t", c.opts.ServerID, c.opts.UseGTID, c.opts.BinlogFile)
+	conf := confdef.GetM2YConfig()
+	if len(conf.MySQL.Tables) != 0 {
+		return modules.ReplicateTableData(db.MySQLDB, db.YashanDB, conf.MySQL.Database, conf.Yashan.RemapSchemas[0], conf.MySQL.Tables, c.opts)
+	}
+	return modules.ReplicateSchemasData(db.MySQLDB, db.YashanDB, conf.MySQL.Schemas, conf.Yashan.RemapSchemas, conf.MySQL.ExcludeTables, c.opts)
+}
@@ -11,17 +11,19 @@ type SyncDataHandler struct {
 	parallel      int
 	tableParallel int
 	batchSize     int
+	resume        bool
+	snapshot      bool
 }
 
-func NewSyncDataHandler(parallel, tableParallel, batchSize int) *SyncDataHandler {
-	return &SyncDataHandler{parallel: parallel, tableParallel: tableParallel, batchSize: batchSize}
+func NewSyncDataHandler(parallel, tableParallel, batchSize int, resume, snapshot bool) *SyncDataHandler {
+	return &SyncDataHandler{parallel: parallel, tableParallel: tableParallel, batchSize: batchSize, resume: resume, snapshot: snapshot}
 }
 
 func (c *SyncDataHandler) SyncData() error {
-	log.Logger.Infof("parallel: %d\ttableParallel: %d\tbatchSize: %d\t", c.parallel, c.tableParallel, c.batchSize)
+	log.Logger.Infof("parallel: %d\ttableParallel: %d\tbatchSize: %d\tresume: %t\tconsistentSnapshot: %t\t", c.parallel, c.tableParallel, c.batchSize, c.resume, c.snapshot)
 	conf := confdef.GetM2YConfig()
 	if len(conf.MySQL.Tables) != 0 {
-		return modules.DealTableData(db.MySQLDB, db.YashanDB, conf.MySQL.Database, conf.Yashan.RemapSchemas[0], conf.MySQL.Tables, c.parallel, c.tableParallel, c.batchSize)
+		return modules.DealTableData(db.MySQLDB, db.YashanDB, conf.MySQL.Database, conf.Yashan.RemapSchemas[0], conf.MySQL.Tables, c.parallel, c.tableParallel, c.batchSize, c.resume, c.snapshot)
 	}
-	return modules.DealSchemasData(db.MySQLDB, db.YashanDB, conf.MySQL.Schemas, conf.Yashan.RemapSchemas, conf.MySQL.ExcludeTables, c.parallel, c.tableParallel, c.batchSize)
+	return modules.DealSchemasData(db.MySQLDB, db.YashanDB, conf.MySQL.Schemas, conf.Yashan.RemapSchemas, conf.MySQL.ExcludeTables, c.parallel, c.tableParallel, c.batchSize, c.resume, c.snapshot)
 }
@@ -0,0 +1,199 @@
+package modules
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"m2y/log"
+)
+
+// DDL的执行阶段, 按顺序执行: 表 -> 非空约束和索引 -> 外键 -> 视图 -> 触发器、存储过程、作业等PL对象
+const (
+	ddl_phase_tables = iota
+	ddl_phase_indexes
+	ddl_phase_foreign_keys
+	ddl_phase_views
+	ddl_phase_programs
+	ddl_phase_count
+)
+
+// 执行结果按对象类型统计
+const (
+	ddl_object_table        = "TABLE"
+	ddl_object_comment      = "COMMENT"
+	ddl_object_sequence     = "SEQUENCE"
+	ddl_object_default      = "DEFAULT"
+	ddl_object_not_null     = "NOT NULL"
+	ddl_object_primary_key  = "PRIMARY KEY"
+	ddl_object_unique       = "UNIQUE CONSTRAINT"
+	ddl_object_check        = "CHECK CONSTRAINT"
+	ddl_object_unique_index = "UNIQUE INDEX"
+	ddl_object_index        = "INDEX"
+	ddl_object_foreign_key  = "FOREIGN KEY"
+	ddl_object_view         = "VIEW"
+	ddl_object_trigger      = "TRIGGER"
+	ddl_object_procedure    = "PROCEDURE"
+	ddl_object_function     = "FUNCTION"
+	ddl_object_job          = "JOB"
+	ddl_object_other        = "OTHER"
+)
+
+type ddlStatement struct {
+	objectType string
+	objectName string
+	sql        string
+	pl         bool // PL对象以END;结尾, 执行时保留分号
+}
+
+type ddlApplyResult struct {
+	created int
+	failed  int
+}
+
+// ddlApplier 收集导出的DDL, 导出完成后按阶段在YashanDB中执行, 为nil时不执行
+type ddlApplier struct {
+	yasdb        *sql.DB
+	phases       [ddl_phase_count][]ddlStatement
+	objectTypes  []string
+	results      map[string]*ddlApplyResult
+	failures     [][]string
+	failedTables map[string]struct{}
+}
+
+func newDDLApplier(yasdb *sql.DB) *ddlApplier {
+	if yasdb == nil {
+		return nil
+	}
+	return &ddlApplier{
+		yasdb:        yasdb,
+		results:      make(map[string]*ddlApplyResult),
+		failedTables: make(map[string]struct{}),
+	}
+}
+
+// add 记录对象objectName的DDL, 同一个对象的语句按添加的顺序执行
+func (a *ddlApplier) add(phase int, objectName string, sqls ...string) {
+	if a == nil {
+		return
+	}
+	for _, s := range sqls {
+		if len(strings.TrimSpace(s)) == 0 {
+			continue
+		}
+		a.phases[phase] = append(a.phases[phase], ddlStatement{objectType: getDDLObjectType(s), objectName: objectName, sql: s})
+	}
+}
+
+// addPL 记录对象objectName的PL定义, 如触发器
+func (a *ddlApplier) addPL(phase int, objectName string, pl string) {
+	if a == nil {
+		return
+	}
+	a.phases[phase] = append(a.phases[phase], ddlStatement{objectType: getDDLObjectType(pl), objectName: objectName, sql: pl, pl: true})
+}
+
+// apply 按阶段执行收集的DDL, 建表失败时跳过该表后续阶段的语句, 执行完成后输出统计信息
+func (a *ddlApplier) apply() {
+	if a == nil {
+		return
+	}
+	log.Logger.Infof("开始在YashanDB中执行DDL......")
+	for phase, statements := range a.phases {
+		for _, stmt := range statements {
+			if _, ok := a.failedTables[stmt.objectName]; ok && phase != ddl_phase_tables {
+				a.record(stmt, fmt.Errorf("表 %s 创建失败, 跳过", stmt.objectName))
+				continue
+			}
+			query := trimDDLTerminator(stmt.sql)
+			if stmt.pl {
+				query = strings.TrimSpace(stmt.sql)
+			}
+			_, err := a.yasdb.Exec(query)
+			if err != nil && phase == ddl_phase_tables && stmt.objectType == ddl_object_table {
+				a.failedTables[stmt.objectName] = struct{}{}
+			}
+			a.record(stmt, err)
+		}
+	}
+	a.printSummary()
+}
+
+func (a *ddlApplier) record(stmt ddlStatement, err error) {
+	result, ok := a.results[stmt.objectType]
+	if !ok {
+		result = &ddlApplyResult{}
+		a.results[stmt.objectType] = result
+		a.objectTypes = append(a.objectTypes, stmt.objectType)
+	}
+	if err == nil {
+		result.created++
+		return
+	}
+	result.failed++
+	log.Logger.Errorf("%s %s 创建失败, sql: %s, err: %v", stmt.objectType, stmt.objectName, strings.TrimSpace(stmt.sql), err)
+	a.failures = append(a.failures, []string{stmt.objectType, stmt.objectName, err.Error()})
+}
+
+func (a *ddlApplier) printSummary() {
+	var created, failed int
+	var data [][]string
+	for _, objectType := range a.objectTypes {
+		result := a.results[objectType]
+		created += result.created
+		failed += result.failed
+		data = append(data, []string{objectType, strconv.Itoa(result.created), strconv.Itoa(result.failed)})
+	}
+	log.Logger.Infof("DDL执行完成, 成功: %d 失败: %d", created, failed)
+	printTable("DDL执行结果统计如下：\n", []string{"Object-Type", "Created", "Failed"}, data)
+	printTable("执行失败的对象如下：\n", []string{"Object-Type", "Object-Name", "Error"}, a.failures)
+}
+
+// getDDLObjectType 按语句的开头判断DDL创建的对象类型, 用于统计执行结果
+func getDDLObjectType(s string) string {
+	upper := strings.ToUpper(strings.TrimSpace(s))
+	switch {
+	case strings.HasPrefix(upper, "CREATE TABLE"):
+		return ddl_object_table
+	case strings.HasPrefix(upper, "COMMENT ON"):
+		return ddl_object_comment
+	case strings.HasPrefix(upper, "CREATE SEQUENCE"):
+		return ddl_object_sequence
+	case strings.HasPrefix(upper, "CREATE UNIQUE INDEX"):
+		return ddl_object_unique_index
+	case strings.HasPrefix(upper, "CREATE INDEX"):
+		return ddl_object_index
+	case strings.HasPrefix(upper, "CREATE VIEW"):
+		return ddl_object_view
+	case strings.HasPrefix(upper, "CREATE OR REPLACE TRIGGER"):
+		return ddl_object_trigger
+	case strings.HasPrefix(upper, "CREATE OR REPLACE PROCEDURE"):
+		return ddl_object_procedure
+	case strings.HasPrefix(upper, "CREATE OR REPLACE FUNCTION"):
+		return ddl_object_function
+	case strings.HasPrefix(upper, "BEGIN") && strings.Contains(upper, "DBMS_SCHEDULER.CREATE_JOB"):
+		return ddl_object_job
+	case strings.HasPrefix(upper, "ALTER TABLE"):
+		switch {
+		case strings.Contains(upper, " CHECK ("):
+			return ddl_object_check
+		case strings.Contains(upper, " FOREIGN KEY "):
+			return ddl_object_foreign_key
+		case strings.Contains(upper, " PRIMARY KEY "):
+			return ddl_object_primary_key
+		case strings.Contains(upper, " UNIQUE "):
+			return ddl_object_unique
+		case strings.Contains(upper, " NOT NULL"):
+			return ddl_object_not_null
+		case strings.Contains(upper, " DEFAULT "):
+			return ddl_object_default
+		}
+	}
+	return ddl_object_other
+}
+
+// trimDDLTerminator 去掉导出文件中语句结尾的分号, 通过驱动执行时不能带分号
+func trimDDLTerminator(s string) string {
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), ";"))
+}
@@ -0,0 +1,146 @@
+package modules
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+
+	"m2y/log"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/encoding/unicode/utf32"
+)
+
+const (
+	mysql_charset_binary = "binary"
+	mysql_charset_latin1 = "latin1"
+	mysql_charset_ascii  = "ascii"
+)
+
+var (
+	// 与UTF-8兼容的字符集, 不需要转码
+	mysqlUTF8Charsets = []string{"utf8", "utf8mb3", "utf8mb4", mysql_charset_ascii, mysql_charset_binary}
+
+	// MySQL字符集对应的编码, 没有列出的字符集(如dec8、swe7、armscii8)无法在增量同步时转码
+	mysqlCharsetEncodings = map[string]encoding.Encoding{
+		// MySQL的latin1实际为cp1252
+		mysql_charset_latin1: charmap.Windows1252,
+		"latin2":             charmap.ISO8859_2,
+		"latin5":             charmap.ISO8859_9,
+		"latin7":             charmap.ISO8859_13,
+		"greek":              charmap.ISO8859_7,
+		"hebrew":             charmap.ISO8859_8,
+		"tis620":             charmap.Windows874,
+		"koi8r":              charmap.KOI8R,
+		"koi8u":              charmap.KOI8U,
+		"cp850":              charmap.CodePage850,
+		"cp852":              charmap.CodePage852,
+		"cp866":              charmap.CodePage866,
+		"cp1250":             charmap.Windows1250,
+		"cp1251":             charmap.Windows1251,
+		"cp1256":             charmap.Windows1256,
+		"cp1257":             charmap.Windows1257,
+		"macroman":           charmap.Macintosh,
+		"gb2312":             simplifiedchinese.GBK,
+		"gbk":                simplifiedchinese.GBK,
+		"gb18030":            simplifiedchinese.GB18030,
+		"big5":               traditionalchinese.Big5,
+		"ujis":               japanese.EUCJP,
+		"eucjpms":            japanese.EUCJP,
+		"sjis":               japanese.ShiftJIS,
+		"cp932":              japanese.ShiftJIS,
+		"euckr":              korean.EUCKR,
+		"ucs2":               unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM),
+		"utf16":              unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM),
+		"utf16le":            unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM),
+		"utf32":              utf32.UTF32(utf32.BigEndian, utf32.IgnoreBOM),
+	}
+)
+
+// getMySQLCharsetEncoding 返回MySQL字符集的编码, 与UTF-8兼容的字符集返回nil, 不支持的字符集返回false
+func getMySQLCharsetEncoding(charset string) (encoding.Encoding, bool) {
+	charset = strings.ToLower(strings.TrimSpace(charset))
+	if len(charset) == 0 || inArrayStr(charset, mysqlUTF8Charsets) {
+		return nil, true
+	}
+	enc, ok := mysqlCharsetEncodings[charset]
+	return enc, ok
+}
+
+// decodeMySQLCharset 将按enc编码的字节转换为UTF-8, 无效的字节转换为U+FFFD
+func decodeMySQLCharset(data []byte, enc encoding.Encoding) ([]byte, error) {
+	if enc == nil {
+		return data, nil
+	}
+	if enc == charmap.Windows1252 {
+		// cp1252中未定义的0x81、0x8D、0x8F、0x90、0x9D在MySQL的latin1中对应相同的码位
+		decoded := make([]byte, 0, len(data))
+		for _, b := range data {
+			r := charmap.Windows1252.DecodeByte(b)
+			if r == utf8.RuneError {
+				r = rune(b)
+			}
+			decoded = utf8.AppendRune(decoded, r)
+		}
+		return decoded, nil
+	}
+	return enc.NewDecoder().Bytes(data)
+}
+
+// isCharsetCheckRequired 字符集中是否存在无效的字节序列, latin1和binary的任意字节都有效
+func isCharsetCheckRequired(charset string) bool {
+	switch strings.ToLower(charset) {
+	case mysql_charset_latin1, mysql_charset_binary:
+		return false
+	}
+	_, ok := getMySQLCharsetEncoding(charset)
+	return ok
+}
+
+// isValidInCharset 字节是否为字符集charset中有效的字节序列
+func isValidInCharset(data []byte, charset string) bool {
+	charset = strings.ToLower(charset)
+	if charset == mysql_charset_ascii {
+		for _, b := range data {
+			if b >= utf8.RuneSelf {
+				return false
+			}
+		}
+		return true
+	}
+	enc, ok := getMySQLCharsetEncoding(charset)
+	if !ok {
+		return true
+	}
+	if enc == nil {
+		return utf8.Valid(data)
+	}
+	decoded, err := decodeMySQLCharset(data, enc)
+	if err != nil {
+		return false
+	}
+	if !bytes.ContainsRune(decoded, utf8.RuneError) {
+		return true
+	}
+	// 数据中本来就有U+FFFD时, 重新编码后与原始字节相同
+	encoded, err := enc.NewEncoder().Bytes(decoded)
+	return err == nil && bytes.Equal(encoded, data)
+}
+
+// logColumnCharset 非UTF-8字符集的列在同步时转换为UTF-8, 增量同步无法转码的字符集输出警告
+func logColumnCharset(mysqlSchema, tableName, columnName, charset, collation string) {
+	enc, ok := getMySQLCharsetEncoding(charset)
+	if !ok {
+		log.Logger.Warnf("表 %s.%s 列 %s 的字符集 %s 不支持转码, 全量同步由MySQL转换为UTF-8, 增量同步按原始字节写入", mysqlSchema, tableName, columnName, charset)
+		return
+	}
+	if enc != nil {
+		log.Logger.Infof("表 %s.%s 列 %s 的字符集为 %s, 排序规则为 %s, 同步时转换为UTF-8", mysqlSchema, tableName, columnName, charset, collation)
+	}
+}
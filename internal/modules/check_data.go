@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"math/big"
 	"os"
 	"strconv"
 	"strings"
@@ -239,6 +240,12 @@ func getMySQLTableData(db *sql.DB, mysqlSchema, tableName string, pkColumnNames
 		query = fmt.Sprintf(sqldef.M_SQL_QUERY_ORDER_RAND_LIMIT, query, sampleLine)
 	}
 
+	// 按[type_mapping]改变了类型的列按同步时写入的值比较
+	typeMappings, err := getMySQLColumnTypeMappings(db, mysqlSchema, tableName)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	rows, err := db.Query(query)
 	if err != nil {
 		return nil, nil, err
@@ -280,7 +287,7 @@ func getMySQLTableData(db *sql.DB, mysqlSchema, tableName string, pkColumnNames
 		mysqlValues := make([]interface{}, len(values))
 		for i, value := range values {
 			// fmt.Println(columns[i].ColumnType)
-			mysqlValues[i] = convertToMySQLType(value, columns[i].ColumnType)
+			mysqlValues[i] = convertToMySQLTypeWithMapping(value, columns[i].ColumnType, typeMappings[columns[i].ColumnName])
 		}
 
 		for i, column := range columns {
@@ -376,8 +383,25 @@ func isDataEqual(v1, v2 any) bool {
 	case time.Time:
 		switch value2 := v2.(type) {
 		case time.Time:
-			return value1.Equal(value2)
+			return value1.Equal(inTargetLocation(value2))
 		}
+	case bool:
+		// 映射为boolean的列, YashanDB可能返回布尔值、数字或文本
+		if str, ok := v2.(string); ok {
+			v2 = []uint8(str)
+		}
+		value2, err := convertValueToBoolean(v2, "")
+		return err == nil && value2 == value1
+	case *timeValue:
+		return isTimeEqual(value1.duration, v2)
+	case *spatialValue:
+		return isSpatialEqual(value1, v2)
+	case *decimalValue:
+		return isDecimalEqual(value1, v2)
+	case int64:
+		return isDecimalEqual(&decimalValue{text: fmt.Sprint(value1), rat: new(big.Rat).SetInt64(value1)}, v2)
+	case uint64:
+		return isDecimalEqual(&decimalValue{text: fmt.Sprint(value1), rat: new(big.Rat).SetUint64(value1)}, v2)
 	}
 	return fmt.Sprint(v1) == fmt.Sprint(v2)
 }
@@ -418,14 +442,14 @@ func compareTableRowData(row1 tableData, row2 tableData, columnNames []string, t
 func convertToMySQLType(value interface{}, columnType string) interface{} {
 	if value != nil {
 		switch columnType {
-		case "DATETIME", "TIMESTAMP":
-			t, _ := time.Parse("2006-01-02 15:04:05", string(value.([]uint8)))
-			cstLocation, _ := time.LoadLocation("Asia/Shanghai")
-			return t.In(cstLocation)
-		case "DATE":
-			t, _ := time.Parse("2006-01-02", string(value.([]uint8)))
-			cstLocation, _ := time.LoadLocation("Asia/Shanghai")
-			return t.In(cstLocation)
+		case "DATETIME", "TIMESTAMP", "DATE":
+			// 与同步时的转换一致, 无法转换时按原文本比较
+			str := string(value.([]uint8))
+			t, err := convertMySQLDateTime(str, columnType)
+			if err != nil {
+				return str
+			}
+			return t
 		case "YEAR":
 			var v string
 			switch val := value.(type) {
@@ -439,10 +463,35 @@ func convertToMySQLType(value interface{}, columnType string) interface{} {
 			year, _ := strconv.ParseInt(v, 10, 64)
 			return year
 		case "TIME":
-			t, _ := time.Parse("2006-01-02 15:04:05", "1970-01-01 "+string(value.([]uint8)))
-			cstLocation, _ := time.LoadLocation("Asia/Shanghai")
-			return t.In(cstLocation)
-		case "BINARY", "VARBINARY", "BLOB":
+			// 按时长比较, 目标端可能是TIME或INTERVAL DAY TO SECOND
+			str := string(value.([]uint8))
+			d, err := parseMySQLTime(str)
+			if err != nil {
+				return str
+			}
+			return &timeValue{text: str, duration: d}
+		case "BINARY", "VARBINARY", "BLOB", "TINYBLOB", "MEDIUMBLOB", "LONGBLOB":
+			return value
+		case "GEOMETRY":
+			// 按WKT和SRID比较空间数据
+			if data, ok := value.([]uint8); ok {
+				if geometry, err := decodeMySQLGeometry(data); err == nil {
+					return geometry
+				}
+			}
+			return value
+		case "ENUM", "SET":
+			// 按取值的文本比较, 不使用ENUM的序号
+			if str, ok := value.([]uint8); ok {
+				return string(str)
+			}
+			return fmt.Sprint(value)
+		case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "BIGINT", "UNSIGNED TINYINT", "UNSIGNED SMALLINT", "UNSIGNED MEDIUMINT", "UNSIGNED INT", "UNSIGNED BIGINT":
+			// ZEROFILL的列查询结果带有前导0
+			str, ok := value.([]uint8)
+			if ok {
+				return convertMySQLInteger(string(str))
+			}
 			return value
 		case "BIT":
 			return convertBitToString(value.([]uint8))
@@ -452,22 +501,29 @@ func convertToMySQLType(value interface{}, columnType string) interface{} {
 				return convertMySQLFloat(string(str))
 			}
 			return value
-		case "DOUBLE", "DECIMAL", "BIGINT":
+		case "DOUBLE":
+			str, ok := value.([]uint8)
+			if ok {
+				return convertMySQLDouble(string(str))
+			}
+			return value
+		case "DECIMAL":
+			// 按精确值比较, 超过float64精度的数字不会丢失
 			str, ok := value.([]uint8)
 			if ok {
-				return convertMySQLDoubleOrDecimal(string(str))
+				return convertMySQLDecimal(string(str))
 			}
 			return value
 		case "JSON":
+			// 去掉MySQL输出的空格后比较, 数组和标量也是有效的JSON, 无法解析时按原文本比较
 			str := string(value.([]uint8))
-			var data map[string]interface{}
-			err := json.Unmarshal([]byte(str), &data)
-			if err != nil {
-				fmt.Println("字符串转换成JSON失败:", err)
+			var data interface{}
+			if err := json.Unmarshal([]byte(str), &data); err != nil {
+				return str
 			}
 			jsonStr, err := json.Marshal(data)
 			if err != nil {
-				fmt.Println("JSON转换成字符串失败:", err)
+				return str
 			}
 			return string(jsonStr)
 		default:
@@ -490,7 +546,17 @@ func convertMySQLFloat(value string) interface{} {
 	return float32(res)
 }
 
-func convertMySQLDoubleOrDecimal(value string) interface{} {
+func convertMySQLInteger(value string) interface{} {
+	if res, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return res
+	}
+	if res, err := strconv.ParseUint(value, 10, 64); err == nil {
+		return res
+	}
+	return value
+}
+
+func convertMySQLDouble(value string) interface{} {
 	res, err := strconv.ParseFloat(value, 64)
 	if err != nil {
 		return value
@@ -0,0 +1,165 @@
+package modules
+
+import (
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"m2y/defs/confdef"
+)
+
+// convertCase MySQL查询结果的原始值, columnType为驱动返回的DatabaseTypeName,
+// 取值来自test/sql/mysql_all_types.sql和mariadb_types.sql
+type convertCase struct {
+	columnType string
+	value      interface{}
+	expected   interface{}
+}
+
+// mysqlPointValue 返回MySQL存储格式(SRID+WKB)的POINT
+func mysqlPointValue(srid uint32, x, y float64) []byte {
+	data := binary.LittleEndian.AppendUint32(nil, srid)
+	data = append(data, 1)
+	data = binary.LittleEndian.AppendUint32(data, 1)
+	data = binary.LittleEndian.AppendUint64(data, math.Float64bits(x))
+	return binary.LittleEndian.AppendUint64(data, math.Float64bits(y))
+}
+
+// mysqlDateTime 返回source_time_zone中的时间
+func mysqlDateTime(year int, month time.Month, day, hour, min, sec, micros int) time.Time {
+	return time.Date(year, month, day, hour, min, sec, micros*int(time.Microsecond), confdef.GetSourceLocation())
+}
+
+func checkConverted(t *testing.T, c convertCase, actual interface{}) {
+	t.Helper()
+	if expected, ok := c.expected.(time.Time); ok {
+		if actual, ok := actual.(time.Time); !ok || !actual.Equal(expected) || actual.Location() != confdef.GetTargetLocation() {
+			t.Errorf("%s %q 转换为 %s, 期望 %v", c.columnType, c.value, showValue(actual), expected.In(confdef.GetTargetLocation()))
+		}
+		return
+	}
+	if !reflect.DeepEqual(actual, c.expected) {
+		t.Errorf("%s %q 转换为 %s, 期望 %s", c.columnType, c.value, showValue(actual), showValue(c.expected))
+	}
+}
+
+func TestConvertValueFromMySQLToYashan(t *testing.T) {
+	cases := []convertCase{
+		{columnType: "TINYINT", value: nil, expected: nil},
+		{columnType: "TINYINT", value: []byte("-128"), expected: "-128"},
+		{columnType: "UNSIGNED MEDIUMINT", value: []byte("00000042"), expected: "00000042"},
+		{columnType: "UNSIGNED INT", value: []byte("0000000007"), expected: "0000000007"},
+		{columnType: "UNSIGNED BIGINT", value: []byte("18446744073709551615"), expected: "18446744073709551615"},
+		{columnType: "DECIMAL", value: []byte("12345678901234.5678"), expected: "12345678901234.5678"},
+		{columnType: "DECIMAL", value: []byte("99999999.99"), expected: "99999999.99"},
+		{columnType: "FLOAT", value: []byte("2.5"), expected: "2.5"},
+		{columnType: "DOUBLE", value: []byte("1e-10"), expected: "1e-10"},
+		{columnType: "BIT", value: []byte{0x02, 0xAA}, expected: 682},
+		{columnType: "BIT", value: []byte{0x00, 0x00}, expected: 0},
+		{columnType: "YEAR", value: []byte("2024"), expected: int64(2024)},
+		{columnType: "YEAR", value: int64(1901), expected: int64(1901)},
+		{columnType: "DATE", value: []byte("2024-02-29"), expected: time.Date(2024, time.February, 29, 0, 0, 0, 0, confdef.GetTargetLocation())},
+		{columnType: "DATETIME", value: []byte("2024-02-29 23:59:59"), expected: mysqlDateTime(2024, time.February, 29, 23, 59, 59, 0)},
+		{columnType: "DATETIME", value: []byte("1000-01-01 00:00:00.000001"), expected: mysqlDateTime(1000, time.January, 1, 0, 0, 0, 1)},
+		{columnType: "TIMESTAMP", value: []byte("2024-02-29 12:00:00.5"), expected: mysqlDateTime(2024, time.February, 29, 12, 0, 0, 500000)},
+		{columnType: "TIME", value: []byte("12:34:56"), expected: "12:34:56.000000"},
+		{columnType: "TIME", value: []byte("23:59:59.999999"), expected: "23:59:59.999999"},
+		{columnType: "CHAR", value: []byte("nchar"), expected: "nchar"},
+		{columnType: "VARCHAR", value: []byte(""), expected: ""},
+		{columnType: "BINARY", value: []byte{1, 2, 3, 4, 5, 6, 7, 8}, expected: []byte{1, 2, 3, 4, 5, 6, 7, 8}},
+		{columnType: "VARBINARY", value: []byte{}, expected: []byte{}},
+		{columnType: "TINYBLOB", value: []byte{0x01}, expected: []byte{0x01}},
+		{columnType: "MEDIUMBLOB", value: []byte{0x04, 0x05}, expected: []byte{0x04, 0x05}},
+		{columnType: "LONGBLOB", value: []byte{0x06, 0x07}, expected: []byte{0x06, 0x07}},
+		{columnType: "TEXT", value: []byte("text"), expected: "text"},
+		{columnType: "JSON", value: []byte(`{"key": "value"}`), expected: []byte(`{"key": "value"}`)},
+		{columnType: "ENUM", value: []byte("medium"), expected: "medium"},
+		{columnType: "SET", value: []byte("a,c"), expected: "a,c"},
+		{columnType: "SET", value: []byte(""), expected: ""},
+		{columnType: "GEOMETRY", value: mysqlPointValue(0, 1, 2), expected: &spatialValue{SRID: 0, WKT: "POINT(1 2)"}},
+		{columnType: "GEOMETRY", value: mysqlPointValue(4326, 116.4, 39.9), expected: &spatialValue{SRID: 4326, WKT: "POINT(116.4 39.9)"}},
+		// 无法解析的空间数据按原值写入
+		{columnType: "GEOMETRY", value: []byte{0x01, 0x02}, expected: []byte{0x01, 0x02}},
+		// MariaDB的INET4、INET6、UUID按字符串返回
+		{columnType: "CHAR", value: []byte("192.168.0.1"), expected: "192.168.0.1"},
+		{columnType: "CHAR", value: []byte("2001:db8::ff00:42:8329"), expected: "2001:db8::ff00:42:8329"},
+		{columnType: "CHAR", value: []byte("123e4567-e89b-12d3-a456-426655440000"), expected: "123e4567-e89b-12d3-a456-426655440000"},
+	}
+	for _, c := range cases {
+		actual, err := convertValueFromMySQLToYashan(c.value, c.columnType)
+		if err != nil {
+			t.Errorf("%s %q 转换失败: %v", c.columnType, c.value, err)
+			continue
+		}
+		checkConverted(t, c, actual)
+	}
+}
+
+// TestConvertValueFromMySQLToYashanError 超出YashanDB TIME范围的值和无法解析的时间返回错误
+func TestConvertValueFromMySQLToYashanError(t *testing.T) {
+	cases := []convertCase{
+		{columnType: "TIME", value: []byte("-838:59:59")},
+		{columnType: "TIME", value: []byte("24:00:00")},
+		{columnType: "TIME", value: []byte("838:59:59.000000")},
+		{columnType: "TIME", value: []byte("12:34")},
+		{columnType: "DATETIME", value: []byte("2024-02-30 00:00:00")},
+		{columnType: "DATE", value: []byte("2024/02/29")},
+	}
+	for _, c := range cases {
+		if actual, err := convertValueFromMySQLToYashan(c.value, c.columnType); err == nil {
+			t.Errorf("%s %q 转换为 %s, 期望返回错误", c.columnType, c.value, showValue(actual))
+		}
+	}
+}
+
+func TestConvertToMySQLType(t *testing.T) {
+	cases := []convertCase{
+		{columnType: "TINYINT", value: nil, expected: nil},
+		{columnType: "TINYINT", value: []byte("-128"), expected: int64(-128)},
+		{columnType: "UNSIGNED TINYINT", value: []byte("255"), expected: int64(255)},
+		{columnType: "UNSIGNED SMALLINT", value: []byte("65535"), expected: int64(65535)},
+		{columnType: "MEDIUMINT", value: []byte("-8388608"), expected: int64(-8388608)},
+		// ZEROFILL的列带有前导0
+		{columnType: "UNSIGNED MEDIUMINT", value: []byte("00000042"), expected: int64(42)},
+		{columnType: "UNSIGNED INT", value: []byte("0000000007"), expected: int64(7)},
+		{columnType: "BIGINT", value: []byte("-9223372036854775808"), expected: int64(math.MinInt64)},
+		{columnType: "UNSIGNED BIGINT", value: []byte("18446744073709551615"), expected: uint64(math.MaxUint64)},
+		{columnType: "DECIMAL", value: []byte("12345678901234.5678"), expected: convertMySQLDecimal("12345678901234.5678")},
+		{columnType: "DECIMAL", value: []byte("-123456789.123"), expected: convertMySQLDecimal("-123456789.123")},
+		{columnType: "FLOAT", value: []byte("2.5"), expected: float32(2.5)},
+		{columnType: "DOUBLE", value: []byte("3.141592653589793"), expected: 3.141592653589793},
+		{columnType: "DOUBLE", value: []byte("1e10"), expected: 1e10},
+		{columnType: "BIT", value: []byte{0x02, 0xAA}, expected: "1010101010"},
+		{columnType: "BIT", value: []byte{0x00, 0x00}, expected: "0"},
+		{columnType: "YEAR", value: []byte("1901"), expected: int64(1901)},
+		{columnType: "DATE", value: []byte("1000-01-01"), expected: time.Date(1000, time.January, 1, 0, 0, 0, 0, confdef.GetTargetLocation())},
+		{columnType: "DATETIME", value: []byte("2024-02-29 23:59:59.123456"), expected: mysqlDateTime(2024, time.February, 29, 23, 59, 59, 123456)},
+		{columnType: "TIMESTAMP", value: []byte("1970-01-01 08:00:01"), expected: mysqlDateTime(1970, time.January, 1, 8, 0, 1, 0)},
+		// 无法解析的时间按原文本比较
+		{columnType: "DATETIME", value: []byte("2024-02-30 00:00:00"), expected: "2024-02-30 00:00:00"},
+		{columnType: "TIME", value: []byte("12:34:56"), expected: &timeValue{text: "12:34:56", duration: 12*time.Hour + 34*time.Minute + 56*time.Second}},
+		{columnType: "TIME", value: []byte("-838:59:59"), expected: &timeValue{text: "-838:59:59", duration: -(838*time.Hour + 59*time.Minute + 59*time.Second)}},
+		{columnType: "TIME", value: []byte("34:00:00.5"), expected: &timeValue{text: "34:00:00.5", duration: 34*time.Hour + 500*time.Millisecond}},
+		{columnType: "CHAR", value: []byte("char"), expected: "char"},
+		{columnType: "BINARY", value: []byte{0x00}, expected: []byte{0x00}},
+		{columnType: "VARBINARY", value: []byte{0xDE, 0xAD, 0xBE, 0xEF}, expected: []byte{0xDE, 0xAD, 0xBE, 0xEF}},
+		{columnType: "TINYBLOB", value: []byte{0x01}, expected: []byte{0x01}},
+		{columnType: "BLOB", value: []byte{0x02, 0x03}, expected: []byte{0x02, 0x03}},
+		{columnType: "TEXT", value: []byte("longtext"), expected: "longtext"},
+		{columnType: "JSON", value: []byte(`{"key": "value"}`), expected: `{"key":"value"}`},
+		{columnType: "JSON", value: []byte(`[]`), expected: `[]`},
+		{columnType: "JSON", value: []byte(`[1, "a", null]`), expected: `[1,"a",null]`},
+		{columnType: "ENUM", value: []byte("small"), expected: "small"},
+		{columnType: "SET", value: []byte("a,c"), expected: "a,c"},
+		{columnType: "GEOMETRY", value: mysqlPointValue(0, 1, 1), expected: &spatialValue{SRID: 0, WKT: "POINT(1 1)"}},
+		{columnType: "GEOMETRY", value: []byte{0x01, 0x02}, expected: []byte{0x01, 0x02}},
+		{columnType: "CHAR", value: []byte("0.0.0.0"), expected: "0.0.0.0"},
+		{columnType: "CHAR", value: []byte("::"), expected: "::"},
+		{columnType: "CHAR", value: []byte("00000000-0000-0000-0000-000000000000"), expected: "00000000-0000-0000-0000-000000000000"},
+	}
+	for _, c := range cases {
+		checkConverted(t, c, convertToMySQLType(c.value, c.columnType))
+	}
+}
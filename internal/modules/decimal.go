@@ -0,0 +1,86 @@
+package modules
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"m2y/defs/confdef"
+	"m2y/defs/sqldef"
+	"m2y/defs/typedef"
+	"m2y/log"
+)
+
+// decimalValue MySQL DECIMAL的精确值, 数据校验时按数值比较, 不经过float64, 作为绑定参数时使用原始文本
+type decimalValue struct {
+	text string
+	rat  *big.Rat
+}
+
+func (d *decimalValue) String() string {
+	return d.text
+}
+
+func (d *decimalValue) Value() (driver.Value, error) {
+	return d.text, nil
+}
+
+// parseDecimal 按十进制解析数值, 支持符号、前导0和指数
+func parseDecimal(value string) (*big.Rat, bool) {
+	value = strings.TrimSpace(value)
+	if len(value) == 0 || strings.ContainsAny(value, "/xXbBoO") {
+		return nil, false
+	}
+	return new(big.Rat).SetString(value)
+}
+
+// convertMySQLDecimal 解析MySQL返回的DECIMAL文本, 无法解析时返回原文本
+func convertMySQLDecimal(value string) interface{} {
+	rat, ok := parseDecimal(value)
+	if !ok {
+		return value
+	}
+	return &decimalValue{text: value, rat: rat}
+}
+
+// isDecimalEqual 按数值比较DECIMAL, 1.50与1.5相等, 目标端返回浮点数时只能按float64比较
+func isDecimalEqual(d *decimalValue, v interface{}) bool {
+	var (
+		rat *big.Rat
+		ok  bool
+	)
+	switch value := v.(type) {
+	case nil:
+		return false
+	case float64:
+		f, _ := d.rat.Float64()
+		return f == value
+	case float32:
+		f, _ := d.rat.Float32()
+		return f == value
+	case []uint8:
+		rat, ok = parseDecimal(string(value))
+	case *decimalValue:
+		rat, ok = value.rat, true
+	default:
+		rat, ok = parseDecimal(fmt.Sprint(value))
+	}
+	return ok && d.rat.Cmp(rat) == 0
+}
+
+// getNumericColumnType 返回number列带精度的类型, 精度超过YashanDB的上限时按decimal_overflow处理
+func getNumericColumnType(mysqlSchema, tableName, columnName, yasType string, precision, scale int64) (string, error) {
+	if precision <= sqldef.Y_MAX_NUMERIC_PRECISION {
+		return fmt.Sprintf(sqldef.Y_FLOAT_FORMAT, yasType, precision, scale), nil
+	}
+	switch confdef.GetM2YConfig().Yashan.DecimalOverflow {
+	case confdef.DECIMAL_OVERFLOW_VARCHAR:
+		// 符号和小数点各占一个字符
+		return fmt.Sprintf(sqldef.Y_CHAR_FORMAT, typedef.Y_VARCHAR, precision+2), nil
+	case confdef.DECIMAL_OVERFLOW_FAIL:
+		return "", fmt.Errorf("列 %s.%s.%s 的精度 %d 超过YashanDB的上限 %d, 请配置type_mapping或decimal_overflow", mysqlSchema, tableName, columnName, precision, sqldef.Y_MAX_NUMERIC_PRECISION)
+	}
+	log.Logger.Warnf("列 %s.%s.%s 的精度 %d 超过YashanDB的上限 %d, 截断为 %d, 超出范围的数据无法同步, 可使用precheck命令检查", mysqlSchema, tableName, columnName, precision, sqldef.Y_MAX_NUMERIC_PRECISION, sqldef.Y_MAX_NUMERIC_PRECISION)
+	return fmt.Sprintf(sqldef.Y_FLOAT_FORMAT, yasType, sqldef.Y_MAX_NUMERIC_PRECISION, scale), nil
+}
@@ -0,0 +1,167 @@
+package modules
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"m2y/db"
+	"m2y/defs/confdef"
+	"m2y/defs/sqldef"
+	"m2y/defs/typedef"
+	"m2y/log"
+)
+
+const (
+	check_enforced_no = "NO"
+)
+
+// getCheckConstraintDDLs 导出表的CHECK约束, MySQL 8.0.16及以上版本才有CHECK约束,
+// ENUM和SET列生成限制取值的CHECK约束, 配置了unsigned_check时为UNSIGNED数值列生成CHECK (col >= 0)约束
+func getCheckConstraintDDLs(mysql *sql.DB, mysqlSchema, yasdbSchema, tableName string) ([]string, error) {
+	checks, err := getEnumCheckDDLs(mysql, mysqlSchema, yasdbSchema, tableName)
+	if err != nil {
+		return nil, err
+	}
+	if db.MySQLVersion == db.MYSQL_VERSION_8 {
+		constraints, err := getMySQLCheckConstraints(mysql, mysqlSchema, yasdbSchema, tableName)
+		if err != nil {
+			// 8.0.16之前的版本没有information_schema.check_constraints
+			log.Logger.Warnf("表 %s.%s 查询CHECK约束失败, 跳过CHECK约束: %v", mysqlSchema, tableName, err)
+		}
+		checks = append(checks, constraints...)
+	}
+	if confdef.GetM2YConfig().Yashan.UnsignedCheck {
+		unsignedChecks, err := getUnsignedCheckDDLs(mysql, mysqlSchema, yasdbSchema, tableName)
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, unsignedChecks...)
+	}
+	return checks, nil
+}
+
+func getMySQLCheckConstraints(mysql *sql.DB, mysqlSchema, yasdbSchema, tableName string) ([]string, error) {
+	rows, err := mysql.Query(sqldef.M_SQL_QUERY_CHECK_CONSTRAINTS, mysqlSchema, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var checks []string
+	for rows.Next() {
+		var name, clause, enforced string
+		if err := rows.Scan(&name, &clause, &enforced); err != nil {
+			return nil, err
+		}
+		if enforced == check_enforced_no {
+			log.Logger.Warnf("表 %s.%s 的CHECK约束 %s 没有启用(NOT ENFORCED), 不导出: %s", mysqlSchema, tableName, name, clause)
+			continue
+		}
+		translated, issues := translateSQLExpression(trimOuterParens(clause))
+		if len(issues) != 0 {
+			log.Logger.Warnf("表 %s.%s 的CHECK约束 %s 无法转换, 不导出: %s, %s", mysqlSchema, tableName, name, clause, strings.Join(issues, "; "))
+			continue
+		}
+		formatter := getSQLFormatter(sqldef.Y_SQL_ADD_CHECK, sqldef.Y_SQL_ADD_CHECK_CASE_SENSITIVE)
+		checks = append(checks, fmt.Sprintf(formatter, formatKeyWord(yasdbSchema), formatKeyWord(tableName), formatKeyWord(name), translated))
+	}
+	return checks, rows.Err()
+}
+
+// getUnsignedCheckDDLs YashanDB没有无符号类型, 为UNSIGNED列生成非负的CHECK约束
+func getUnsignedCheckDDLs(mysql *sql.DB, mysqlSchema, yasdbSchema, tableName string) ([]string, error) {
+	rows, err := mysql.Query(sqldef.M_SQL_QUERY_UNSIGNED_COLUMNS, mysqlSchema, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("查询UNSIGNED列 information_schema.columns 出错: %v", err)
+	}
+	defer rows.Close()
+	var checks []string
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return nil, fmt.Errorf("查询UNSIGNED列 information_schema.columns 出错: %v", err)
+		}
+		formatter := getSQLFormatter(sqldef.Y_SQL_ADD_UNNAMED_CHECK, sqldef.Y_SQL_ADD_UNNAMED_CHECK_CASE_SENSITIVE)
+		condition := fmt.Sprintf(sqldef.Y_SQL_UNSIGNED_CHECK_FORMAT, genColumnString([]string{column}))
+		checks = append(checks, fmt.Sprintf(formatter, formatKeyWord(yasdbSchema), formatKeyWord(tableName), condition))
+	}
+	return checks, rows.Err()
+}
+
+// getEnumCheckDDLs ENUM和SET列在YashanDB中是VARCHAR, 生成CHECK约束限制取值, ENUM的值必须是成员之一,
+// SET的值必须是逗号分隔的成员, 通过[type_mapping]映射为非字符类型的列不生成
+func getEnumCheckDDLs(mysql *sql.DB, mysqlSchema, yasdbSchema, tableName string) ([]string, error) {
+	rows, err := mysql.Query(sqldef.M_SQL_QUERY_ENUM_COLUMNS, mysqlSchema, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("查询ENUM和SET列 information_schema.columns 出错: %v", err)
+	}
+	defer rows.Close()
+	var checks []string
+	for rows.Next() {
+		var column, dataType, columnType string
+		if err := rows.Scan(&column, &dataType, &columnType); err != nil {
+			return nil, fmt.Errorf("查询ENUM和SET列 information_schema.columns 出错: %v", err)
+		}
+		yasType, err := getYashanColumnType(mysqlSchema, tableName, column, dataType, columnType)
+		if err != nil {
+			return nil, err
+		}
+		if !inArrayStr(getYashanTypeBaseName(yasType), []string{typedef.Y_CHAR, typedef.Y_VARCHAR, typedef.Y_NCHAR, typedef.Y_NVARCHAR}) {
+			continue
+		}
+		elements := parseMySQLEnumElements(columnType)
+		if len(elements) == 0 {
+			continue
+		}
+		columnName := genColumnString([]string{column})
+		var condition string
+		if strings.EqualFold(dataType, typedef.M_SET) {
+			var members []string
+			for _, element := range elements {
+				members = append(members, escapeSQLString(regexp.QuoteMeta(element)))
+			}
+			pattern := strings.Join(members, "|")
+			condition = fmt.Sprintf(sqldef.Y_SQL_SET_CHECK_FORMAT, columnName, pattern, pattern)
+		} else {
+			var values []string
+			for _, element := range elements {
+				values = append(values, "'"+escapeSQLString(element)+"'")
+			}
+			condition = fmt.Sprintf(sqldef.Y_SQL_ENUM_CHECK_FORMAT, columnName, strings.Join(values, ", "))
+		}
+		formatter := getSQLFormatter(sqldef.Y_SQL_ADD_UNNAMED_CHECK, sqldef.Y_SQL_ADD_UNNAMED_CHECK_CASE_SENSITIVE)
+		checks = append(checks, fmt.Sprintf(formatter, formatKeyWord(yasdbSchema), formatKeyWord(tableName), condition))
+	}
+	return checks, rows.Err()
+}
+
+// trimOuterParens 去掉包住整个表达式的一层括号, information_schema中的CHECK条件都带有括号
+func trimOuterParens(expression string) string {
+	expression = strings.TrimSpace(expression)
+	if !strings.HasPrefix(expression, "(") || !strings.HasSuffix(expression, ")") {
+		return expression
+	}
+	depth := 0
+	inQuote := byte(0)
+	for i := 0; i < len(expression); i++ {
+		c := expression[i]
+		switch {
+		case inQuote != 0:
+			if c == inQuote {
+				inQuote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			inQuote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			// 第一个括号在末尾之前就闭合了, 如(a > 0) and (b > 0)
+			if depth == 0 && i != len(expression)-1 {
+				return expression
+			}
+		}
+	}
+	return strings.TrimSpace(expression[1 : len(expression)-1])
+}
@@ -0,0 +1,379 @@
+package modules
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"m2y/defs/runtimedef"
+	"m2y/log"
+	"m2y/utils/fileutil"
+
+	"git.yasdb.com/go/yasutil/fs"
+)
+
+const (
+	data_dir           = "data"
+	data_manifest_file = "manifest.json"
+	data_file_format   = "data_%03d_%06d.csv.gz"
+	data_null          = `\N`
+)
+
+// 按十六进制写入导出文件的类型, 与go-sql-driver的DatabaseTypeName一致
+var binaryDataType = map[string]struct{}{
+	"BINARY":     {},
+	"VARBINARY":  {},
+	"TINYBLOB":   {},
+	"BLOB":       {},
+	"MEDIUMBLOB": {},
+	"LONGBLOB":   {},
+	"BIT":        {},
+	"GEOMETRY":   {},
+}
+
+// dataColumn 导出文件中的一列, Type为go-sql-driver返回的类型名, 加载时按该类型转换为YashanDB的值
+// Generated为true时是MySQL的生成列, 加载时跳过, MappedType为导出时[type_mapping]配置的类型, 加载时按该类型转换
+type dataColumn struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Generated  bool   `json:"generated,omitempty"`
+	MappedType string `json:"mapped_type,omitempty"`
+}
+
+type dataFile struct {
+	Name string `json:"name"`
+	Rows int64  `json:"rows"`
+}
+
+// dataManifest 一张表的导出文件清单, 表导出完成后才写入, 没有清单的目录不会被加载
+type dataManifest struct {
+	MySQLSchema string       `json:"mysql_schema"`
+	Table       string       `json:"table"`
+	Columns     []dataColumn `json:"columns"`
+	Files       []dataFile   `json:"files"`
+	Rows        int64        `json:"rows"`
+	ExportedAt  string       `json:"exported_at"`
+}
+
+// dataFileWriter 写入一个gzip压缩的CSV文件
+type dataFileWriter struct {
+	file   *os.File
+	gz     *gzip.Writer
+	writer *csv.Writer
+	name   string
+	rows   int64
+}
+
+func ExportTableData(mysql *sql.DB, mysqlSchema string, alltables []string, parallel, tableParallel, fileRows int) error {
+	sts := []schemaTable{}
+	for _, table := range alltables {
+		sts = append(sts, schemaTable{mysqlSchema: mysqlSchema, table: table})
+	}
+	return exportTablesData(mysql, sts, parallel, tableParallel, fileRows)
+}
+
+func ExportSchemasData(mysql *sql.DB, mysqlSchemas, yasdbSchemas []string, excludeTables []string, parallel, tableParallel, fileRows int) error {
+	sts, err := getSchemaTables(mysql, mysqlSchemas, yasdbSchemas, excludeTables)
+	if err != nil {
+		return err
+	}
+	return exportTablesData(mysql, sts, parallel, tableParallel, fileRows)
+}
+
+func exportTablesData(mysql *sql.DB, sts []schemaTable, parallel, tableParallel, fileRows int) error {
+	start := time.Now()
+	semaphore := make(chan bool, parallel)
+	var wg sync.WaitGroup
+	var totalRows int64
+	log.Logger.Infof("开始导出mysql数据到 %s ......", getExportDataPath())
+	for _, st := range sts {
+		wg.Add(1)
+		semaphore <- true
+		go func(mysqlSchema, mysqlTable string) {
+			defer wg.Done()
+			rows := exportTableData(mysql, mysqlSchema, mysqlTable, tableParallel, fileRows)
+			atomic.AddInt64(&totalRows, rows)
+			<-semaphore
+		}(st.mysqlSchema, st.table)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	log.Logger.Infof("数据导出任务完成, 共导出数据量: %d 共耗时: %v 速度: %.0f 行/秒", totalRows, elapsed, rowsPerSecond(totalRows, elapsed))
+	return nil
+}
+
+// exportTableData 按与sync相同的方式切分表数据, 每个分片按fileRows行一个文件写入, 返回导出的行数
+func exportTableData(mysql *sql.DB, mysqlSchema, mysqlTable string, tableParallel, fileRows int) int64 {
+	start := time.Now()
+	log.Logger.Infof("开始导出mysql表 %s.%s", mysqlSchema, mysqlTable)
+	dir := getTableDataPath(mysqlSchema, mysqlTable)
+	if err := os.RemoveAll(dir); err != nil {
+		log.Logger.Errorf("表 %s.%s 导出失败, 清理导出目录失败: %v", mysqlSchema, mysqlTable, err)
+		return 0
+	}
+	if err := fs.Mkdir(dir); err != nil {
+		log.Logger.Errorf("表 %s.%s 导出失败, 创建导出目录失败: %v", mysqlSchema, mysqlTable, err)
+		return 0
+	}
+	count, err := getMySQLTableCount(mysql, mysqlSchema, mysqlTable)
+	if err != nil {
+		log.Logger.Errorf("表 %s.%s 导出失败, 获取mysql端表数据失败: %v", mysqlSchema, mysqlTable, err)
+		return 0
+	}
+	key, err := getMySQLTableKey(mysql, mysqlSchema, mysqlTable)
+	if err != nil {
+		log.Logger.Errorf("表 %s.%s 导出失败, 获取mysql端表主键失败: %v", mysqlSchema, mysqlTable, err)
+		return 0
+	}
+	generatedColumns, err := getMySQLGeneratedColumns(mysql, mysqlSchema, mysqlTable)
+	if err != nil {
+		log.Logger.Errorf("表 %s.%s 导出失败, 获取mysql端生成列失败: %v", mysqlSchema, mysqlTable, err)
+		return 0
+	}
+	typeMappings, err := getMySQLColumnTypeMappings(mysql, mysqlSchema, mysqlTable)
+	if err != nil {
+		log.Logger.Errorf("表 %s.%s 导出失败, 获取列的类型映射失败: %v", mysqlSchema, mysqlTable, err)
+		return 0
+	}
+	var queries []rangeQuery
+	if key != nil {
+		ranges, err := splitTableByKey(mysql, mysqlSchema, mysqlTable, key, count, tableParallel)
+		if err != nil {
+			log.Logger.Errorf("表 %s.%s 导出失败, 按主键切分数据失败: %v", mysqlSchema, mysqlTable, err)
+			return 0
+		}
+		for _, r := range ranges {
+			query, args := buildKeyRangeQuery(mysqlSchema, mysqlTable, key, r)
+			queries = append(queries, rangeQuery{query: query, args: args})
+		}
+	} else {
+		queries = splitTableByLimit(mysqlSchema, mysqlTable, count, tableParallel)
+	}
+	if len(queries) < tableParallel {
+		tableParallel = len(queries)
+	}
+	if tableParallel < 1 {
+		tableParallel = 1
+	}
+	manifest := &dataManifest{MySQLSchema: mysqlSchema, Table: mysqlTable}
+	var mu sync.Mutex
+	var failedCount int64
+	semaphore := make(chan bool, tableParallel)
+	var wg sync.WaitGroup
+	for i, q := range queries {
+		wg.Add(1)
+		semaphore <- true
+		go func(i int, q rangeQuery) {
+			defer wg.Done()
+			columns, files, err := exportChunkData(mysql, dir, i, q, fileRows)
+			mu.Lock()
+			if len(manifest.Columns) == 0 {
+				manifest.Columns = columns
+			}
+			manifest.Files = append(manifest.Files, files...)
+			mu.Unlock()
+			if err != nil {
+				log.Logger.Errorf("表 %s.%s 导出失败, %v", mysqlSchema, mysqlTable, err)
+				atomic.AddInt64(&failedCount, 1)
+			}
+			<-semaphore
+		}(i, q)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	for _, file := range manifest.Files {
+		manifest.Rows += file.Rows
+	}
+	if failedCount > 0 {
+		log.Logger.Errorf("表 %s.%s 导出未完成, 导出数据量: %d 耗时 %v\n", mysqlSchema, mysqlTable, manifest.Rows, elapsed)
+		return manifest.Rows
+	}
+	sort.Slice(manifest.Files, func(i, j int) bool { return manifest.Files[i].Name < manifest.Files[j].Name })
+	for i := range manifest.Columns {
+		manifest.Columns[i].Generated = inArrayStr(manifest.Columns[i].Name, generatedColumns)
+		manifest.Columns[i].MappedType = typeMappings[manifest.Columns[i].Name]
+	}
+	manifest.ExportedAt = time.Now().Format("2006-01-02 15:04:05")
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err == nil {
+		err = fileutil.WriteFile(path.Join(dir, data_manifest_file), data)
+	}
+	if err != nil {
+		log.Logger.Errorf("表 %s.%s 导出失败, 写入清单文件失败: %v", mysqlSchema, mysqlTable, err)
+		return manifest.Rows
+	}
+	log.Logger.Infof("表 %s.%s 导出完成, 导出数据量: %d 文件数: %d 耗时 %v 速度: %.0f 行/秒\n", mysqlSchema, mysqlTable, manifest.Rows, len(manifest.Files), elapsed, rowsPerSecond(manifest.Rows, elapsed))
+	return manifest.Rows
+}
+
+// exportChunkData 导出一个分片的数据, 返回结果集的列信息和写入的文件
+func exportChunkData(mysql *sql.DB, dir string, chunk int, q rangeQuery, fileRows int) ([]dataColumn, []dataFile, error) {
+	rows, err := mysql.Query(q.query, q.args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("源端数据查询失败: %v", err)
+	}
+	defer rows.Close()
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, nil, fmt.Errorf("源端数据列信息获取失败: %v", err)
+	}
+	var columns []dataColumn
+	for _, columnType := range columnTypes {
+		columns = append(columns, dataColumn{Name: columnType.Name(), Type: columnType.DatabaseTypeName()})
+	}
+	var files []dataFile
+	var writer *dataFileWriter
+	closeWriter := func() error {
+		if writer == nil {
+			return nil
+		}
+		err := writer.close()
+		files = append(files, dataFile{Name: writer.name, Rows: writer.rows})
+		writer = nil
+		return err
+	}
+	values := make([]interface{}, len(columns))
+	valuePointers := make([]interface{}, len(columns))
+	for i := range values {
+		valuePointers[i] = &values[i]
+	}
+	record := make([]string, len(columns))
+	for rows.Next() {
+		if err := rows.Scan(valuePointers...); err != nil {
+			_ = closeWriter()
+			return columns, files, fmt.Errorf("源端数据查询失败: %v", err)
+		}
+		if writer == nil {
+			writer, err = newDataFileWriter(dir, fmt.Sprintf(data_file_format, chunk, len(files)+1))
+			if err != nil {
+				return columns, files, fmt.Errorf("创建导出文件失败: %v", err)
+			}
+		}
+		for i, value := range values {
+			record[i] = encodeDataValue(value, columns[i].Type)
+		}
+		if err := writer.write(record); err != nil {
+			_ = closeWriter()
+			return columns, files, fmt.Errorf("写入导出文件失败: %v", err)
+		}
+		if writer.rows >= int64(fileRows) {
+			if err := closeWriter(); err != nil {
+				return columns, files, fmt.Errorf("写入导出文件失败: %v", err)
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		_ = closeWriter()
+		return columns, files, fmt.Errorf("源端数据查询失败: %v", err)
+	}
+	if err := closeWriter(); err != nil {
+		return columns, files, fmt.Errorf("写入导出文件失败: %v", err)
+	}
+	return columns, files, nil
+}
+
+func newDataFileWriter(dir, name string) (*dataFileWriter, error) {
+	file, err := os.Create(path.Join(dir, name))
+	if err != nil {
+		return nil, err
+	}
+	gz := gzip.NewWriter(file)
+	return &dataFileWriter{file: file, gz: gz, writer: csv.NewWriter(gz), name: name}, nil
+}
+
+func (w *dataFileWriter) write(record []string) error {
+	if err := w.writer.Write(record); err != nil {
+		return err
+	}
+	w.rows++
+	return nil
+}
+
+func (w *dataFileWriter) close() error {
+	w.writer.Flush()
+	err := w.writer.Error()
+	if gzErr := w.gz.Close(); err == nil {
+		err = gzErr
+	}
+	if fileErr := w.file.Close(); err == nil {
+		err = fileErr
+	}
+	return err
+}
+
+// encodeDataValue 将MySQL的值编码为CSV字段, NULL写为\N, 二进制类型写为十六进制,
+// 文本中的反斜杠和换行符转义, 避免与NULL混淆以及CSV读取时丢失\r
+func encodeDataValue(value interface{}, columnType string) string {
+	if value == nil {
+		return data_null
+	}
+	b := keyValueToBytes(value)
+	if _, ok := binaryDataType[columnType]; ok {
+		return hex.EncodeToString(b)
+	}
+	if bytes.IndexAny(b, "\\\r\n") < 0 {
+		return string(b)
+	}
+	var buf bytes.Buffer
+	for _, c := range b {
+		switch c {
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\n':
+			buf.WriteString(`\n`)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return buf.String()
+}
+
+// decodeDataValue 将CSV字段解码为与go-sql-driver查询结果相同形式的值
+func decodeDataValue(field string, columnType string) (interface{}, error) {
+	if field == data_null {
+		return nil, nil
+	}
+	if _, ok := binaryDataType[columnType]; ok {
+		return hex.DecodeString(field)
+	}
+	if len(field) == 0 || !bytes.ContainsRune([]byte(field), '\\') {
+		return []byte(field), nil
+	}
+	buf := make([]byte, 0, len(field))
+	for i := 0; i < len(field); i++ {
+		if field[i] != '\\' || i+1 == len(field) {
+			buf = append(buf, field[i])
+			continue
+		}
+		i++
+		switch field[i] {
+		case 'r':
+			buf = append(buf, '\r')
+		case 'n':
+			buf = append(buf, '\n')
+		default:
+			buf = append(buf, field[i])
+		}
+	}
+	return buf, nil
+}
+
+func getExportDataPath() string {
+	return path.Join(runtimedef.GetExportPath(), data_dir)
+}
+
+func getTableDataPath(mysqlSchema, mysqlTable string) string {
+	return path.Join(getExportDataPath(), mysqlSchema, mysqlTable)
+}
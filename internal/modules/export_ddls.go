@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"sort"
 	"strings"
 	"time"
 
@@ -23,6 +24,14 @@ const (
 	others_ddl = "others"
 )
 
+// information_schema.referential_constraints中的外键规则
+const (
+	fk_rule_cascade   = "CASCADE"
+	fk_rule_set_null  = "SET NULL"
+	fk_rule_restrict  = "RESTRICT"
+	fk_rule_no_action = "NO ACTION"
+)
+
 type Index struct {
 	Table      string
 	NonUnique  int
@@ -30,12 +39,28 @@ type Index struct {
 	ColumnName string
 	IndexType  string
 	SeqInIndex int
+	Collation  string // A升序, D降序, 没有排序时为空
+	SubPart    int    // 前缀索引的长度, 不是前缀索引时为0
+	Expression string // MySQL 8函数索引的表达式
+}
+
+// DealTablesDDLs 导出表的DDL, yasdb不为nil时导出完成后在YashanDB中按阶段执行
+func DealTablesDDLs(mysql, yasdb *sql.DB, mysqlSchema, yasdbSchema string, tables []string, withViews bool) error {
+	applier := newDDLApplier(yasdb)
+	if err := dealTablesDDLs(mysql, applier, mysqlSchema, yasdbSchema, tables, withViews); err != nil {
+		return err
+	}
+	applier.apply()
+	return nil
 }
 
-func DealTablesDDLs(mysql *sql.DB, mysqlSchema, yasdbSchema string, tables []string, withViews bool) error {
+func dealTablesDDLs(mysql *sql.DB, applier *ddlApplier, mysqlSchema, yasdbSchema string, tables []string, withViews bool) error {
 	if err := mkdirDDLPath(); err != nil {
 		return err
 	}
+	// 按表名排序, 保证每次导出的文件内容一致
+	tables = append([]string{}, tables...)
+	sort.Strings(tables)
 	tabFileName := path.Join(getTablesDDLPath(), fmt.Sprintf("%s_tables.sql", mysqlSchema))
 	idxFileName := path.Join(getOthersDDLPath(), fmt.Sprintf("%s_others.sql", mysqlSchema))
 
@@ -68,6 +93,8 @@ func DealTablesDDLs(mysql *sql.DB, mysqlSchema, yasdbSchema string, tables []str
 			log.Logger.Errorf("表 %s.%s DDL导出失败: %v", mysqlSchema, tableName, err)
 			continue
 		}
+		objectName := yasdbSchema + "." + tableName
+		applier.add(ddl_phase_tables, objectName, tableDDLs...)
 		tableComments, err := getTableComments(mysql, mysqlSchema, yasdbSchema, tableName)
 		if err != nil {
 			log.Logger.Errorf("表 %s.%s 注释导出失败: %v", mysqlSchema, tableName, err)
@@ -77,18 +104,32 @@ func DealTablesDDLs(mysql *sql.DB, mysqlSchema, yasdbSchema string, tables []str
 			log.Logger.Errorf("表 %s.%s 注释导出失败: %v", mysqlSchema, tableName, err)
 			continue
 		}
+		applier.add(ddl_phase_tables, objectName, tableComments...)
 		if _, err = idxFile.WriteString(strings.Join(nullableStrs, "\n")); err != nil {
 			log.Logger.Errorf("表 %s.%s 非空约束导出失败: %v", mysqlSchema, tableName, err)
 			continue
 		}
+		applier.add(ddl_phase_indexes, objectName, nullableStrs...)
+		checks, err := getCheckConstraintDDLs(mysql, mysqlSchema, yasdbSchema, tableName)
+		if err != nil {
+			log.Logger.Errorf("表 %s.%s CHECK约束导出失败: %v", mysqlSchema, tableName, err)
+			continue
+		}
+		if _, err = idxFile.WriteString(strings.Join(checks, "\n")); err != nil {
+			log.Logger.Errorf("表 %s.%s CHECK约束导出失败: %v", mysqlSchema, tableName, err)
+			continue
+		}
+		applier.add(ddl_phase_indexes, objectName, checks...)
 	}
 
 	msgIdx := "\n--再创建数据库内的索引\n"
 	if _, err = idxFile.WriteString(msgIdx); err != nil {
 		return err
 	}
+	// YashanDB的索引名在schema内唯一, MySQL的索引名只在表内唯一
+	namer := newIndexNamer()
 	for _, tableName := range tables {
-		primarykeys, err := getPrimaryKeyDDLs(mysql, mysqlSchema, yasdbSchema, tableName)
+		primarykeys, err := getPrimaryKeyDDLs(mysql, namer, mysqlSchema, yasdbSchema, tableName)
 		if err != nil {
 			log.Logger.Errorf("表 %s.%s 主键约束导出失败: %v", mysqlSchema, tableName, err)
 			continue
@@ -97,7 +138,8 @@ func DealTablesDDLs(mysql *sql.DB, mysqlSchema, yasdbSchema string, tables []str
 			log.Logger.Errorf("表 %s.%s 主键约束导出失败: %v", mysqlSchema, tableName, err)
 			continue
 		}
-		uniqIndexes, err := getUniqueIndexDDLs(mysql, mysqlSchema, yasdbSchema, tableName)
+		applier.add(ddl_phase_indexes, yasdbSchema+"."+tableName, primarykeys...)
+		uniqIndexes, err := getUniqueIndexDDLs(mysql, namer, mysqlSchema, yasdbSchema, tableName)
 		if err != nil {
 			log.Logger.Errorf("表 %s.%s unique索引导出失败: %v", mysqlSchema, tableName, err)
 			continue
@@ -106,7 +148,8 @@ func DealTablesDDLs(mysql *sql.DB, mysqlSchema, yasdbSchema string, tables []str
 			log.Logger.Errorf("表 %s.%s unique索引导出失败: %v", mysqlSchema, tableName, err)
 			continue
 		}
-		nonUniqueIndexes, err := getNonUniqueIndexDDL(mysql, mysqlSchema, yasdbSchema, tableName)
+		applier.add(ddl_phase_indexes, yasdbSchema+"."+tableName, uniqIndexes...)
+		nonUniqueIndexes, err := getNonUniqueIndexDDL(mysql, namer, mysqlSchema, yasdbSchema, tableName)
 		if err != nil {
 			log.Logger.Errorf("表 %s.%s non unique索引导出失败: %v", mysqlSchema, tableName, err)
 			continue
@@ -115,6 +158,7 @@ func DealTablesDDLs(mysql *sql.DB, mysqlSchema, yasdbSchema string, tables []str
 			log.Logger.Errorf("表 %s.%s non unique索引导出失败: %v", mysqlSchema, tableName, err)
 			continue
 		}
+		applier.add(ddl_phase_indexes, yasdbSchema+"."+tableName, nonUniqueIndexes...)
 	}
 	consIdx := "\n--最后创建外键约束\n"
 	if _, err = idxFile.WriteString(consIdx); err != nil {
@@ -130,6 +174,7 @@ func DealTablesDDLs(mysql *sql.DB, mysqlSchema, yasdbSchema string, tables []str
 			log.Logger.Errorf("表 %s.%s 外键约束导出失败: %v", mysqlSchema, tableName, err)
 			continue
 		}
+		applier.add(ddl_phase_foreign_keys, yasdbSchema+"."+tableName, constraints...)
 	}
 	if withViews {
 		viewMsg := "\n--创建视图\n"
@@ -143,11 +188,28 @@ func DealTablesDDLs(mysql *sql.DB, mysqlSchema, yasdbSchema string, tables []str
 		if _, err = idxFile.WriteString(strings.Join(viewDDLs, "\n")); err != nil {
 			return err
 		}
+		applier.add(ddl_phase_views, yasdbSchema, viewDDLs...)
+	}
+	// 存储过程、函数和事件属于schema, 与视图一样只在按schema导出时导出, 触发器可能调用函数, 先于触发器执行
+	if withViews {
+		if err := exportRoutines(mysql, applier, mysqlSchema, yasdbSchema); err != nil {
+			log.Logger.Errorf("schema %s 存储过程导出失败: %v", mysqlSchema, err)
+		}
+		// 事件可能调用存储过程, 在存储过程之后创建
+		if err := exportEvents(mysql, applier, mysqlSchema, yasdbSchema); err != nil {
+			log.Logger.Errorf("schema %s 事件导出失败: %v", mysqlSchema, err)
+		}
+	}
+	if err := exportTriggers(mysql, applier, mysqlSchema, yasdbSchema, tables); err != nil {
+		log.Logger.Errorf("schema %s 触发器导出失败: %v", mysqlSchema, err)
 	}
 	return nil
 }
 
-func DealSchemasDDL(mysqlDB *sql.DB, schemas, remapSchemas []string, excludeTables []string) error {
+// DealSchemasDDL 导出schema的DDL, yasdb不为nil时所有schema导出完成后在YashanDB中按阶段执行,
+// 视图可能引用其他schema的表, 因此所有schema的表创建完成后才创建视图
+func DealSchemasDDL(mysqlDB, yasdb *sql.DB, schemas, remapSchemas []string, excludeTables []string) error {
+	applier := newDDLApplier(yasdb)
 	if err := mkdirDDLPath(); err != nil {
 		return err
 	}
@@ -174,12 +236,13 @@ func DealSchemasDDL(mysqlDB *sql.DB, schemas, remapSchemas []string, excludeTabl
 				tables = append(tables, table)
 			}
 		}
-		if err := DealTablesDDLs(mysqlDB, schema, remapSchemas[i], tables, true); err != nil {
+		if err := dealTablesDDLs(mysqlDB, applier, schema, remapSchemas[i], tables, true); err != nil {
 			log.Logger.Errorf("schema %s DDL导出失败: %v", schema, err)
 			continue
 		}
 	}
 	log.Logger.Infof("任务完成，耗时: %v, 结果保存在: %s", time.Since(start), runtimedef.GetExportPath())
+	applier.apply()
 	return nil
 }
 
@@ -223,22 +286,27 @@ func getTableColumnDDLs(mysql *sql.DB, mysqlSchema, yasdbSchema, tableName strin
 	}
 	defer columns.Close()
 
-	// 存储表名和列信息的映射关系
-	tableColumns := make(map[string][]string)
-	// 存储列注释信息
-	columnComments := make(map[string]string)
+	// 列定义, 按ORDINAL_POSITION的顺序保存
+	var columnStmts []string
+	// 存储列注释信息, 与列的顺序一致
+	var columnComments [][2]string
+	// 存储列的MySQL数据类型, 用于转换分区的边界值
+	columnTypes := make(map[string]string)
 	// 遍历列信息结果
 	for columns.Next() {
 		var (
-			tableName, columnName, columnComment, dataType, isNullable, columnDefault string
-			maxLength, numericPrecision, numericScale                                 sql.NullInt64
-			columnTypeLength                                                          sql.NullString
+			columnName, columnComment, dataType, isNullable, columnDefault, extra, columnType string
+			charset, collation, generationExpression                                          string
+			maxLength, numericPrecision, numericScale                                         sql.NullInt64
+			columnTypeLength                                                                  sql.NullString
 		)
-		if err := columns.Scan(&tableName, &columnName, &dataType, &maxLength, &numericPrecision, &numericScale, &columnComment, &columnTypeLength, &isNullable, &columnDefault); err != nil {
+		if err := columns.Scan(&columnName, &dataType, &maxLength, &numericPrecision, &numericScale, &columnComment, &columnTypeLength, &isNullable, &columnDefault, &extra, &columnType, &charset, &collation, &generationExpression); err != nil {
 			return nil, nil, fmt.Errorf("查询表属性 information_schema.columns 出错: %s", err.Error())
 		}
+		logColumnCharset(mysqlSchema, tableName, columnName, charset, collation)
 		// 将MySQL数据类型映射为目标端数据类型和长度信息
-		yasType, err := typedef.MySQLToYasType(dataType)
+		columnTypes[columnName] = dataType
+		yasType, err := getYashanColumnType(mysqlSchema, tableName, columnName, dataType, columnType)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -246,6 +314,10 @@ func getTableColumnDDLs(mysql *sql.DB, mysqlSchema, yasdbSchema, tableName strin
 		var nullableStr, columnDefaultStr string
 		switch yasType {
 		case typedef.Y_VARCHAR, typedef.Y_CHAR, typedef.Y_NCHAR, typedef.Y_NVARCHAR:
+			if !maxLength.Valid {
+				maxLength.Int64, maxLength.Valid = typedef.DefaultCharLength(dataType)
+			}
+			// MySQL字符列的长度按字符计算, 与字符集无关, 使用char语义, 转换为UTF-8后多字节字符也能放下
 			if maxLength.Valid {
 				yasType = fmt.Sprintf(sqldef.Y_CHAR_FORMAT, yasType, maxLength.Int64)
 			}
@@ -259,10 +331,10 @@ func getTableColumnDDLs(mysql *sql.DB, mysqlSchema, yasdbSchema, tableName strin
 			columnDefaultStr = getDefaultStmt(yasType, columnDefault, hasDefault)
 		case typedef.Y_FLOAT, typedef.Y_DOUBLE, typedef.Y_NUMBER:
 			if numericPrecision.Valid && numericScale.Valid {
-				if numericPrecision.Int64 > sqldef.Y_MAX_NUMERIC_PRECISION {
-					numericPrecision.Int64 = sqldef.Y_MAX_NUMERIC_PRECISION
+				yasType, err = getNumericColumnType(mysqlSchema, tableName, columnName, yasType, numericPrecision.Int64, numericScale.Int64)
+				if err != nil {
+					return nil, nil, err
 				}
-				yasType = fmt.Sprintf(sqldef.Y_FLOAT_FORMAT, yasType, numericPrecision.Int64, numericScale.Int64)
 			}
 			columnDefaultStr = getDefaultStmt(yasType, columnDefault, hasDefault)
 		case typedef.Y_BIT:
@@ -273,9 +345,17 @@ func getTableColumnDDLs(mysql *sql.DB, mysqlSchema, yasdbSchema, tableName strin
 			if maxLength.Valid {
 				yasType = fmt.Sprintf(sqldef.Y_RAW_FORMAT, yasType, maxLength.Int64)
 			}
+		case typedef.Y_BOOLEAN:
+			columnDefaultStr = getBooleanDefaultStmt(columnDefault, hasDefault)
 		default:
 			columnDefaultStr = getDefaultStmt(yasType, columnDefault, hasDefault)
 		}
+		if dataType == typedef.M_TIME && isYashanIntervalType(yasType) {
+			columnDefaultStr = getIntervalDefaultStmt(columnDefault, hasDefault)
+		}
+		if isGeneratedColumn(extra) {
+			columnDefaultStr = getGeneratedColumnStmt(mysqlSchema, tableName, columnName, extra, generationExpression)
+		}
 		//构建not null的单独语句
 		if isNullable == "NO" {
 			// nullableStr = " not null"
@@ -286,20 +366,25 @@ func getTableColumnDDLs(mysql *sql.DB, mysqlSchema, yasdbSchema, tableName strin
 		// 构建列语句
 		formatter := getSQLFormatter(sqldef.Y_SQL_COLUMN_STMT_FORMAT, sqldef.Y_SQL_COLUMN_STMT_FORMAT_CASE_SENSITIVE)
 		columnStmt := fmt.Sprintf(formatter, formatKeyWord(columnName), yasType, columnDefaultStr)
-		// 将列信息添加到对应的表
-		tableColumns[tableName] = append(tableColumns[tableName], columnStmt)
+		columnStmts = append(columnStmts, columnStmt)
 		columnComment = strings.Replace(columnComment, "'", "''", -1)
-		// 将列注释信息添加到map中
-		columnComments[columnName] = columnComment
+		columnComments = append(columnComments, [2]string{columnName, columnComment})
+	}
+	if err := columns.Err(); err != nil {
+		return nil, nil, fmt.Errorf("查询表属性 information_schema.columns 出错: %s", err.Error())
+	}
+	partitionClause, err := getPartitionClause(mysql, mysqlSchema, tableName, columnTypes)
+	if err != nil {
+		return nil, nil, err
 	}
 	// 构建建表语句
-	for tableName, columns := range tableColumns {
+	if len(columnStmts) != 0 {
 		formatter := getSQLFormatter(sqldef.Y_SQL_CREATE_TABLE, sqldef.Y_SQL_CREATE_TABLE_CASE_SENSITIVE)
-		createTableStmt := fmt.Sprintf(formatter, formatKeyWord(yasdbSchema), formatKeyWord(tableName), strings.Join(columns, ",\n\t"))
-		tableDDL := fmt.Sprintln(createTableStmt)
-		tableDDLs = append(tableDDLs, tableDDL)
+		createTableStmt := fmt.Sprintf(formatter, formatKeyWord(yasdbSchema), formatKeyWord(tableName), strings.Join(columnStmts, ",\n\t"), partitionClause)
+		tableDDLs = append(tableDDLs, fmt.Sprintln(createTableStmt))
 	}
-	for column, comment := range columnComments {
+	for _, columnComment := range columnComments {
+		column, comment := columnComment[0], columnComment[1]
 		if comment != "" {
 			formatter := getSQLFormatter(sqldef.Y_SQL_COLUMN_COMMENT_FORMAT, sqldef.Y_SQL_COLUMN_COMMENT_FORMAT_CASE_SENSITIVE)
 			commentDDL := fmt.Sprintf(formatter, formatKeyWord(yasdbSchema), formatKeyWord(tableName), formatKeyWord(column), comment)
@@ -309,6 +394,24 @@ func getTableColumnDDLs(mysql *sql.DB, mysqlSchema, yasdbSchema, tableName strin
 	return tableDDLs, nullableStrs, nil
 }
 
+// isGeneratedColumn 根据information_schema.columns的EXTRA判断是否为生成列
+func isGeneratedColumn(extra string) bool {
+	extra = strings.ToUpper(extra)
+	return strings.Contains(extra, "VIRTUAL GENERATED") || strings.Contains(extra, "STORED GENERATED")
+}
+
+// getGeneratedColumnStmt 生成列转换为YashanDB的虚拟列, MySQL的STORED生成列也转换为虚拟列
+func getGeneratedColumnStmt(mysqlSchema, tableName, columnName, extra, expression string) string {
+	translated, issues := translateSQLExpression(expression)
+	if len(issues) != 0 {
+		log.Logger.Warnf("表 %s.%s 生成列 %s 的表达式 %s 无法完全转换, 请检查: %s", mysqlSchema, tableName, columnName, expression, strings.Join(issues, "; "))
+	}
+	if strings.Contains(strings.ToUpper(extra), "STORED") {
+		log.Logger.Infof("表 %s.%s 的STORED生成列 %s 转换为虚拟列", mysqlSchema, tableName, columnName)
+	}
+	return fmt.Sprintf(sqldef.Y_SQL_GENERATED_COLUMN_FORMAT, translated)
+}
+
 func getTableAutoIncrementDDLs(mysql *sql.DB, mysqlSchema, yasdbSchema, tableName string) ([]string, error) {
 	var ddls []string
 	// 查询表的自增主键列信息
@@ -419,83 +522,127 @@ func getDefaultStmt(yasType string, columnDefault string, hasDefault bool) (defa
 	return
 }
 
-func getPrimaryKeyDDLs(mysql *sql.DB, mysqlSchema, yasdbSchema, tableName string) ([]string, error) {
+// getBooleanDefaultStmt MySQL的布尔列是tinyint, 默认值是数字, 转换为TRUE或FALSE
+func getBooleanDefaultStmt(columnDefault string, hasDefault bool) string {
+	if !hasDefault || columnDefault == sqldef.M_DEFAULT_COLUMN_NULL {
+		return getDefaultStmt(typedef.Y_BOOLEAN, columnDefault, hasDefault)
+	}
+	if columnDefault == "0" || strings.EqualFold(columnDefault, "false") || columnDefault == "b'0'" {
+		return fmt.Sprintf(sqldef.Y_DEFAULT_NUMBER_FORMAT, "FALSE")
+	}
+	return fmt.Sprintf(sqldef.Y_DEFAULT_NUMBER_FORMAT, "TRUE")
+}
+
+// getIntervalDefaultStmt TIME列映射为INTERVAL DAY TO SECOND时, 默认值转换为时间间隔的文本, 如'+00 12:00:00.000000'
+func getIntervalDefaultStmt(columnDefault string, hasDefault bool) string {
+	// NULL和无法解析的默认值按原样导出
+	d, err := parseMySQLTime(columnDefault)
+	if !hasDefault || err != nil {
+		return getDefaultStmt(yashan_interval_type, columnDefault, hasDefault)
+	}
+	return fmt.Sprintf(sqldef.Y_DEFAULT_STRING_FORMAT, formatIntervalDayToSecond(d))
+}
+
+func getPrimaryKeyDDLs(mysql *sql.DB, namer *indexNamer, mysqlSchema, yasdbSchema, tableName string) ([]string, error) {
 	var primarykeys []string
 	indexes, err := getIndexes(mysql, mysqlSchema, tableName)
 	if err != nil {
 		return nil, err
 	}
-	// 以索引名称分组索引列
-	indexMap := make(map[string][]string)
-	for _, index := range indexes {
-		if strings.ToUpper(index.KeyName) != "PRIMARY" { // 排除主键
-			continue
-		}
-		indexMap[index.KeyName] = append(indexMap[index.KeyName], index.ColumnName)
+	ciColumns, err := getCaseInsensitiveColumns(mysql, mysqlSchema, tableName)
+	if err != nil {
+		return nil, err
 	}
+	// 以索引名称分组索引列
+	keyNames, indexMap := groupIndexColumns(indexes, func(index Index) bool {
+		return strings.ToUpper(index.KeyName) == "PRIMARY"
+	})
 	// 生成创建索引的语句
-	for _, columns := range indexMap {
+	for _, keyName := range keyNames {
+		columns := getIndexColumnNames(indexMap[keyName])
+		for _, index := range indexMap[keyName] {
+			if index.SubPart > 0 {
+				log.Logger.Warnf("表 %s.%s 主键列 %s 是长度为 %d 的前缀索引, YashanDB主键不支持前缀, 改为使用整列", mysqlSchema, tableName, index.ColumnName, index.SubPart)
+			}
+		}
 		formatter := getSQLFormatter(sqldef.Y_SQL_ADD_PRIMARY_KEY, sqldef.Y_SQL_ADD_PRIMARY_KEY_CASE_SENSITIVE)
 		primarykey := fmt.Sprintf(formatter, formatKeyWord(yasdbSchema), formatKeyWord(tableName), genColumnString(columns))
 		primarykeys = append(primarykeys, primarykey)
+
+		// 主键只能建在列上, 大小写不敏感的列另外创建UPPER唯一索引, 保证只有大小写不同的值不能重复
+		if !hasCaseInsensitiveColumn(indexMap[keyName], ciColumns) {
+			continue
+		}
+		wholeColumns := make([]Index, 0, len(indexMap[keyName]))
+		for _, index := range indexMap[keyName] {
+			index.SubPart = 0
+			wholeColumns = append(wholeColumns, index)
+		}
+		// 主键只包含整列, 不会因为表达式无法转换而跳过
+		columnString, _ := genIndexColumnString(mysqlSchema, tableName, wholeColumns, ciColumns)
+		indexName := namer.name(mysqlSchema, tableName, tableName+"_pk_ci", columns)
+		formatter = getSQLFormatter(sqldef.Y_SQL_CREATE_UNIQUE_INDEX, sqldef.Y_SQL_CREATE_UNIQUE_INDEX_CASE_SENSITIVE)
+		primarykeys = append(primarykeys, fmt.Sprintf(formatter, formatKeyWord(yasdbSchema), formatKeyWord(indexName),
+			formatKeyWord(yasdbSchema), formatKeyWord(tableName), columnString))
 	}
 	return primarykeys, nil
 }
 
-func getUniqueIndexDDLs(mysql *sql.DB, mysqlSchema, yasdbSchema, tableName string) ([]string, error) {
+func getUniqueIndexDDLs(mysql *sql.DB, namer *indexNamer, mysqlSchema, yasdbSchema, tableName string) ([]string, error) {
 	var ddls []string
 	indexes, err := getIndexes(mysql, mysqlSchema, tableName)
 	if err != nil {
 		return nil, err
 	}
-	indexMap := make(map[string][]string)
-	for _, index := range indexes {
-		if index.KeyName == "PRIMARY" || index.NonUnique != 0 {
-			continue
-		}
-		indexMap[index.KeyName] = append(indexMap[index.KeyName], index.ColumnName)
+	keyNames, indexMap := groupIndexColumns(indexes, func(index Index) bool {
+		return index.KeyName != "PRIMARY" && index.NonUnique == 0
+	})
+	ciColumns, err := getCaseInsensitiveColumns(mysql, mysqlSchema, tableName)
+	if err != nil {
+		return nil, err
 	}
 
 	// 生成创建索引的语句
-	for _, columns := range indexMap {
-		columnString := genColumnString(columns)
-		columnStringName := strings.Join(columns, "_")
-		indexName := "idx_" + tableName + "_" + columnStringName
-		if len(indexName) > 64 {
-			indexName = indexName[0:64]
+	for _, keyName := range keyNames {
+		columns := getIndexColumnNames(indexMap[keyName])
+		columnString, ok := genIndexColumnString(mysqlSchema, tableName, indexMap[keyName], ciColumns)
+		if !ok {
+			continue
 		}
+		indexName := namer.name(mysqlSchema, tableName, keyName, columns)
 		formatter := getSQLFormatter(sqldef.Y_SQL_CREATE_UNIQUE_INDEX, sqldef.Y_SQL_CREATE_UNIQUE_INDEX_CASE_SENSITIVE)
 		ddls = append(ddls, fmt.Sprintf(formatter, formatKeyWord(yasdbSchema),
 			formatKeyWord(indexName), formatKeyWord(yasdbSchema), formatKeyWord(tableName), columnString))
 
+		// 唯一约束只能建在列上, 索引中有表达式、前缀、降序列或UPPER函数时只保留唯一索引
+		if !isPlainColumnIndex(indexMap[keyName], ciColumns) {
+			continue
+		}
+		columnString = genColumnString(columns)
 		formatter = getSQLFormatter(sqldef.Y_SQL_ADD_UNIQUE_CONSTRAINT, sqldef.Y_SQL_ADD_UNIQUE_CONSTRAINT_CASE_SENSITIVE)
-		ddls = append(ddls, fmt.Sprintf(formatter, formatKeyWord(yasdbSchema), formatKeyWord(tableName), indexName, columnString))
+		ddls = append(ddls, fmt.Sprintf(formatter, formatKeyWord(yasdbSchema), formatKeyWord(tableName), formatKeyWord(indexName), columnString))
 	}
 	return ddls, nil
 }
 
-func getNonUniqueIndexDDL(mysql *sql.DB, mysqlSchema, yasdbSchema, tableName string) ([]string, error) {
+func getNonUniqueIndexDDL(mysql *sql.DB, namer *indexNamer, mysqlSchema, yasdbSchema, tableName string) ([]string, error) {
 	var ddls []string
 	indexes, err := getIndexes(mysql, mysqlSchema, tableName)
 	if err != nil {
 		return nil, err
 	}
-	// 以索引名称分组索引列
-	indexMap := make(map[string][]string)
-	for _, index := range indexes {
-		if index.KeyName == "PRIMARY" || index.NonUnique != 1 { // 排除主键和唯一索引
-			continue
-		}
-		indexMap[index.KeyName] = append(indexMap[index.KeyName], index.ColumnName)
-	}
+	// 以索引名称分组索引列, 排除主键和唯一索引
+	keyNames, indexMap := groupIndexColumns(indexes, func(index Index) bool {
+		return index.KeyName != "PRIMARY" && index.NonUnique == 1
+	})
 	// 生成创建索引的语句
-	for _, columns := range indexMap {
-		columnString := genColumnString(columns)
-		columnStringName := strings.Join(columns, "_")
-		indexName := "idx_" + tableName + "_" + columnStringName
-		if len(indexName) > 64 {
-			indexName = indexName[0:64]
+	for _, keyName := range keyNames {
+		columns := getIndexColumnNames(indexMap[keyName])
+		columnString, ok := genIndexColumnString(mysqlSchema, tableName, indexMap[keyName], nil)
+		if !ok {
+			continue
 		}
+		indexName := namer.name(mysqlSchema, tableName, keyName, columns)
 		formatter := getSQLFormatter(sqldef.Y_SQL_CREATE_INDEX, sqldef.Y_SQL_CREATE_INDEX_CASE_SENSITIVE)
 		ddls = append(ddls, fmt.Sprintf(formatter, formatKeyWord(yasdbSchema), formatKeyWord(indexName),
 			formatKeyWord(yasdbSchema), formatKeyWord(tableName), columnString))
@@ -503,6 +650,17 @@ func getNonUniqueIndexDDL(mysql *sql.DB, mysqlSchema, yasdbSchema, tableName str
 	return ddls, nil
 }
 
+// foreignKey 外键约束, 多列外键的列按ORDINAL_POSITION排列, 与引用列一一对应
+type foreignKey struct {
+	name              string
+	columns           []string
+	referencedSchema  string
+	referencedTable   string
+	referencedColumns []string
+	deleteRule        string
+	updateRule        string
+}
+
 func getTableForeignKeys(db *sql.DB, mysqlSchema, yasdbSchema, tableName string) ([]string, error) {
 	rows, err := db.Query(sqldef.M_SQL_QUERY_FOREIGN_KEY, mysqlSchema, tableName)
 	if err != nil {
@@ -510,63 +668,90 @@ func getTableForeignKeys(db *sql.DB, mysqlSchema, yasdbSchema, tableName string)
 	}
 	defer rows.Close()
 
-	var constraints []string
+	var fks []*foreignKey
 	for rows.Next() {
-		var constraintName, columnName, referencedTableName, referencedColumnName sql.NullString
-		err := rows.Scan(&constraintName, &columnName, &referencedTableName, &referencedColumnName)
+		var constraintName, columnName, referencedSchema, referencedTableName, referencedColumnName, deleteRule, updateRule sql.NullString
+		err := rows.Scan(&constraintName, &columnName, &referencedSchema, &referencedTableName, &referencedColumnName, &deleteRule, &updateRule)
 		if err != nil {
 			return nil, err
 		}
+		// 结果按约束名和列的位置排序, 同一个约束的列是连续的
+		if len(fks) == 0 || fks[len(fks)-1].name != constraintName.String {
+			fks = append(fks, &foreignKey{
+				name:             constraintName.String,
+				referencedSchema: referencedSchema.String,
+				referencedTable:  referencedTableName.String,
+				deleteRule:       deleteRule.String,
+				updateRule:       updateRule.String,
+			})
+		}
+		fk := fks[len(fks)-1]
+		fk.columns = append(fk.columns, columnName.String)
+		fk.referencedColumns = append(fk.referencedColumns, referencedColumnName.String)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var constraints []string
+	for _, fk := range fks {
+		referencedYasdbSchema := yasdbSchema
+		if fk.referencedSchema != mysqlSchema {
+			var ok bool
+			if referencedYasdbSchema, ok = remapMySQLSchema(fk.referencedSchema); !ok {
+				log.Logger.Warnf("表 %s.%s 外键 %s 引用的表 %s.%s 不在迁移范围内, 引用的schema保持为 %s", mysqlSchema, tableName, fk.name, fk.referencedSchema, fk.referencedTable, fk.referencedSchema)
+				referencedYasdbSchema = fk.referencedSchema
+			}
+		}
 		formatter := getSQLFormatter(sqldef.Y_SQL_ADD_FOREIGN_KEY, sqldef.Y_SQL_ADD_FOREIGN_KEY_CASE_SENSITIVE)
 		constraint := fmt.Sprintf(
 			formatter,
 			formatKeyWord(yasdbSchema),
 			formatKeyWord(tableName),
-			formatKeyWord(constraintName.String),
-			formatKeyWord(columnName.String),
-			formatKeyWord(yasdbSchema),
-			formatKeyWord(referencedTableName.String),
-			formatKeyWord(referencedColumnName.String),
+			formatKeyWord(fk.name),
+			genColumnString(fk.columns),
+			formatKeyWord(referencedYasdbSchema),
+			formatKeyWord(fk.referencedTable),
+			genColumnString(fk.referencedColumns),
+			getForeignKeyRule(mysqlSchema, tableName, fk),
 		)
 		constraints = append(constraints, constraint)
 	}
-	if err = rows.Err(); err != nil {
-		return nil, err
-	}
 	return constraints, nil
 }
 
-func getViewDDLs(db *sql.DB, mysqlSchema, yasdbSchema string) ([]string, error) {
-	caseSensitive := confdef.GetM2YConfig().Yashan.CaseSensitive
-	var viewDDLs []string
-	rows, err := db.Query(fmt.Sprintf(sqldef.M_SQL_QUERY_VIEW, mysqlSchema))
-	if err != nil {
-		return nil, err
+// getForeignKeyRule YashanDB支持ON DELETE CASCADE和ON DELETE SET NULL, 不支持ON UPDATE,
+// 不支持的规则按NO ACTION处理并输出警告
+func getForeignKeyRule(mysqlSchema, tableName string, fk *foreignKey) string {
+	if fk.updateRule != fk_rule_restrict && fk.updateRule != fk_rule_no_action && len(fk.updateRule) != 0 {
+		log.Logger.Warnf("表 %s.%s 外键 %s 的 ON UPDATE %s YashanDB不支持, 已忽略", mysqlSchema, tableName, fk.name, fk.updateRule)
+	}
+	switch fk.deleteRule {
+	case fk_rule_cascade, fk_rule_set_null:
+		return fmt.Sprintf(sqldef.Y_SQL_ON_DELETE_FORMAT, fk.deleteRule)
+	case fk_rule_restrict, fk_rule_no_action, "":
+		return ""
+	default:
+		log.Logger.Warnf("表 %s.%s 外键 %s 的 ON DELETE %s YashanDB不支持, 已忽略", mysqlSchema, tableName, fk.name, fk.deleteRule)
+		return ""
 	}
-	defer rows.Close()
+}
 
-	for rows.Next() {
-		var viewDDL, viewName string
-		if err := rows.Scan(&viewName, &viewDDL); err != nil {
-			return nil, err
+// remapMySQLSchema 返回MySQL的database在YashanDB中对应的schema, 不在迁移范围内时返回false
+func remapMySQLSchema(mysqlSchema string) (string, bool) {
+	conf := confdef.GetM2YConfig()
+	if len(conf.MySQL.Tables) != 0 {
+		if conf.MySQL.Database == mysqlSchema && len(conf.Yashan.RemapSchemas) > 0 {
+			return conf.Yashan.RemapSchemas[0], true
 		}
-		viewDDL = strings.ReplaceAll(viewDDL, "`"+mysqlSchema+"`.", "")
-		viewDDL = strings.ReplaceAll(viewDDL, mysqlSchema+".", "")
-		if caseSensitive {
-			// 反引号换成引号
-			viewDDL = strings.ReplaceAll(viewDDL, "`", "\"")
-		} else {
-			// 转成全大写，反引号换成引号
-			viewDDL = strings.ReplaceAll(strings.ToUpper(viewDDL), "`", "\"")
-		}
-		if len(strings.TrimSpace(viewDDL)) == 0 {
-			continue
+		return "", false
+	}
+	for i, schema := range conf.MySQL.Schemas {
+		if schema == mysqlSchema && i < len(conf.Yashan.RemapSchemas) {
+			return conf.Yashan.RemapSchemas[i], true
 		}
-		formatter := getSQLFormatter(sqldef.Y_SQL_CREATE_VIEW, sqldef.Y_SQL_CREATE_VIEW_CASE_SENSITIVE)
-		viewDDL = fmt.Sprintf(formatter, formatKeyWord(yasdbSchema), formatKeyWord(viewName), viewDDL)
-		viewDDLs = append(viewDDLs, viewDDL)
 	}
-	return viewDDLs, nil
+	return "", false
 }
 
 func genColumnString(columns []string) string {
@@ -609,7 +794,7 @@ func getIndexes8(mysql *sql.DB, mysqlSchema, tableName string) ([]Index, error)
 		nonUnique     int
 		keyName       string
 		seqInIndex    int
-		columnName    string
+		columnName    sql.NullString
 		Collation     sql.NullString
 		Cardinality   sql.NullString
 		Sub_part      sql.NullString
@@ -632,9 +817,12 @@ func getIndexes8(mysql *sql.DB, mysqlSchema, tableName string) ([]Index, error)
 			Table:      table,
 			NonUnique:  nonUnique,
 			KeyName:    keyName,
-			ColumnName: columnName,
+			ColumnName: columnName.String,
 			IndexType:  indexType,
 			SeqInIndex: seqInIndex,
+			Collation:  Collation.String,
+			SubPart:    parseIndexSubPart(Sub_part),
+			Expression: Expression.String,
 		}
 		indexes = append(indexes, index)
 	}
@@ -684,6 +872,8 @@ func getIndexes5(mysql *sql.DB, mysqlSchema, tableName string) ([]Index, error)
 			ColumnName: columnName,
 			IndexType:  indexType,
 			SeqInIndex: seqInIndex,
+			Collation:  collation.String,
+			SubPart:    parseIndexSubPart(subPart),
 		}
 		indexes = append(indexes, index)
 	}
@@ -0,0 +1,178 @@
+package modules
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"m2y/db"
+	"m2y/defs/sqldef"
+)
+
+var ddlColumnsResult = []string{"column_name", "data_type", "character_maximum_length", "numeric_precision", "numeric_scale", "column_comment",
+	"column_type_length", "is_nullable", "column_default", "extra", "column_type", "character_set_name", "collation_name", "generation_expression"}
+
+// TestDealTablesDDLs 导出的DDL与testdata/ddls中的golden文件一致: 表按表名排序, 列按ORDINAL_POSITION,
+// 注释按列的顺序, 索引按索引名排序, 索引列按SeqInIndex排序
+func TestDealTablesDDLs(t *testing.T) {
+	cases := []struct {
+		name    string
+		version string
+	}{
+		{name: "mysql8", version: db.MYSQL_VERSION_8},
+		{name: "mysql5", version: db.MYSQL_VERSION_5},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			setMySQLVersion(t, c.version)
+			mysql, mysqlFake := newFakeDB(t)
+			addShopDDLFixture(mysqlFake, c.version)
+			// 表名不按顺序传入
+			if err := DealTablesDDLs(mysql, nil, "shop", "SHOP", []string{"orders", "customers"}, false); err != nil {
+				t.Fatal(err)
+			}
+			if unmatched := mysqlFake.getUnmatched(); len(unmatched) != 0 {
+				t.Fatalf("没有预设结果的查询: %v", unmatched)
+			}
+			checkGoldenFile(t, path.Join(getTablesDDLPath(), "shop_tables.sql"), filepath.Join("testdata", "ddls", c.name, "shop_tables.sql"))
+			checkGoldenFile(t, path.Join(getOthersDDLPath(), "shop_others.sql"), filepath.Join("testdata", "ddls", c.name, "shop_others.sql"))
+		})
+	}
+}
+
+func setMySQLVersion(t *testing.T, version string) {
+	old := db.MySQLVersion
+	db.MySQLVersion = version
+	t.Cleanup(func() {
+		db.MySQLVersion = old
+	})
+}
+
+// checkGoldenFile 比较导出的文件与golden文件, 指定-update时用导出的文件覆盖golden文件
+func checkGoldenFile(t *testing.T, fileName, goldenName string) {
+	t.Helper()
+	actual, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(goldenName), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(goldenName, actual, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := os.ReadFile(goldenName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(actual) != string(expected) {
+		t.Errorf("%s 与 %s 不一致\n--- 导出结果:\n%s\n--- 期望:\n%s", fileName, goldenName, actual, expected)
+	}
+}
+
+// addShopDDLFixture 预设shop库中customers和orders两张表的元数据, SHOW INDEXES的结果故意不按SeqInIndex排列
+func addShopDDLFixture(f *fakeDB, version string) {
+	intType, intLength, bigintType, bigintLength := "int", "", "bigint", ""
+	if version == db.MYSQL_VERSION_5 {
+		intType, intLength, bigintType, bigintLength = "int(11)", "11", "bigint(20)", "20"
+	}
+	f.addRows(sqldef.M_SQL_QUERY_COLUMNS, []interface{}{"shop", "customers"}, ddlColumnsResult,
+		[]driver.Value{"id", "int", nil, int64(10), int64(0), "", intLength, "NO", "", "auto_increment", intType, "", "", ""},
+		[]driver.Value{"name", "varchar", int64(64), nil, nil, "客户姓名", "64", "NO", "", "", "varchar(64)", "gbk", "gbk_chinese_ci", ""},
+		[]driver.Value{"email", "varchar", int64(128), nil, nil, "联系邮箱", "128", "YES", "", "", "varchar(128)", "utf8mb4", "utf8mb4_general_ci", ""},
+		[]driver.Value{"created", "datetime", nil, nil, nil, "", "", "NO", "CURRENT_TIMESTAMP", "", "datetime", "", "", ""},
+	)
+	f.addRows(sqldef.M_SQL_QUERY_COLUMNS, []interface{}{"shop", "orders"}, ddlColumnsResult,
+		[]driver.Value{"id", "bigint", nil, int64(19), int64(0), "", bigintLength, "NO", "", "auto_increment", bigintType, "", "", ""},
+		[]driver.Value{"customer_id", "int", nil, int64(10), int64(0), "客户ID", intLength, "NO", "", "", intType, "", "", ""},
+		[]driver.Value{"amount", "decimal", nil, int64(10), int64(2), "订单金额", "10,2", "NO", "0.00", "", "decimal(10,2)", "", "", ""},
+		[]driver.Value{"amount_cents", "bigint", nil, int64(19), int64(0), "", bigintLength, "YES", "", "VIRTUAL GENERATED", bigintType, "", "", "(`amount` * 100)"},
+		[]driver.Value{"status", "enum", int64(4), nil, nil, "", "'new','paid'", "NO", "new", "", "enum('new','paid')", "utf8mb4", "utf8mb4_general_ci", ""},
+		[]driver.Value{"note", "text", int64(65535), nil, nil, "备注", "", "YES", "", "", "text", "utf8mb4", "utf8mb4_general_ci", ""},
+	)
+	for _, table := range []string{"customers", "orders"} {
+		f.addRows(sqldef.M_SQL_QUERY_PARTITIONS, []interface{}{"shop", table},
+			[]string{"partition_name", "subpartition_name", "partition_method", "subpartition_method", "partition_expression", "subpartition_expression", "partition_description"})
+		f.addRows(sqldef.M_SQL_QUERY_AUTO_INCREMENT, []interface{}{"shop", table}, []string{"COLUMN_NAME"}, []driver.Value{"id"})
+	}
+	f.addRows(fmt.Sprintf(sqldef.M_SQL_QUERY_MAX_ID, "id", "shop", "customers"), nil, []string{"max"}, []driver.Value{"101"})
+	f.addRows(fmt.Sprintf(sqldef.M_SQL_QUERY_MAX_ID, "id", "shop", "orders"), nil, []string{"max"}, []driver.Value{"5001"})
+	f.addRows(sqldef.M_SQL_QUERY_TABLE_COMMENTS, []interface{}{"shop", "customers"}, []string{"table_comment"}, []driver.Value{"客户表"})
+	f.addRows(sqldef.M_SQL_QUERY_TABLE_COMMENTS, []interface{}{"shop", "orders"}, []string{"table_comment"}, []driver.Value{""})
+	f.addRows(sqldef.M_SQL_QUERY_ENUM_COLUMNS, []interface{}{"shop", "customers"}, []string{"column_name", "data_type", "column_type"})
+	f.addRows(sqldef.M_SQL_QUERY_ENUM_COLUMNS, []interface{}{"shop", "orders"}, []string{"column_name", "data_type", "column_type"},
+		[]driver.Value{"status", "enum", "enum('new','paid')"},
+	)
+	f.addRows(sqldef.M_SQL_QUERY_CHECK_CONSTRAINTS, []interface{}{"shop", "customers"}, []string{"constraint_name", "check_clause", "enforced"},
+		[]driver.Value{"chk_email", "(`email` like _utf8mb4'%@%')", "YES"},
+	)
+	f.addRows(sqldef.M_SQL_QUERY_CHECK_CONSTRAINTS, []interface{}{"shop", "orders"}, []string{"constraint_name", "check_clause", "enforced"})
+
+	showIndexColumns := []string{"Table", "Non_unique", "Key_name", "Seq_in_index", "Column_name", "Collation", "Cardinality",
+		"Sub_part", "Packed", "Null", "Index_type", "Comment", "Index_comment"}
+	indexRow := func(table string, nonUnique int64, keyName string, seq int64, column string, subPart interface{}) []driver.Value {
+		row := []driver.Value{table, nonUnique, keyName, seq, column, "A", int64(0), subPart, nil, "", "BTREE", "", ""}
+		if version == db.MYSQL_VERSION_8 {
+			row = append(row, "YES", nil)
+		}
+		return row
+	}
+	customerIndexes := [][]driver.Value{
+		indexRow("customers", 1, "idx_name_created", 2, "created", nil),
+		indexRow("customers", 0, "uk_email", 1, "email", nil),
+		indexRow("customers", 0, "PRIMARY", 1, "id", nil),
+		indexRow("customers", 1, "idx_name_created", 1, "name", nil),
+	}
+	orderIndexes := [][]driver.Value{
+		indexRow("orders", 0, "PRIMARY", 1, "id", nil),
+		indexRow("orders", 1, "idx_note", 1, "note", int64(20)),
+		indexRow("orders", 1, "idx_customer_status", 2, "status", nil),
+		indexRow("orders", 1, "idx_customer_status", 1, "customer_id", nil),
+	}
+	if version == db.MYSQL_VERSION_8 {
+		showIndexColumns = append(showIndexColumns, "Visible", "Expression")
+		// 函数索引的Column_name为NULL, 无法转换的表达式不导出索引
+		functionalIndexRow := func(table, keyName, expression string) []driver.Value {
+			row := indexRow(table, 1, keyName, 1, "", nil)
+			row[4], row[len(row)-1] = nil, expression
+			return row
+		}
+		customerIndexes = append(customerIndexes, functionalIndexRow("customers", "idx_email_lower", "lower(`email`)"))
+		orderIndexes = append(orderIndexes, functionalIndexRow("orders", "idx_note_sku", "(`note` ->> _utf8mb4'$.sku')"))
+	}
+	f.addRows(fmt.Sprintf(sqldef.M_SQL_SHOW_INDEX, "shop", "customers"), nil, showIndexColumns, customerIndexes...)
+	f.addRows(fmt.Sprintf(sqldef.M_SQL_SHOW_INDEX, "shop", "orders"), nil, showIndexColumns, orderIndexes...)
+
+	fkColumns := []string{"constraint_name", "column_name", "referenced_table_schema", "referenced_table_name", "referenced_column_name", "delete_rule", "update_rule"}
+	f.addRows(sqldef.M_SQL_QUERY_FOREIGN_KEY, []interface{}{"shop", "customers"}, fkColumns)
+	f.addRows(sqldef.M_SQL_QUERY_FOREIGN_KEY, []interface{}{"shop", "orders"}, fkColumns,
+		[]driver.Value{"fk_orders_customer", "customer_id", "shop", "customers", "id", "CASCADE", "NO ACTION"},
+	)
+	f.addRows(sqldef.M_SQL_QUERY_TRIGGERS, []interface{}{"shop"},
+		[]string{"trigger_name", "event_manipulation", "event_object_table", "action_timing", "action_statement"})
+}
+
+// TestGetIntervalDefaultStmt TIME列映射为interval day to second时默认值按时间间隔的文本导出
+func TestGetIntervalDefaultStmt(t *testing.T) {
+	cases := []struct {
+		columnDefault string
+		hasDefault    bool
+		expected      string
+	}{
+		{columnDefault: "12:00:00", hasDefault: true, expected: " default '+00 12:00:00.000000'"},
+		{columnDefault: "838:59:59.5", hasDefault: true, expected: " default '+34 22:59:59.500000'"},
+		{columnDefault: "-01:30:00", hasDefault: true, expected: " default '-00 01:30:00.000000'"},
+		{columnDefault: sqldef.M_DEFAULT_COLUMN_NULL, hasDefault: true, expected: sqldef.Y_DEFAULT_NULL},
+		{columnDefault: "", hasDefault: false, expected: ""},
+	}
+	for _, c := range cases {
+		if actual := getIntervalDefaultStmt(c.columnDefault, c.hasDefault); actual != c.expected {
+			t.Errorf("getIntervalDefaultStmt(%q, %v) = %q, 期望 %q", c.columnDefault, c.hasDefault, actual, c.expected)
+		}
+	}
+}
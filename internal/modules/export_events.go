@@ -0,0 +1,217 @@
+package modules
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"m2y/defs/sqldef"
+	"m2y/log"
+)
+
+const (
+	event_type_one_time       = "ONE TIME"
+	event_status_enabled      = "ENABLED"
+	event_completion_preserve = "PRESERVE"
+	event_time_zone_system    = "SYSTEM"
+
+	events_file_format        = "%s_events.sql"
+	events_review_file_format = "%s_events_review.sql"
+)
+
+// MySQL事件的间隔单位对应的DBMS_SCHEDULER的FREQ, factor为换算成FREQ单位的倍数
+var eventIntervalFreqs = map[string]struct {
+	freq   string
+	factor int
+}{
+	"YEAR":    {"YEARLY", 1},
+	"QUARTER": {"MONTHLY", 3},
+	"MONTH":   {"MONTHLY", 1},
+	"WEEK":    {"WEEKLY", 1},
+	"DAY":     {"DAILY", 1},
+	"HOUR":    {"HOURLY", 1},
+	"MINUTE":  {"MINUTELY", 1},
+	"SECOND":  {"SECONDLY", 1},
+}
+
+// 复合单位换算成最小的单位, 如HOUR_MINUTE的'1:30'换算为90分钟, 数组为各部分换算成最小单位的倍数,
+// 事件的间隔和表达式中的INTERVAL共用
+var mysqlCompoundIntervals = map[string][]int{
+	"YEAR_MONTH":    {12, 1},
+	"DAY_HOUR":      {24, 1},
+	"DAY_MINUTE":    {1440, 60, 1},
+	"DAY_SECOND":    {86400, 3600, 60, 1},
+	"HOUR_MINUTE":   {60, 1},
+	"HOUR_SECOND":   {3600, 60, 1},
+	"MINUTE_SECOND": {60, 1},
+}
+
+type mysqlEvent struct {
+	name          string
+	definition    string
+	eventType     string // ONE TIME或RECURRING
+	executeAt     sql.NullString
+	intervalValue sql.NullString
+	intervalField sql.NullString
+	starts        sql.NullString
+	ends          sql.NullString
+	status        string // ENABLED、DISABLED或SLAVESIDE_DISABLED
+	onCompletion  string // PRESERVE或NOT PRESERVE
+	comment       string
+	timeZone      string
+}
+
+// exportEvents 将schema中的事件导出为YashanDB的DBMS_SCHEDULER作业, 转换成功的写入{schema}_events.sql,
+// 无法完全转换的连同原因和原始定义写入{schema}_events_review.sql, 需要人工修改后执行
+func exportEvents(mysql *sql.DB, applier *ddlApplier, mysqlSchema, yasdbSchema string) error {
+	events, err := getEvents(mysql, mysqlSchema)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(path.Join(getOthersDDLPath(), fmt.Sprintf(events_file_format, mysqlSchema)))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	reviewFileName := path.Join(getOthersDDLPath(), fmt.Sprintf(events_review_file_format, mysqlSchema))
+	var reviews []string
+	for _, event := range events {
+		if event.timeZone != event_time_zone_system {
+			log.Logger.Warnf("事件 %s.%s 的时区为 %s, 作业的开始和结束时间按YashanDB的时区执行, 请确认", mysqlSchema, event.name, event.timeZone)
+		}
+		ddl, issues := translateEvent(event, yasdbSchema)
+		if len(issues) != 0 {
+			log.Logger.Warnf("事件 %s.%s 无法自动转换, 请在 %s 中人工修改: %s", mysqlSchema, event.name, reviewFileName, strings.Join(issues, "; "))
+			reviews = append(reviews, genPLReview("事件", mysqlSchema+"."+event.name, issues, event.definition, ddl))
+			continue
+		}
+		if _, err := file.WriteString(ddl + sqldef.Y_SQL_PL_TERMINATOR); err != nil {
+			return err
+		}
+		applier.addPL(ddl_phase_programs, yasdbSchema+"."+event.name, ddl)
+	}
+	return writePLReviews(reviewFileName, reviews)
+}
+
+func getEvents(mysql *sql.DB, mysqlSchema string) ([]mysqlEvent, error) {
+	rows, err := mysql.Query(sqldef.M_SQL_QUERY_EVENTS, mysqlSchema)
+	if err != nil {
+		return nil, fmt.Errorf("查询事件 information_schema.events 出错: %v", err)
+	}
+	defer rows.Close()
+	var events []mysqlEvent
+	for rows.Next() {
+		var event mysqlEvent
+		if err := rows.Scan(&event.name, &event.definition, &event.eventType, &event.executeAt, &event.intervalValue, &event.intervalField,
+			&event.starts, &event.ends, &event.status, &event.onCompletion, &event.comment, &event.timeZone); err != nil {
+			return nil, fmt.Errorf("查询事件 information_schema.events 出错: %v", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询事件 information_schema.events 出错: %v", err)
+	}
+	return events, nil
+}
+
+// translateEvent 将MySQL的事件转换为创建DBMS_SCHEDULER作业的PL块, 事件的语句转换为作业的PLSQL_BLOCK,
+// STATUS对应作业是否启用, ON COMPLETION NOT PRESERVE对应执行完成后自动删除
+func translateEvent(event mysqlEvent, yasdbSchema string) (string, []string) {
+	tokens, err := tokenizePL(event.definition)
+	if err != nil {
+		return "", []string{err.Error()}
+	}
+	p := newPLTranslator(tokens, false)
+	block := p.body()
+	var action strings.Builder
+	if len(block.decls) != 0 {
+		action.WriteString("DECLARE\n")
+		action.WriteString(strings.Join(block.decls, "\n") + "\n")
+	}
+	action.WriteString(strings.Join(block.render(""), "\n") + "\n")
+	action.WriteString("END;")
+
+	startDate, endDate, repeatInterval := "NULL", "NULL", "NULL"
+	if event.eventType == event_type_one_time {
+		startDate = formatEventTimestamp(event.executeAt)
+	} else {
+		startDate = formatEventTimestamp(event.starts)
+		endDate = formatEventTimestamp(event.ends)
+		repeatInterval, err = getEventRepeatInterval(event.intervalValue.String, event.intervalField.String)
+		if err != nil {
+			p.issue("%v", err)
+		}
+	}
+	enabled, autoDrop := "FALSE", "TRUE"
+	if event.status == event_status_enabled {
+		enabled = "TRUE"
+	}
+	if event.onCompletion == event_completion_preserve {
+		autoDrop = "FALSE"
+	}
+	jobName := fmt.Sprintf(getSQLFormatter(sqldef.Y_SQL_JOB_NAME, sqldef.Y_SQL_JOB_NAME_CASE_SENSITIVE), formatKeyWord(yasdbSchema), formatKeyWord(event.name))
+	ddl := fmt.Sprintf(sqldef.Y_SQL_CREATE_JOB, jobName, escapeSQLString(action.String()), startDate, repeatInterval, endDate,
+		enabled, autoDrop, escapeSQLString(event.comment))
+	return ddl, p.issues
+}
+
+// getEventRepeatInterval 将EVERY n unit转换为DBMS_SCHEDULER的repeat_interval
+func getEventRepeatInterval(value, field string) (string, error) {
+	field = strings.ToUpper(field)
+	interval, unit, ok := parseMySQLInterval(value, field)
+	if !ok {
+		return "", fmt.Errorf("无法解析的事件间隔: EVERY '%s' %s", value, field)
+	}
+	freq, ok := eventIntervalFreqs[unit]
+	if !ok {
+		return "", fmt.Errorf("不支持的事件间隔单位: EVERY '%s' %s", value, field)
+	}
+	if interval <= 0 {
+		return "", fmt.Errorf("无法解析的事件间隔: EVERY '%s' %s", value, field)
+	}
+	return fmt.Sprintf(sqldef.Y_SQL_REPEAT_INTERVAL_FORMAT, freq.freq, interval*freq.factor), nil
+}
+
+// parseMySQLInterval 将MySQL的间隔值换算成最小的单位, 返回数值和单位, 如HOUR_MINUTE的'1:30'返回90和MINUTE,
+// 不是复合单位时返回原单位, 不处理负号
+func parseMySQLInterval(value, field string) (int, string, bool) {
+	var numbers []int
+	for _, s := range strings.FieldsFunc(value, func(r rune) bool { return !unicode.IsDigit(r) }) {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, "", false
+		}
+		numbers = append(numbers, n)
+	}
+	multipliers := []int{1}
+	unit := field
+	if m, ok := mysqlCompoundIntervals[field]; ok {
+		multipliers = m
+		unit = field[strings.LastIndex(field, "_")+1:]
+	}
+	if len(numbers) == 0 || len(numbers) > len(multipliers) {
+		return 0, "", false
+	}
+	// 省略的高位按0处理, 如DAY_SECOND的'12:00:00'
+	offset := len(multipliers) - len(numbers)
+	var interval int
+	for i, n := range numbers {
+		interval += n * multipliers[offset+i]
+	}
+	return interval, unit, true
+}
+
+func formatEventTimestamp(t sql.NullString) string {
+	if !t.Valid || len(t.String) == 0 {
+		return "NULL"
+	}
+	return fmt.Sprintf(sqldef.Y_SQL_TIMESTAMP_LITERAL, t.String)
+}
+
+func escapeSQLString(s string) string {
+	return strings.ReplaceAll(s, "'", "''")
+}
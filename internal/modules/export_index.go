@@ -0,0 +1,212 @@
+package modules
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"m2y/defs/confdef"
+	"m2y/defs/sqldef"
+	"m2y/log"
+)
+
+const (
+	index_collation_desc = "D"
+	index_expression_col = "expr"
+)
+
+var (
+	// MySQL在函数索引表达式中的字符串前会带上字符集, 如 _utf8mb4'abc'
+	charsetIntroducerRegexp = regexp.MustCompile(`(^|[^\w])_(utf8mb4|utf8mb3|utf8|latin1|binary|ascii|gbk)'`)
+	backQuotedIdentRegexp   = regexp.MustCompile("`([^`]+)`")
+)
+
+// groupIndexColumns 以索引名称分组满足filter的索引列, 索引名称按字母顺序返回, 索引列按SeqInIndex排序
+func groupIndexColumns(indexes []Index, filter func(Index) bool) ([]string, map[string][]Index) {
+	sorted := make([]Index, 0, len(indexes))
+	for _, index := range indexes {
+		if filter(index) {
+			sorted = append(sorted, index)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].KeyName != sorted[j].KeyName {
+			return sorted[i].KeyName < sorted[j].KeyName
+		}
+		return sorted[i].SeqInIndex < sorted[j].SeqInIndex
+	})
+	var keyNames []string
+	indexMap := make(map[string][]Index)
+	for _, index := range sorted {
+		if _, ok := indexMap[index.KeyName]; !ok {
+			keyNames = append(keyNames, index.KeyName)
+		}
+		indexMap[index.KeyName] = append(indexMap[index.KeyName], index)
+	}
+	return keyNames, indexMap
+}
+
+// getIndexColumnNames 返回索引的列名, 函数索引的表达式列使用expr代替, 用于生成索引名称
+func getIndexColumnNames(indexes []Index) []string {
+	var columns []string
+	for _, index := range indexes {
+		if len(index.Expression) != 0 {
+			columns = append(columns, index_expression_col)
+			continue
+		}
+		columns = append(columns, index.ColumnName)
+	}
+	return columns
+}
+
+// genIndexColumnString 按SeqInIndex的顺序生成建索引语句中的列, 前缀索引转换为SUBSTR函数索引,
+// 函数索引的表达式与生成列、CHECK约束使用相同的转换, ciColumns中大小写不敏感的列转换为UPPER函数索引, 降序列加上DESC,
+// 表达式无法转换时输出警告并返回false, 不导出该索引
+func genIndexColumnString(mysqlSchema, tableName string, indexes []Index, ciColumns map[string]bool) (string, bool) {
+	var columns []string
+	for _, index := range indexes {
+		var column string
+		switch {
+		case len(index.Expression) != 0:
+			translated, issues := translateSQLExpression(index.Expression)
+			if len(issues) != 0 {
+				log.Logger.Warnf("表 %s.%s 函数索引 %s 的表达式 %s 无法转换, 不导出: %s", mysqlSchema, tableName, index.KeyName, index.Expression, strings.Join(issues, "; "))
+				return "", false
+			}
+			column = translated
+			log.Logger.Infof("表 %s.%s 索引 %s 是函数索引, 表达式 %s 转换为 %s", mysqlSchema, tableName, index.KeyName, index.Expression, column)
+		case index.SubPart > 0:
+			column = fmt.Sprintf(sqldef.Y_SQL_INDEX_PREFIX_FORMAT, genColumnString([]string{index.ColumnName}), index.SubPart)
+			log.Logger.Warnf("表 %s.%s 索引 %s 的列 %s 是长度为 %d 的前缀索引, 转换为函数索引 %s", mysqlSchema, tableName, index.KeyName, index.ColumnName, index.SubPart, column)
+		default:
+			column = genColumnString([]string{index.ColumnName})
+		}
+		if len(index.Expression) == 0 && ciColumns[index.ColumnName] {
+			column = fmt.Sprintf(sqldef.Y_SQL_INDEX_UPPER_FORMAT, column)
+		}
+		if index.Collation == index_collation_desc {
+			column += " DESC"
+		}
+		columns = append(columns, column)
+	}
+	return strings.Join(columns, ", "), true
+}
+
+// isPlainColumnIndex 索引只包含按升序排列的整列, 并且没有转换为UPPER函数索引时返回true
+func isPlainColumnIndex(indexes []Index, ciColumns map[string]bool) bool {
+	for _, index := range indexes {
+		if len(index.Expression) != 0 || index.SubPart > 0 || index.Collation == index_collation_desc || ciColumns[index.ColumnName] {
+			return false
+		}
+	}
+	return true
+}
+
+// getCaseInsensitiveColumns ci_collation为upper时查询排序规则大小写不敏感的列, 唯一索引和主键需要按UPPER保证唯一,
+// 为binary时返回nil, 按二进制比较
+func getCaseInsensitiveColumns(mysql *sql.DB, mysqlSchema, tableName string) (map[string]bool, error) {
+	if confdef.GetM2YConfig().Yashan.CICollation != confdef.CI_COLLATION_UPPER {
+		return nil, nil
+	}
+	rows, err := mysql.Query(sqldef.M_SQL_QUERY_CI_COLUMNS, mysqlSchema, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("查询表属性 information_schema.columns 出错: %v", err)
+	}
+	defer rows.Close()
+	ciColumns := make(map[string]bool)
+	for rows.Next() {
+		var columnName string
+		if err := rows.Scan(&columnName); err != nil {
+			return nil, fmt.Errorf("查询表属性 information_schema.columns 出错: %v", err)
+		}
+		ciColumns[columnName] = true
+	}
+	return ciColumns, rows.Err()
+}
+
+// hasCaseInsensitiveColumn 索引中是否有大小写不敏感的列
+func hasCaseInsensitiveColumn(indexes []Index, ciColumns map[string]bool) bool {
+	for _, index := range indexes {
+		if len(index.Expression) == 0 && ciColumns[index.ColumnName] {
+			return true
+		}
+	}
+	return false
+}
+
+func parseIndexSubPart(subPart sql.NullString) int {
+	if !subPart.Valid {
+		return 0
+	}
+	n, err := strconv.Atoi(subPart.String)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// indexNamer 按配置的模板生成YashanDB的索引名称, 并解决同一schema内的重名和超长问题
+type indexNamer struct {
+	template string
+	used     map[string]struct{}
+}
+
+func newIndexNamer() *indexNamer {
+	template := confdef.GetM2YConfig().Yashan.IndexNameTemplate
+	if len(strings.TrimSpace(template)) == 0 {
+		template = confdef.DefaultIndexNameTemplate
+	}
+	return &indexNamer{template: template, used: make(map[string]struct{})}
+}
+
+// name 返回表tableName上索引keyName在YashanDB中的名称, 重名时在末尾加上_2、_3等后缀
+func (n *indexNamer) name(mysqlSchema, tableName, keyName string, columns []string) string {
+	name := strings.NewReplacer(
+		"{name}", keyName,
+		"{table}", tableName,
+		"{columns}", strings.Join(columns, "_"),
+	).Replace(n.template)
+	candidate := truncateIdentifier(name, sqldef.Y_MAX_IDENTIFIER_LENGTH)
+	for i := 2; n.isUsed(candidate); i++ {
+		suffix := fmt.Sprintf("_%d", i)
+		candidate = truncateIdentifier(name, sqldef.Y_MAX_IDENTIFIER_LENGTH-len(suffix)) + suffix
+	}
+	if candidate != name {
+		log.Logger.Warnf("表 %s.%s 索引 %s 的名称 %s 超长或重名, 改为 %s", mysqlSchema, tableName, keyName, name, candidate)
+	}
+	n.used[n.key(candidate)] = struct{}{}
+	return candidate
+}
+
+func (n *indexNamer) isUsed(name string) bool {
+	_, ok := n.used[n.key(name)]
+	return ok
+}
+
+// key 大小写不敏感时名称在YashanDB中都是大写, 只有大小写不同的名称也是重名
+func (n *indexNamer) key(name string) string {
+	if confdef.GetM2YConfig().Yashan.CaseSensitive {
+		return name
+	}
+	return strings.ToUpper(name)
+}
+
+// truncateIdentifier 将标识符截断到maxBytes字节以内, 不截断多字节字符
+func truncateIdentifier(name string, maxBytes int) string {
+	if len(name) <= maxBytes {
+		return name
+	}
+	end := 0
+	for i, r := range name {
+		size := utf8.RuneLen(r)
+		if i+size > maxBytes {
+			break
+		}
+		end = i + size
+	}
+	return name[:end]
+}
@@ -0,0 +1,341 @@
+package modules
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"m2y/defs/sqldef"
+	"m2y/defs/typedef"
+	"m2y/log"
+)
+
+const (
+	partition_range = "RANGE"
+	partition_list  = "LIST"
+	partition_hash  = "HASH"
+
+	partition_maxvalue = "MAXVALUE"
+
+	// MySQL的TO_DAYS('1970-01-01')
+	mysql_to_days_unix_epoch = 719528
+)
+
+var (
+	partitionColumnRegexp   = regexp.MustCompile("^`([^`]+)`$")
+	partitionFunctionRegexp = regexp.MustCompile("^(?i)(\\w+)\\(\\s*`([^`]+)`\\s*\\)$")
+)
+
+// MySQL的分区方式对应的YashanDB的分区方式, KEY和LINEAR HASH都转换为HASH分区
+var partitionMethods = map[string]string{
+	"RANGE":         partition_range,
+	"RANGE COLUMNS": partition_range,
+	"LIST":          partition_list,
+	"LIST COLUMNS":  partition_list,
+	"HASH":          partition_hash,
+	"LINEAR HASH":   partition_hash,
+	"KEY":           partition_hash,
+	"LINEAR KEY":    partition_hash,
+}
+
+type mysqlPartition struct {
+	name          string
+	method        string
+	expression    sql.NullString
+	description   sql.NullString
+	subMethod     sql.NullString
+	subExpression sql.NullString
+	subNames      []string
+}
+
+// partitionKey 分区键, function不为空时MySQL的分区表达式是对column调用的函数, 如YEAR(created)
+type partitionKey struct {
+	columns  []string
+	function string
+}
+
+// getPartitionClause 查询information_schema.partitions, 生成建表语句中的PARTITION BY子句, 不是分区表时返回空字符串,
+// 分区表达式在YashanDB中没有对应的写法时输出警告并导出为非分区表
+func getPartitionClause(mysql *sql.DB, mysqlSchema, tableName string, columnTypes map[string]string) (string, error) {
+	partitions, err := getPartitions(mysql, mysqlSchema, tableName)
+	if err != nil || len(partitions) == 0 {
+		return "", err
+	}
+	first := partitions[0]
+	clause, err := genPartitionClause(mysql, mysqlSchema, tableName, partitions, columnTypes)
+	if err != nil {
+		log.Logger.Warnf("表 %s.%s 的分区 %s(%s) 在YashanDB中没有对应的写法, 导出为非分区表: %v", mysqlSchema, tableName, first.method, first.expression.String, err)
+		return "", nil
+	}
+	return clause, nil
+}
+
+func getPartitions(mysql *sql.DB, mysqlSchema, tableName string) ([]*mysqlPartition, error) {
+	rows, err := mysql.Query(sqldef.M_SQL_QUERY_PARTITIONS, mysqlSchema, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("查询分区 information_schema.partitions 出错: %v", err)
+	}
+	defer rows.Close()
+	var partitions []*mysqlPartition
+	for rows.Next() {
+		var (
+			partition mysqlPartition
+			subName   sql.NullString
+		)
+		if err := rows.Scan(&partition.name, &subName, &partition.method, &partition.subMethod,
+			&partition.expression, &partition.subExpression, &partition.description); err != nil {
+			return nil, fmt.Errorf("查询分区 information_schema.partitions 出错: %v", err)
+		}
+		// 有子分区时每个子分区一行, 按分区合并
+		if n := len(partitions); n != 0 && partitions[n-1].name == partition.name {
+			partitions[n-1].subNames = append(partitions[n-1].subNames, subName.String)
+			continue
+		}
+		if subName.Valid {
+			partition.subNames = []string{subName.String}
+		}
+		partitions = append(partitions, &partition)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询分区 information_schema.partitions 出错: %v", err)
+	}
+	return partitions, nil
+}
+
+func genPartitionClause(mysql *sql.DB, mysqlSchema, tableName string, partitions []*mysqlPartition, columnTypes map[string]string) (string, error) {
+	first := partitions[0]
+	method, ok := partitionMethods[first.method]
+	if !ok {
+		return "", fmt.Errorf("不支持的分区方式 %s", first.method)
+	}
+	key, err := getPartitionKey(mysql, mysqlSchema, tableName, first.method, first.expression.String)
+	if err != nil {
+		return "", err
+	}
+	if len(key.function) != 0 && method == partition_hash {
+		log.Logger.Warnf("表 %s.%s 的分区表达式 %s 转换为按列 %s 哈希分区, 数据在分区中的分布与MySQL不同",
+			mysqlSchema, tableName, first.expression.String, strings.Join(key.columns, ", "))
+		key.function = ""
+	}
+	if len(key.function) != 0 && method == partition_list {
+		return "", fmt.Errorf("列表分区不支持分区表达式 %s", first.expression.String)
+	}
+	if key.function == "UNIX_TIMESTAMP" {
+		log.Logger.Warnf("表 %s.%s 的分区表达式为 %s, 分区边界按UTC时间转换, 请确认", mysqlSchema, tableName, first.expression.String)
+	}
+	clause := fmt.Sprintf(sqldef.Y_SQL_PARTITION_BY, method, genColumnString(key.columns))
+
+	var subKey partitionKey
+	if first.subMethod.Valid && len(first.subMethod.String) != 0 {
+		subKey, err = getPartitionKey(mysql, mysqlSchema, tableName, first.subMethod.String, first.subExpression.String)
+		if err != nil {
+			return "", err
+		}
+		if len(subKey.function) != 0 {
+			log.Logger.Warnf("表 %s.%s 的子分区表达式 %s 转换为按列 %s 哈希分区, 数据在子分区中的分布与MySQL不同",
+				mysqlSchema, tableName, first.subExpression.String, strings.Join(subKey.columns, ", "))
+		}
+		clause += fmt.Sprintf(sqldef.Y_SQL_SUBPARTITION_BY, genColumnString(subKey.columns))
+	}
+
+	var partitionStmts []string
+	for _, partition := range partitions {
+		name := genColumnString([]string{partition.name})
+		var stmt string
+		switch method {
+		case partition_range:
+			values, err := translatePartitionValues(partition.description.String, key, columnTypes)
+			if err != nil {
+				return "", err
+			}
+			stmt = fmt.Sprintf(sqldef.Y_SQL_RANGE_PARTITION, name, values)
+		case partition_list:
+			values, err := translatePartitionValues(partition.description.String, key, columnTypes)
+			if err != nil {
+				return "", err
+			}
+			stmt = fmt.Sprintf(sqldef.Y_SQL_LIST_PARTITION, name, values)
+		default:
+			stmt = fmt.Sprintf(sqldef.Y_SQL_HASH_PARTITION, name)
+		}
+		if len(partition.subNames) != 0 {
+			var subStmts []string
+			for _, subName := range partition.subNames {
+				subStmts = append(subStmts, fmt.Sprintf(sqldef.Y_SQL_SUBPARTITION, genColumnString([]string{subName})))
+			}
+			stmt += fmt.Sprintf(sqldef.Y_SQL_SUBPARTITIONS, strings.Join(subStmts, ", "))
+		}
+		partitionStmts = append(partitionStmts, stmt)
+	}
+	clause += fmt.Sprintf(sqldef.Y_SQL_PARTITIONS, strings.Join(partitionStmts, ",\n\t"))
+	return clause, nil
+}
+
+// getPartitionKey 解析MySQL的分区表达式, COLUMNS和KEY分区是逗号分隔的列, 其他分区是整数表达式,
+// 只支持单独的列或对单独的列调用函数, KEY()没有指定列时使用主键
+func getPartitionKey(mysql *sql.DB, mysqlSchema, tableName, method, expression string) (partitionKey, error) {
+	var key partitionKey
+	expression = strings.TrimSpace(expression)
+	if strings.HasSuffix(method, "KEY") && len(expression) == 0 {
+		columns, err := getPrimaryKeyColumns(mysql, mysqlSchema, tableName)
+		if err != nil {
+			return key, err
+		}
+		if len(columns) == 0 {
+			return key, fmt.Errorf("KEY分区没有指定列, 表也没有主键")
+		}
+		key.columns = columns
+		return key, nil
+	}
+	if strings.HasSuffix(method, "COLUMNS") || strings.HasSuffix(method, "KEY") {
+		for _, column := range strings.Split(expression, ",") {
+			match := partitionColumnRegexp.FindStringSubmatch(strings.TrimSpace(column))
+			if match == nil {
+				return key, fmt.Errorf("无法解析的分区列 %s", expression)
+			}
+			key.columns = append(key.columns, match[1])
+		}
+		return key, nil
+	}
+	if match := partitionColumnRegexp.FindStringSubmatch(expression); match != nil {
+		key.columns = []string{match[1]}
+		return key, nil
+	}
+	if match := partitionFunctionRegexp.FindStringSubmatch(expression); match != nil {
+		key.function = strings.ToUpper(match[1])
+		key.columns = []string{match[2]}
+		return key, nil
+	}
+	// 哈希分区只影响数据的分布, 表达式只引用了列时可以直接按列分区
+	if !strings.HasSuffix(method, "HASH") {
+		return key, fmt.Errorf("不支持的分区表达式 %s", expression)
+	}
+	for _, match := range backQuotedIdentRegexp.FindAllStringSubmatch(expression, -1) {
+		if !inArrayStr(match[1], key.columns) {
+			key.columns = append(key.columns, match[1])
+		}
+	}
+	if len(key.columns) == 0 {
+		return key, fmt.Errorf("不支持的分区表达式 %s", expression)
+	}
+	key.function = expression
+	return key, nil
+}
+
+func getPrimaryKeyColumns(mysql *sql.DB, mysqlSchema, tableName string) ([]string, error) {
+	rows, err := mysql.Query(sqldef.M_SQL_QUERY_PRIMARY_KEY, mysqlSchema, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("查询主键 information_schema.columns 出错: %v", err)
+	}
+	defer rows.Close()
+	var columns []string
+	for rows.Next() {
+		var column, dataType string
+		if err := rows.Scan(&column, &dataType); err != nil {
+			return nil, fmt.Errorf("查询主键 information_schema.columns 出错: %v", err)
+		}
+		columns = append(columns, column)
+	}
+	return columns, rows.Err()
+}
+
+// translatePartitionValues 转换分区的边界值或列表值, 日期时间类型的分区键使用DATE或TIMESTAMP字面量,
+// 分区表达式为YEAR、TO_DAYS、UNIX_TIMESTAMP时将整数边界换算为对应列的日期时间
+func translatePartitionValues(description string, key partitionKey, columnTypes map[string]string) (string, error) {
+	var values []string
+	for _, value := range splitPartitionValues(description) {
+		// 多列的列表分区值为(a, b)
+		if strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")") {
+			tuple, err := translatePartitionValues(value[1:len(value)-1], key, columnTypes)
+			if err != nil {
+				return "", err
+			}
+			values = append(values, "("+tuple+")")
+			continue
+		}
+		column := key.columns[len(values)%len(key.columns)]
+		translated, err := translatePartitionValue(value, key.function, columnTypes[column])
+		if err != nil {
+			return "", err
+		}
+		values = append(values, translated)
+	}
+	return strings.Join(values, ", "), nil
+}
+
+func translatePartitionValue(value, function, columnType string) (string, error) {
+	if strings.EqualFold(value, partition_maxvalue) || strings.EqualFold(value, "NULL") {
+		return strings.ToUpper(value), nil
+	}
+	literal := func(t time.Time) string {
+		if columnType == typedef.M_DATE {
+			return fmt.Sprintf(sqldef.Y_SQL_DATE_LITERAL, t.Format("2006-01-02"))
+		}
+		return fmt.Sprintf(sqldef.Y_SQL_TIMESTAMP_LITERAL, t.Format("2006-01-02 15:04:05"))
+	}
+	isDateTime := columnType == typedef.M_DATE || columnType == typedef.M_DATETIME || columnType == typedef.M_TIMESTAMP
+	switch function {
+	case "":
+		if !isDateTime || !strings.HasPrefix(value, "'") {
+			return value, nil
+		}
+		s := strings.Trim(value, "'")
+		for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02"} {
+			if t, err := time.Parse(layout, s); err == nil {
+				return literal(t), nil
+			}
+		}
+		return "", fmt.Errorf("无法解析的分区边界值 %s", value)
+	case "YEAR", "TO_DAYS", "UNIX_TIMESTAMP":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || !isDateTime {
+			return "", fmt.Errorf("无法转换的分区边界值 %s(%s)", function, value)
+		}
+		switch function {
+		case "YEAR":
+			return literal(time.Date(int(n), 1, 1, 0, 0, 0, 0, time.UTC)), nil
+		case "TO_DAYS":
+			return literal(time.Unix(0, 0).UTC().AddDate(0, 0, int(n-mysql_to_days_unix_epoch))), nil
+		default:
+			// UNIX_TIMESTAMP只能用于TIMESTAMP列, 按UTC换算
+			return literal(time.Unix(n, 0).UTC()), nil
+		}
+	}
+	return "", fmt.Errorf("不支持的分区函数 %s", function)
+}
+
+// splitPartitionValues 按最外层的逗号拆分分区的描述, 忽略字符串和括号中的逗号
+func splitPartitionValues(description string) []string {
+	var (
+		values  []string
+		depth   int
+		inQuote bool
+		start   int
+	)
+	for i := 0; i < len(description); i++ {
+		switch c := description[i]; {
+		case c == '\'':
+			// 字符串中的''是转义的引号
+			if inQuote && i+1 < len(description) && description[i+1] == '\'' {
+				i++
+				continue
+			}
+			inQuote = !inQuote
+		case inQuote:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			values = append(values, strings.TrimSpace(description[start:i]))
+			start = i + 1
+		}
+	}
+	if last := strings.TrimSpace(description[start:]); len(last) != 0 {
+		values = append(values, last)
+	}
+	return values
+}
@@ -0,0 +1,240 @@
+package modules
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"m2y/defs/sqldef"
+	"m2y/log"
+)
+
+const (
+	routine_type_function = "FUNCTION"
+
+	routines_file_format        = "%s_routines.sql"
+	routines_review_file_format = "%s_routines_review.sql"
+)
+
+type mysqlRoutine struct {
+	name        string
+	routineType string // PROCEDURE或FUNCTION
+	definition  string
+}
+
+// exportRoutines 导出schema中的存储过程和函数, 转换成功的写入{schema}_routines.sql,
+// 无法完全转换的连同原因和原始定义写入{schema}_routines_review.sql, 需要人工修改后执行
+func exportRoutines(mysql *sql.DB, applier *ddlApplier, mysqlSchema, yasdbSchema string) error {
+	routines, err := getRoutines(mysql, mysqlSchema)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(path.Join(getOthersDDLPath(), fmt.Sprintf(routines_file_format, mysqlSchema)))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	reviewFileName := path.Join(getOthersDDLPath(), fmt.Sprintf(routines_review_file_format, mysqlSchema))
+	var reviews []string
+	for _, routine := range routines {
+		objectType := "存储过程"
+		if routine.routineType == routine_type_function {
+			objectType = "函数"
+		}
+		ddl, issues := translateRoutine(routine, yasdbSchema)
+		if len(issues) != 0 {
+			log.Logger.Warnf("%s %s.%s 无法自动转换, 请在 %s 中人工修改: %s", objectType, mysqlSchema, routine.name, reviewFileName, strings.Join(issues, "; "))
+			reviews = append(reviews, genPLReview(objectType, mysqlSchema+"."+routine.name, issues, routine.definition, ddl))
+			continue
+		}
+		if _, err := file.WriteString(ddl + sqldef.Y_SQL_PL_TERMINATOR); err != nil {
+			return err
+		}
+		applier.addPL(ddl_phase_programs, yasdbSchema+"."+routine.name, ddl)
+	}
+	return writePLReviews(reviewFileName, reviews)
+}
+
+// getRoutines 查询schema中的存储过程和函数的定义, 函数在前, 存储过程可能调用函数
+func getRoutines(mysql *sql.DB, mysqlSchema string) ([]mysqlRoutine, error) {
+	rows, err := mysql.Query(sqldef.M_SQL_QUERY_ROUTINES, mysqlSchema)
+	if err != nil {
+		return nil, fmt.Errorf("查询存储过程 information_schema.routines 出错: %v", err)
+	}
+	var routines []mysqlRoutine
+	for rows.Next() {
+		var routine mysqlRoutine
+		if err := rows.Scan(&routine.name, &routine.routineType); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("查询存储过程 information_schema.routines 出错: %v", err)
+		}
+		routines = append(routines, routine)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询存储过程 information_schema.routines 出错: %v", err)
+	}
+	for i := range routines {
+		routines[i].definition, err = getRoutineDefinition(mysql, mysqlSchema, routines[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return routines, nil
+}
+
+// getRoutineDefinition 通过SHOW CREATE PROCEDURE|FUNCTION获取完整定义, 第3列为建立语句
+func getRoutineDefinition(mysql *sql.DB, mysqlSchema string, routine mysqlRoutine) (string, error) {
+	query := fmt.Sprintf(sqldef.M_SQL_SHOW_CREATE_ROUTINE, routine.routineType, mysqlSchema, routine.name)
+	rows, err := mysql.Query(query)
+	if err != nil {
+		return "", fmt.Errorf("执行 %s 出错: %v", query, err)
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", fmt.Errorf("执行 %s 出错: %v", query, err)
+	}
+	if !rows.Next() || len(columns) < 3 {
+		return "", fmt.Errorf("执行 %s 没有返回定义", query)
+	}
+	values := make([]sql.NullString, len(columns))
+	valuePointers := make([]interface{}, len(columns))
+	for i := range values {
+		valuePointers[i] = &values[i]
+	}
+	if err := rows.Scan(valuePointers...); err != nil {
+		return "", fmt.Errorf("执行 %s 出错: %v", query, err)
+	}
+	return values[2].String, nil
+}
+
+// translateRoutine 将MySQL的存储过程或函数转换为YashanDB的PL, 返回转换结果和无法转换的原因
+func translateRoutine(routine mysqlRoutine, yasdbSchema string) (string, []string) {
+	if len(strings.TrimSpace(routine.definition)) == 0 {
+		return "", []string{"无法读取定义, 请确认用户有SHOW_ROUTINE权限或者是对象的DEFINER"}
+	}
+	tokens, err := tokenizePL(routine.definition)
+	if err != nil {
+		return "", []string{err.Error()}
+	}
+	p := newPLTranslator(tokens, false)
+	// 跳过CREATE DEFINER=...
+	for !p.eof() && !p.peek().is("PROCEDURE", "FUNCTION") {
+		p.next()
+	}
+	isFunction := p.next().is("FUNCTION")
+	if p.accept("IF") {
+		p.expect("NOT")
+		p.expect("EXISTS")
+	}
+	p.next()
+	if p.acceptSymbol(".") {
+		p.next()
+	}
+	var params []string
+	args, end, ok := splitPLArgs(p.tokens, p.pos)
+	if !ok {
+		return "", []string{"无法解析参数列表"}
+	}
+	p.pos = end + 1
+	for _, arg := range args {
+		params = append(params, p.translateParam(arg))
+	}
+	var paramStr string
+	if len(params) != 0 {
+		paramStr = "(" + strings.Join(params, ", ") + ")"
+	}
+	var returnType string
+	if isFunction {
+		p.expect("RETURNS")
+		returnType = stripPLTypeLength(p.translateType(p.typeTokens()))
+	}
+	p.skipCharacteristics()
+	block := p.body()
+
+	var sb strings.Builder
+	if isFunction {
+		formatter := getSQLFormatter(sqldef.Y_SQL_CREATE_FUNCTION, sqldef.Y_SQL_CREATE_FUNCTION_CASE_SENSITIVE)
+		sb.WriteString(fmt.Sprintf(formatter, formatKeyWord(yasdbSchema), formatKeyWord(routine.name), paramStr, returnType))
+	} else {
+		formatter := getSQLFormatter(sqldef.Y_SQL_CREATE_PROCEDURE, sqldef.Y_SQL_CREATE_PROCEDURE_CASE_SENSITIVE)
+		sb.WriteString(fmt.Sprintf(formatter, formatKeyWord(yasdbSchema), formatKeyWord(routine.name), paramStr))
+	}
+	if len(block.decls) != 0 {
+		sb.WriteString(strings.Join(block.decls, "\n") + "\n")
+	}
+	sb.WriteString(strings.Join(block.render(""), "\n") + "\n")
+	sb.WriteString("END;")
+	return sb.String(), p.issues
+}
+
+// translateParam 转换参数, IN、OUT、INOUT写在参数名之后, 参数类型不能带长度
+func (p *plTranslator) translateParam(tokens []plToken) string {
+	mode := "IN"
+	if len(tokens) > 0 && tokens[0].is("IN", "OUT", "INOUT") {
+		mode = strings.ToUpper(tokens[0].text)
+		tokens = tokens[1:]
+	}
+	if mode == "INOUT" {
+		mode = "IN OUT"
+	}
+	if len(tokens) < 2 {
+		p.issue("无法解析的参数: %s", renderPLTokens(tokens))
+		return renderPLTokens(tokens)
+	}
+	return p.identifier(tokens[0]) + " " + mode + " " + stripPLTypeLength(p.translateType(tokens[1:]))
+}
+
+// typeTokens 读取RETURNS后的数据类型, 包括长度、UNSIGNED、字符集和排序规则
+func (p *plTranslator) typeTokens() []plToken {
+	start := p.pos
+	p.next()
+	if p.peek().isSymbol("(") {
+		if _, end, ok := splitPLArgs(p.tokens, p.pos); ok {
+			p.pos = end + 1
+		}
+	}
+	for {
+		switch {
+		case p.accept("UNSIGNED"), p.accept("ZEROFILL"), p.accept("BINARY"):
+		case p.accept("CHARSET"), p.accept("COLLATE"):
+			p.next()
+		case p.peek().is("CHARACTER") && p.peekAt(1).is("SET"):
+			p.pos += 3
+		default:
+			return p.tokens[start:p.pos]
+		}
+	}
+}
+
+// skipCharacteristics 跳过存储过程的特性, 如COMMENT、DETERMINISTIC、SQL SECURITY等
+func (p *plTranslator) skipCharacteristics() {
+	for {
+		switch {
+		case p.accept("COMMENT"), p.accept("LANGUAGE"):
+			p.next()
+		case p.accept("NOT"), p.accept("DETERMINISTIC"):
+		case p.accept("CONTAINS"), p.accept("NO"):
+			p.next()
+		case p.accept("READS"), p.accept("MODIFIES"):
+			p.next()
+			p.next()
+		case p.accept("SQL"):
+			p.expect("SECURITY")
+			p.next()
+		default:
+			return
+		}
+	}
+}
+
+// stripPLTypeLength 去掉数据类型中的长度和精度
+func stripPLTypeLength(t string) string {
+	if i := strings.Index(t, "("); i > 0 {
+		return t[:i]
+	}
+	return t
+}
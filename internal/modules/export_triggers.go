@@ -0,0 +1,129 @@
+package modules
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"m2y/defs/sqldef"
+	"m2y/log"
+)
+
+const (
+	triggers_file_format        = "%s_triggers.sql"
+	triggers_review_file_format = "%s_triggers_review.sql"
+)
+
+type mysqlTrigger struct {
+	name      string
+	event     string
+	table     string
+	timing    string
+	statement string
+}
+
+// exportTriggers 导出tables上的触发器, 转换成功的写入{schema}_triggers.sql,
+// 无法完全转换的连同原因和原始定义写入{schema}_triggers_review.sql, 需要人工修改后执行
+func exportTriggers(mysql *sql.DB, applier *ddlApplier, mysqlSchema, yasdbSchema string, tables []string) error {
+	triggers, err := getTriggers(mysql, mysqlSchema, tables)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(path.Join(getOthersDDLPath(), fmt.Sprintf(triggers_file_format, mysqlSchema)))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	reviewFileName := path.Join(getOthersDDLPath(), fmt.Sprintf(triggers_review_file_format, mysqlSchema))
+	var reviews []string
+	for _, trigger := range triggers {
+		ddl, issues := translateTrigger(trigger, yasdbSchema)
+		if len(issues) != 0 {
+			log.Logger.Warnf("触发器 %s.%s 无法自动转换, 请在 %s 中人工修改: %s", mysqlSchema, trigger.name, reviewFileName, strings.Join(issues, "; "))
+			reviews = append(reviews, genPLReview("触发器", mysqlSchema+"."+trigger.name, issues, trigger.statement, ddl))
+			continue
+		}
+		if _, err := file.WriteString(ddl + sqldef.Y_SQL_PL_TERMINATOR); err != nil {
+			return err
+		}
+		applier.addPL(ddl_phase_programs, yasdbSchema+"."+trigger.table, ddl)
+	}
+	return writePLReviews(reviewFileName, reviews)
+}
+
+func getTriggers(mysql *sql.DB, mysqlSchema string, tables []string) ([]mysqlTrigger, error) {
+	rows, err := mysql.Query(sqldef.M_SQL_QUERY_TRIGGERS, mysqlSchema)
+	if err != nil {
+		return nil, fmt.Errorf("查询触发器 information_schema.triggers 出错: %v", err)
+	}
+	defer rows.Close()
+	var triggers []mysqlTrigger
+	for rows.Next() {
+		var trigger mysqlTrigger
+		if err := rows.Scan(&trigger.name, &trigger.event, &trigger.table, &trigger.timing, &trigger.statement); err != nil {
+			return nil, fmt.Errorf("查询触发器 information_schema.triggers 出错: %v", err)
+		}
+		if !inArrayStr(trigger.table, tables) {
+			continue
+		}
+		triggers = append(triggers, trigger)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询触发器 information_schema.triggers 出错: %v", err)
+	}
+	return triggers, nil
+}
+
+// translateTrigger 将MySQL的行级触发器转换为YashanDB的触发器, 返回转换结果和无法转换的原因
+func translateTrigger(trigger mysqlTrigger, yasdbSchema string) (string, []string) {
+	tokens, err := tokenizePL(trigger.statement)
+	if err != nil {
+		return "", []string{err.Error()}
+	}
+	p := newPLTranslator(tokens, true)
+	block := p.body()
+	formatter := getSQLFormatter(sqldef.Y_SQL_CREATE_TRIGGER, sqldef.Y_SQL_CREATE_TRIGGER_CASE_SENSITIVE)
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(formatter, formatKeyWord(yasdbSchema), formatKeyWord(trigger.name),
+		strings.ToUpper(trigger.timing), strings.ToUpper(trigger.event), formatKeyWord(yasdbSchema), formatKeyWord(trigger.table)))
+	if len(block.decls) != 0 {
+		sb.WriteString("DECLARE\n")
+		sb.WriteString(strings.Join(block.decls, "\n") + "\n")
+	}
+	sb.WriteString(strings.Join(block.render(""), "\n") + "\n")
+	sb.WriteString("END;")
+	return sb.String(), p.issues
+}
+
+// genPLReview 生成需要人工修改的PL对象, 包括无法转换的原因、MySQL原始定义和自动转换的结果
+func genPLReview(objectType, name string, issues []string, original, translated string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("-- %s %s 无法自动转换, 需要人工修改:\n", objectType, name))
+	for _, issue := range issues {
+		sb.WriteString("--   " + strings.ReplaceAll(issue, "\n", " ") + "\n")
+	}
+	sb.WriteString("-- MySQL原始定义:\n/*\n" + strings.ReplaceAll(original, "*/", "* /") + "\n*/\n")
+	if len(translated) != 0 {
+		sb.WriteString("-- 自动转换的结果:\n" + translated + sqldef.Y_SQL_PL_TERMINATOR)
+	}
+	return sb.String()
+}
+
+// writePLReviews 有需要人工修改的对象时才生成文件, 没有时删除上一次导出的文件
+func writePLReviews(fileName string, reviews []string) error {
+	if len(reviews) == 0 {
+		if err := os.Remove(fileName); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.WriteString(strings.Join(reviews, "\n"))
+	return err
+}
@@ -0,0 +1,159 @@
+package modules
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"m2y/defs/sqldef"
+	"m2y/log"
+)
+
+type mysqlView struct {
+	name       string
+	definition string
+	ddl        string
+	depends    []string // 引用的同一schema中的其他视图
+}
+
+// getViewDDLs 导出schema中的视图, 视图定义中的标识符、函数和LIMIT转换为YashanDB的写法,
+// 并按依赖关系排序, 被引用的视图先创建
+func getViewDDLs(db *sql.DB, mysqlSchema, yasdbSchema string) ([]string, error) {
+	rows, err := db.Query(fmt.Sprintf(sqldef.M_SQL_QUERY_VIEW, mysqlSchema))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var views []*mysqlView
+	for rows.Next() {
+		view := &mysqlView{}
+		if err := rows.Scan(&view.name, &view.definition); err != nil {
+			return nil, err
+		}
+		if len(strings.TrimSpace(view.definition)) == 0 {
+			log.Logger.Warnf("视图 %s.%s 的定义为空, 请确认用户有SHOW VIEW权限", mysqlSchema, view.name)
+			continue
+		}
+		views = append(views, view)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(views))
+	for _, view := range views {
+		names = append(names, view.name)
+	}
+	for _, view := range views {
+		definition, depends, issues := translateViewDefinition(view.definition, mysqlSchema, names)
+		if len(issues) != 0 {
+			log.Logger.Warnf("视图 %s.%s 无法完全转换, 请检查: %s", mysqlSchema, view.name, strings.Join(issues, "; "))
+		}
+		formatter := getSQLFormatter(sqldef.Y_SQL_CREATE_VIEW, sqldef.Y_SQL_CREATE_VIEW_CASE_SENSITIVE)
+		view.ddl = fmt.Sprintf(formatter, formatKeyWord(yasdbSchema), formatKeyWord(view.name), definition)
+		view.depends = depends
+	}
+	var viewDDLs []string
+	for _, view := range sortViewsByDependency(mysqlSchema, views) {
+		viewDDLs = append(viewDDLs, view.ddl)
+	}
+	return viewDDLs, nil
+}
+
+// translateViewDefinition 转换information_schema.views中的视图定义, 返回转换结果、引用的视图和无法转换的原因
+func translateViewDefinition(definition, mysqlSchema string, viewNames []string) (string, []string, []string) {
+	tokens, err := tokenizePL(charsetIntroducerRegexp.ReplaceAllString(definition, "$1'"))
+	if err != nil {
+		return definition, nil, []string{err.Error()}
+	}
+	var depends []string
+	for i, t := range tokens {
+		if t.kind != pl_token_ident || !inArrayStr(t.text, viewNames) || inArrayStr(t.text, depends) {
+			continue
+		}
+		// 视图定义中的对象都带有schema, 只有本schema中的视图才是依赖
+		if i >= 2 && tokens[i-1].isSymbol(".") && tokens[i-2].text == mysqlSchema {
+			depends = append(depends, t.text)
+		}
+	}
+	p := newPLTranslator(nil, false)
+	tokens = translateLimit(p, tokens)
+	return renderPLTokens(p.translateTokens(tokens)), depends, p.issues
+}
+
+// translateLimit 将LIMIT n、LIMIT m, n、LIMIT n OFFSET m转换为OFFSET m ROWS FETCH NEXT n ROWS ONLY
+func translateLimit(p *plTranslator, tokens []plToken) []plToken {
+	word := func(text string) plToken { return plToken{kind: pl_token_word, text: text} }
+	var out []plToken
+	for i := 0; i < len(tokens); i++ {
+		if !tokens[i].is("LIMIT") || i+1 == len(tokens) {
+			out = append(out, tokens[i])
+			continue
+		}
+		count, offset := tokens[i+1], plToken{}
+		i++
+		switch {
+		case i+2 < len(tokens) && tokens[i+1].isSymbol(","):
+			offset, count = count, tokens[i+2]
+			i += 2
+		case i+2 < len(tokens) && tokens[i+1].is("OFFSET"):
+			offset = tokens[i+2]
+			i += 2
+		}
+		if count.kind != pl_token_word || (len(offset.text) != 0 && offset.kind != pl_token_word) {
+			p.issue("无法转换的LIMIT")
+		}
+		if len(offset.text) != 0 {
+			out = append(out, word("OFFSET"), offset, word("ROWS"), word("FETCH"), word("NEXT"))
+		} else {
+			out = append(out, word("FETCH"), word("FIRST"))
+		}
+		out = append(out, count, word("ROWS"), word("ONLY"))
+	}
+	return out
+}
+
+// sortViewsByDependency 按依赖关系对视图拓扑排序, 没有依赖关系的视图按名称排序, 存在循环依赖时按名称输出剩余的视图
+func sortViewsByDependency(mysqlSchema string, views []*mysqlView) []*mysqlView {
+	viewMap := make(map[string]*mysqlView)
+	dependents := make(map[string][]string)
+	inDegree := make(map[string]int)
+	for _, view := range views {
+		viewMap[view.name] = view
+		inDegree[view.name] = len(view.depends)
+		for _, depend := range view.depends {
+			dependents[depend] = append(dependents[depend], view.name)
+		}
+	}
+	var ready []string
+	for _, view := range views {
+		if inDegree[view.name] == 0 {
+			ready = append(ready, view.name)
+		}
+	}
+	var sorted []*mysqlView
+	for len(ready) != 0 {
+		sort.Strings(ready)
+		name := ready[0]
+		ready = ready[1:]
+		sorted = append(sorted, viewMap[name])
+		for _, dependent := range dependents[name] {
+			inDegree[dependent]--
+			if inDegree[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+	if len(sorted) == len(views) {
+		return sorted
+	}
+	var cyclic []string
+	for _, view := range views {
+		if inDegree[view.name] > 0 {
+			cyclic = append(cyclic, view.name)
+			sorted = append(sorted, view)
+		}
+	}
+	log.Logger.Warnf("schema %s 的视图 %s 存在循环依赖, 按名称顺序导出", mysqlSchema, strings.Join(cyclic, ", "))
+	return sorted
+}
@@ -0,0 +1,231 @@
+package modules
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const fake_driver_name = "m2y-fake"
+
+var (
+	fakeDBsMu sync.Mutex
+	fakeDBs   = make(map[string]*fakeDB)
+)
+
+func init() {
+	sql.Register(fake_driver_name, fakeDriver{})
+}
+
+// fakeDB 测试用的数据库, 按SQL返回预设的查询结果, 并记录执行的语句和事务的提交、回滚
+type fakeDB struct {
+	mu        sync.Mutex
+	results   []*fakeResult
+	execs     []fakeExec
+	unmatched []string
+}
+
+// fakeResult 一条预设的查询结果, SQL中的空白不影响匹配
+type fakeResult struct {
+	query   string
+	args    []interface{} // 为nil时不比较参数
+	columns []string
+	rows    [][]driver.Value
+}
+
+// fakeExec 执行的语句, 事务的开始、提交和回滚分别记录为BEGIN、COMMIT和ROLLBACK
+type fakeExec struct {
+	Query string
+	Args  []driver.Value
+}
+
+// newFakeDB 返回连接到新的fakeDB的*sql.DB, 测试结束时关闭
+func newFakeDB(t *testing.T) (*sql.DB, *fakeDB) {
+	t.Helper()
+	f := &fakeDB{}
+	fakeDBsMu.Lock()
+	name := fmt.Sprintf("%s-%d", t.Name(), len(fakeDBs))
+	fakeDBs[name] = f
+	fakeDBsMu.Unlock()
+	db, err := sql.Open(fake_driver_name, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		fakeDBsMu.Lock()
+		delete(fakeDBs, name)
+		fakeDBsMu.Unlock()
+	})
+	return db, f
+}
+
+// addRows 预设查询query的结果, args为nil时匹配任意参数
+func (f *fakeDB) addRows(query string, args []interface{}, columns []string, rows ...[]driver.Value) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.results = append(f.results, &fakeResult{query: normalizeFakeSQL(query), args: args, columns: columns, rows: rows})
+}
+
+// getExecs 返回记录的语句
+func (f *fakeDB) getExecs() []fakeExec {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]fakeExec(nil), f.execs...)
+}
+
+// getUnmatched 返回没有预设结果的查询, 被调用方忽略的查询错误可以通过它发现
+func (f *fakeDB) getUnmatched() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.unmatched...)
+}
+
+func (f *fakeDB) record(query string, args []driver.Value) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.execs = append(f.execs, fakeExec{Query: query, Args: args})
+}
+
+func (f *fakeDB) query(query string, args []driver.Value) (driver.Rows, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	query = normalizeFakeSQL(query)
+	for _, result := range f.results {
+		if result.query != query || !matchFakeArgs(result.args, args) {
+			continue
+		}
+		return &fakeRows{columns: result.columns, rows: result.rows}, nil
+	}
+	f.unmatched = append(f.unmatched, fmt.Sprintf("%s %v", query, args))
+	return nil, fmt.Errorf("fakeDB: 没有预设的查询结果, sql: %s args: %v", query, args)
+}
+
+func normalizeFakeSQL(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
+
+func matchFakeArgs(expected []interface{}, args []driver.Value) bool {
+	if expected == nil {
+		return true
+	}
+	if len(expected) != len(args) {
+		return false
+	}
+	for i := range expected {
+		if fmt.Sprint(expected[i]) != fmt.Sprint(args[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fakeDBsMu.Lock()
+	defer fakeDBsMu.Unlock()
+	f, ok := fakeDBs[name]
+	if !ok {
+		return nil, fmt.Errorf("fakeDB: %s 不存在", name)
+	}
+	return &fakeConn{db: f}, nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{db: c.db, query: query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.db.record("BEGIN", nil)
+	return &fakeTx{db: c.db}, nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.db.query(query, namedValues(args))
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.db.record(query, namedValues(args))
+	return driver.RowsAffected(1), nil
+}
+
+func namedValues(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, 0, len(args))
+	for _, arg := range args {
+		values = append(values, arg.Value)
+	}
+	return values
+}
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.record(s.query, args)
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.db.query(s.query, args)
+}
+
+type fakeTx struct {
+	db *fakeDB
+}
+
+func (tx *fakeTx) Commit() error {
+	tx.db.record("COMMIT", nil)
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.db.record("ROLLBACK", nil)
+	return nil
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+	next    int
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.next])
+	r.next++
+	return nil
+}
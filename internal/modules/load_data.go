@@ -0,0 +1,240 @@
+package modules
+
+import (
+	"compress/gzip"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"m2y/log"
+	"m2y/utils/fileutil"
+)
+
+func LoadTableData(yasdb *sql.DB, mysqlSchema, yasdbSchema string, alltables []string, parallel, tableParallel, batchSize int) error {
+	sts := []schemaTable{}
+	for _, table := range alltables {
+		sts = append(sts, schemaTable{mysqlSchema: mysqlSchema, yasdbSchema: yasdbSchema, table: table})
+	}
+	return loadTablesData(yasdb, sts, parallel, tableParallel, batchSize)
+}
+
+func LoadSchemasData(yasdb *sql.DB, mysqlSchemas, yasdbSchemas []string, excludeTables []string, parallel, tableParallel, batchSize int) error {
+	sts := []schemaTable{}
+	for i, schema := range mysqlSchemas {
+		entries, err := os.ReadDir(path.Join(getExportDataPath(), schema))
+		if err != nil {
+			log.Logger.Infof("schema %s 没有导出的数据, 请先执行export-data: %v", schema, err)
+			continue
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() || inArrayStr(entry.Name(), excludeTables) {
+				continue
+			}
+			sts = append(sts, schemaTable{mysqlSchema: schema, yasdbSchema: yasdbSchemas[i], table: entry.Name()})
+		}
+	}
+	return loadTablesData(yasdb, sts, parallel, tableParallel, batchSize)
+}
+
+func loadTablesData(yasdb *sql.DB, sts []schemaTable, parallel, tableParallel, batchSize int) error {
+	start := time.Now()
+	semaphore := make(chan bool, parallel)
+	var wg sync.WaitGroup
+	var totalRows int64
+	log.Logger.Infof("开始加载 %s 中的数据到yashandb......", getExportDataPath())
+	for _, st := range sts {
+		wg.Add(1)
+		semaphore <- true
+		go func(st schemaTable) {
+			defer wg.Done()
+			rows := loadTableData(yasdb, st.mysqlSchema, st.yasdbSchema, st.table, tableParallel, batchSize)
+			atomic.AddInt64(&totalRows, rows)
+			<-semaphore
+		}(st)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	log.Logger.Infof("数据加载任务完成, 共加载数据量: %d 共耗时: %v 速度: %.0f 行/秒", totalRows, elapsed, rowsPerSecond(totalRows, elapsed))
+	return nil
+}
+
+// loadTableData 按清单并行加载一张表的导出文件, 返回加载的行数
+func loadTableData(yasdb *sql.DB, mysqlSchema, yasdbSchema, table string, tableParallel, batchSize int) int64 {
+	start := time.Now()
+	dir := getTableDataPath(mysqlSchema, table)
+	manifest, err := loadDataManifest(dir)
+	if err != nil {
+		log.Logger.Errorf("表 %s.%s 加载失败, %v", mysqlSchema, table, err)
+		return 0
+	}
+	log.Logger.Infof("开始加载表 %s.%s, 文件数: %d", mysqlSchema, table, len(manifest.Files))
+	yasdbColumns, err := getYasdbColumns(yasdb, yasdbSchema, table)
+	if err != nil {
+		log.Logger.Errorf("表 %s.%s 加载失败, 获取yashandb端表结构失败: %v", mysqlSchema, table, err)
+		return 0
+	}
+	if len(yasdbColumns) != len(manifest.Columns) {
+		log.Logger.Errorf("表 %s.%s 加载失败, 导出文件列数 %d 与目标表列数 %d 不一致", mysqlSchema, table, len(manifest.Columns), len(yasdbColumns))
+		return 0
+	}
+	if len(manifest.Files) < tableParallel {
+		tableParallel = len(manifest.Files)
+	}
+	if tableParallel < 1 {
+		tableParallel = 1
+	}
+	var totalCount, failedCount int64
+	semaphore := make(chan bool, tableParallel)
+	var wg sync.WaitGroup
+	for _, file := range manifest.Files {
+		wg.Add(1)
+		semaphore <- true
+		go func(file dataFile) {
+			defer wg.Done()
+			resultCount, ok := loadDataFile(yasdb, mysqlSchema, yasdbSchema, table, path.Join(dir, file.Name), manifest.Columns, yasdbColumns, batchSize)
+			atomic.AddInt64(&totalCount, int64(resultCount))
+			if !ok || int64(resultCount) != file.Rows {
+				atomic.AddInt64(&failedCount, 1)
+			}
+			<-semaphore
+		}(file)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	if failedCount > 0 {
+		log.Logger.Errorf("表 %s.%s 加载未完成, 加载数据量: %d 导出数据量: %d 耗时 %v\n", mysqlSchema, table, totalCount, manifest.Rows, elapsed)
+		return totalCount
+	}
+	log.Logger.Infof("表 %s.%s 加载完成, 加载数据量: %d 耗时 %v 速度: %.0f 行/秒\n", mysqlSchema, table, totalCount, elapsed, rowsPerSecond(totalCount, elapsed))
+	return totalCount
+}
+
+// loadDataFile 加载一个导出文件, 与sync相同按batchSize提交事务, 返回加载的行数以及文件是否完整读取
+func loadDataFile(yasdb *sql.DB, mysqlSchema, yasdbSchema, table, fileName string, columns []dataColumn, yasdbColumns []ColumnInfo, batchSize int) (int, bool) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		log.Logger.Errorf("表 %s.%s 加载失败, 打开文件失败: %v", mysqlSchema, table, err)
+		return 0, false
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		log.Logger.Errorf("表 %s.%s 加载失败, 读取文件 %s 失败: %v", mysqlSchema, table, fileName, err)
+		return 0, false
+	}
+	defer gz.Close()
+	reader := csv.NewReader(gz)
+	reader.FieldsPerRecord = len(columns)
+	reader.ReuseRecord = true
+
+	var resultCount, batchCount, readCount int
+	targetTx, err := yasdb.Begin()
+	if err != nil {
+		log.Logger.Errorf("表 %s.%s 加载失败, 事务开始失败: %v", mysqlSchema, table, err)
+		return 0, false
+	}
+	var generatedColumns []string
+	for _, column := range columns {
+		if column.Generated {
+			generatedColumns = append(generatedColumns, column.Name)
+		}
+	}
+	inserter, err := newBatchInserter(yasdb, mysqlSchema, yasdbSchema, table, table, yasdbColumns, generatedColumns, batchSize)
+	if err != nil {
+		_ = targetTx.Rollback()
+		log.Logger.Errorf("表 %s.%s 加载失败, 目标端插入语句预编译失败: %v", mysqlSchema, table, err)
+		return 0, false
+	}
+	defer inserter.close()
+	ok := true
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Logger.Errorf("表 %s.%s 加载失败, 读取文件 %s 失败: %v", mysqlSchema, table, fileName, err)
+			ok = false
+			break
+		}
+		values := make([]interface{}, len(record))
+		for i, field := range record {
+			value, err := decodeDataValue(field, columns[i].Type)
+			if err != nil {
+				log.Logger.Errorf("表 %s.%s 加载失败, 文件 %s 中列 %s 的值解析失败: %v", mysqlSchema, table, fileName, columns[i].Name, err)
+				ok = false
+				break
+			}
+			values[i], err = convertValueWithTypeMapping(value, columns[i].Type, columns[i].MappedType)
+			if err != nil {
+				log.Logger.Errorf("表 %s.%s 加载失败, 文件 %s 中列 %s 的值转换失败: %v", mysqlSchema, table, fileName, columns[i].Name, err)
+				ok = false
+				break
+			}
+		}
+		if !ok {
+			break
+		}
+		inserted, err := inserter.add(targetTx, values, nil)
+		batchCount += inserted
+		if err != nil {
+			ok = false
+			break
+		}
+		readCount++
+		if readCount >= batchSize {
+			inserted, err = inserter.flush(targetTx)
+			batchCount += inserted
+			if err != nil {
+				ok = false
+				break
+			}
+			if err := targetTx.Commit(); err != nil {
+				log.Logger.Errorf("表 %s.%s 加载失败, 事务提交失败: %v", mysqlSchema, table, err)
+				return resultCount, false
+			}
+			resultCount += batchCount
+			batchCount = 0
+			readCount = 0
+			targetTx, err = yasdb.Begin()
+			if err != nil {
+				log.Logger.Errorf("表 %s.%s 加载失败, 事务开始失败: %v", mysqlSchema, table, err)
+				return resultCount, false
+			}
+		}
+	}
+	inserted, err := inserter.flush(targetTx)
+	batchCount += inserted
+	if err != nil {
+		ok = false
+	}
+	if err := targetTx.Commit(); err != nil {
+		log.Logger.Errorf("表 %s.%s 加载失败, 事务提交失败: %v", mysqlSchema, table, err)
+		return resultCount, false
+	}
+	resultCount += batchCount
+	return resultCount, ok
+}
+
+func loadDataManifest(dir string) (*dataManifest, error) {
+	fileName := path.Join(dir, data_manifest_file)
+	if !fileutil.IsExist(fileName) {
+		return nil, fmt.Errorf("清单文件 %s 不存在, 表数据没有导出或导出未完成", fileName)
+	}
+	data, err := fileutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	manifest := &dataManifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("解析清单文件 %s 失败: %v", fileName, err)
+	}
+	return manifest, nil
+}
@@ -0,0 +1,52 @@
+package modules
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"m2y/defs/confdef"
+	"m2y/defs/runtimedef"
+	"m2y/log"
+)
+
+// update 为true时用测试结果重新生成testdata中的golden文件: go test ./internal/modules/ -update
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+// TestMain 在临时目录中初始化运行环境、日志和testdata/m2y.toml中的配置
+func TestMain(m *testing.M) {
+	flag.Parse()
+	home, err := os.MkdirTemp("", "m2y-test")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	code, err := runTests(m, home)
+	os.RemoveAll(home)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(code)
+}
+
+func runTests(m *testing.M, home string) (int, error) {
+	os.Setenv("M2Y_DEBUG_MODE", "1")
+	os.Setenv("M2Y_HOME", home)
+	if err := runtimedef.InitRuntime(); err != nil {
+		return 0, err
+	}
+	if err := log.InitLogger("m2y-test", log.NewLogOption(log.SetLogPath(runtimedef.GetLogPath()))); err != nil {
+		return 0, err
+	}
+	config, err := filepath.Abs(filepath.Join("testdata", "m2y.toml"))
+	if err != nil {
+		return 0, err
+	}
+	if err := confdef.InitM2YConfig(config); err != nil {
+		return 0, err
+	}
+	return m.Run(), nil
+}
@@ -0,0 +1,1557 @@
+package modules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"m2y/defs/confdef"
+	"m2y/defs/typedef"
+)
+
+// MySQL存储程序的词法单元类型
+type plTokenKind int
+
+const (
+	pl_token_word     plTokenKind = iota // 关键字、标识符和数字
+	pl_token_ident                       // 反引号括起来的标识符
+	pl_token_string                      // 字符串, text为去掉引号和转义后的内容
+	pl_token_variable                    // 用户变量@x和系统变量@@x
+	pl_token_symbol                      // 运算符和标点
+)
+
+const pl_indent = "    "
+
+// 复合语句中结束一组语句的关键字
+var plTerminators = []string{"END", "ELSE", "ELSEIF", "WHEN", "UNTIL"}
+
+// 后面跟括号时不是函数调用的关键字, 输出时与括号之间保留空格
+var plSpacedKeywords = map[string]struct{}{
+	"AND": {}, "OR": {}, "NOT": {}, "IN": {}, "VALUES": {}, "ON": {}, "AS": {}, "IS": {},
+	"THEN": {}, "ELSE": {}, "WHEN": {}, "WHERE": {}, "SELECT": {}, "FROM": {}, "SET": {},
+	"INTO": {}, "EXISTS": {}, "RETURN": {}, "IF": {}, "ELSIF": {}, "WHILE": {}, "UNTIL": {},
+}
+
+// 只需要改名的函数
+var plFunctionNames = map[string]string{
+	"IFNULL":           "NVL",
+	"SUBSTRING":        "SUBSTR",
+	"LCASE":            "LOWER",
+	"UCASE":            "UPPER",
+	"LENGTH":           "LENGTHB",
+	"CHAR_LENGTH":      "LENGTH",
+	"CHARACTER_LENGTH": "LENGTH",
+	"MID":              "SUBSTR",
+	"POW":              "POWER",
+	"TRUNCATE":         "TRUNC",
+	"CEILING":          "CEIL",
+	"DATE":             "TRUNC",
+}
+
+// MySQL特有的函数, YashanDB没有对应的函数且无法自动改写, 需要人工修改
+var plUnsupportedFunctions = map[string]struct{}{
+	"LAST_INSERT_ID": {}, "ROW_COUNT": {}, "FOUND_ROWS": {}, "CONNECTION_ID": {}, "GET_LOCK": {}, "RELEASE_LOCK": {},
+	"DATE_ADD": {}, "DATE_SUB": {}, "ADDDATE": {}, "SUBDATE": {}, "TIMESTAMPADD": {}, "TIMESTAMPDIFF": {},
+	"FROM_UNIXTIME": {}, "UNIX_TIMESTAMP": {}, "UTC_TIMESTAMP": {}, "UTC_DATE": {}, "UTC_TIME": {},
+	"CURTIME": {}, "CURRENT_TIME": {}, "CONVERT_TZ": {}, "TIMEDIFF": {}, "ADDTIME": {}, "SUBTIME": {},
+	"TIME_TO_SEC": {}, "SEC_TO_TIME": {}, "TO_DAYS": {}, "FROM_DAYS": {}, "MAKEDATE": {}, "MAKETIME": {},
+	"PERIOD_ADD": {}, "PERIOD_DIFF": {}, "DAYOFWEEK": {}, "DAYOFYEAR": {}, "WEEKDAY": {}, "WEEKOFYEAR": {},
+	"YEARWEEK": {}, "DAYNAME": {}, "MONTHNAME": {}, "INTERVAL": {},
+	"SUBSTRING_INDEX": {}, "FIND_IN_SET": {}, "FIELD": {}, "ELT": {}, "MAKE_SET": {}, "EXPORT_SET": {},
+	"STRCMP": {}, "FORMAT": {}, "REPEAT": {}, "UUID": {}, "UUID_SHORT": {}, "RAND": {},
+	"MD5": {}, "SHA1": {}, "SHA2": {}, "INET_ATON": {}, "INET_NTOA": {}, "BIT_COUNT": {},
+}
+
+// MySQL的INTERVAL单位对应的YashanDB函数和单位, factor为换算成该单位的运算, 如WEEK换算为天数
+var plIntervalUnits = map[string]struct {
+	function string
+	unit     string
+	factor   []string
+}{
+	"MICROSECOND": {"NUMTODSINTERVAL", "SECOND", []string{"/", "1000000"}},
+	"SECOND":      {"NUMTODSINTERVAL", "SECOND", nil},
+	"MINUTE":      {"NUMTODSINTERVAL", "MINUTE", nil},
+	"HOUR":        {"NUMTODSINTERVAL", "HOUR", nil},
+	"DAY":         {"NUMTODSINTERVAL", "DAY", nil},
+	"WEEK":        {"NUMTODSINTERVAL", "DAY", []string{"*", "7"}},
+	"MONTH":       {"NUMTOYMINTERVAL", "MONTH", nil},
+	"QUARTER":     {"NUMTOYMINTERVAL", "MONTH", []string{"*", "3"}},
+	"YEAR":        {"NUMTOYMINTERVAL", "YEAR", nil},
+}
+
+// MySQL的DATE_FORMAT、STR_TO_DATE的格式符对应的YashanDB的格式, 不补零的格式符(如%c、%e)按补零的格式转换
+var mysqlDateFormats = map[byte]string{
+	'Y': "YYYY",
+	'y': "YY",
+	'm': "MM",
+	'c': "MM",
+	'd': "DD",
+	'e': "DD",
+	'H': "HH24",
+	'k': "HH24",
+	'h': "HH12",
+	'I': "HH12",
+	'l': "HH12",
+	'i': "MI",
+	's': "SS",
+	'S': "SS",
+	'f': "FF6",
+	'p': "AM",
+	'M': "MONTH",
+	'b': "MON",
+	'W': "DAY",
+	'a': "DY",
+	'j': "DDD",
+	'T': "HH24:MI:SS",
+	'r': "HH12:MI:SS AM",
+}
+
+type plToken struct {
+	kind plTokenKind
+	text string
+}
+
+// is 词法单元是words中的某个关键字时返回true, 不区分大小写
+func (t plToken) is(words ...string) bool {
+	if t.kind != pl_token_word {
+		return false
+	}
+	for _, word := range words {
+		if strings.EqualFold(t.text, word) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t plToken) isSymbol(symbol string) bool {
+	return t.kind == pl_token_symbol && t.text == symbol
+}
+
+func isPLWordRune(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// tokenizePL 将MySQL存储程序的定义切分为词法单元, 忽略空白和注释
+func tokenizePL(src string) ([]plToken, error) {
+	var tokens []plToken
+	rs := []rune(src)
+	at := func(i int) rune {
+		if i < len(rs) {
+			return rs[i]
+		}
+		return 0
+	}
+	for i := 0; i < len(rs); {
+		c := rs[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '#' || (c == '-' && at(i+1) == '-' && (i+2 >= len(rs) || unicode.IsSpace(at(i+2)))):
+			for i < len(rs) && rs[i] != '\n' {
+				i++
+			}
+		case c == '/' && at(i+1) == '*':
+			end := strings.Index(string(rs[i+2:]), "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("注释没有结束")
+			}
+			i += 2 + len([]rune(string(rs[i+2:])[:end])) + 2
+		case c == '`':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(rs); j++ {
+				if rs[j] == '`' {
+					if at(j+1) == '`' {
+						sb.WriteRune('`')
+						j++
+						continue
+					}
+					break
+				}
+				sb.WriteRune(rs[j])
+			}
+			if j >= len(rs) {
+				return nil, fmt.Errorf("标识符 `%s 没有结束", sb.String())
+			}
+			tokens = append(tokens, plToken{kind: pl_token_ident, text: sb.String()})
+			i = j + 1
+		case c == '\'' || c == '"':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(rs); j++ {
+				if rs[j] == '\\' && j+1 < len(rs) {
+					j++
+					sb.WriteString(unescapeMySQLChar(rs[j]))
+					continue
+				}
+				if rs[j] == c {
+					if at(j+1) == c {
+						sb.WriteRune(c)
+						j++
+						continue
+					}
+					break
+				}
+				sb.WriteRune(rs[j])
+			}
+			if j >= len(rs) {
+				return nil, fmt.Errorf("字符串 %c%s 没有结束", c, sb.String())
+			}
+			tokens = append(tokens, plToken{kind: pl_token_string, text: sb.String()})
+			i = j + 1
+		case c == '@':
+			j := i + 1
+			for j < len(rs) && (rs[j] == '@' || rs[j] == '.' || isPLWordRune(rs[j])) {
+				j++
+			}
+			tokens = append(tokens, plToken{kind: pl_token_variable, text: string(rs[i:j])})
+			i = j
+		case isPLWordRune(c):
+			j := i + 1
+			for j < len(rs) && (isPLWordRune(rs[j]) || (unicode.IsDigit(c) && rs[j] == '.')) {
+				j++
+			}
+			tokens = append(tokens, plToken{kind: pl_token_word, text: string(rs[i:j])})
+			i = j
+		default:
+			symbol := string(c)
+			rest := string(rs[i:])
+			for _, s := range []string{"<=>", "->>", ":=", "<=", ">=", "<>", "!=", "||", "&&", "->", "<<", ">>"} {
+				if strings.HasPrefix(rest, s) {
+					symbol = s
+					break
+				}
+			}
+			tokens = append(tokens, plToken{kind: pl_token_symbol, text: symbol})
+			i += len([]rune(symbol))
+		}
+	}
+	return tokens, nil
+}
+
+func unescapeMySQLChar(r rune) string {
+	switch r {
+	case 'n':
+		return "\n"
+	case 't':
+		return "\t"
+	case 'r':
+		return "\r"
+	case '0':
+		return "\x00"
+	case 'Z':
+		return "\x1a"
+	case '%', '_':
+		// LIKE中的\%和\_保留反斜杠
+		return "\\" + string(r)
+	default:
+		return string(r)
+	}
+}
+
+// renderPLTokens 将转换后的词法单元拼接为语句文本
+func renderPLTokens(tokens []plToken) string {
+	var sb strings.Builder
+	for i, t := range tokens {
+		if i > 0 && needPLSpace(tokens[i-1], t) && !isPLUnarySign(tokens, i-1) {
+			sb.WriteByte(' ')
+		}
+		if t.kind == pl_token_string {
+			sb.WriteString("'" + strings.ReplaceAll(t.text, "'", "''") + "'")
+			continue
+		}
+		sb.WriteString(t.text)
+	}
+	return sb.String()
+}
+
+// isPLUnarySign 位置i的+或-是正负号时返回true, 正负号与后面的数字之间不加空格
+func isPLUnarySign(tokens []plToken, i int) bool {
+	if !tokens[i].isSymbol("-") && !tokens[i].isSymbol("+") {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	prev := tokens[i-1]
+	if prev.kind == pl_token_symbol {
+		return prev.text != ")"
+	}
+	_, ok := plSpacedKeywords[strings.ToUpper(prev.text)]
+	return prev.kind == pl_token_word && ok
+}
+
+func needPLSpace(prev, cur plToken) bool {
+	if cur.kind == pl_token_symbol {
+		switch cur.text {
+		case ",", ";", ")", ".", "%":
+			return false
+		case "(":
+			if prev.kind == pl_token_word {
+				_, ok := plSpacedKeywords[strings.ToUpper(prev.text)]
+				return ok
+			}
+			return !prev.isSymbol("(")
+		}
+	}
+	if prev.kind == pl_token_symbol {
+		switch prev.text {
+		case "(", ".", "%":
+			return false
+		}
+	}
+	return true
+}
+
+// plBlock MySQL的BEGIN...END块, 转换为YashanDB的DECLARE...BEGIN...EXCEPTION...END块
+type plBlock struct {
+	label      string
+	decls      []string
+	body       []string
+	exceptions []string
+	others     []string   // EXIT HANDLER FOR SQLEXCEPTION转换的WHEN OTHERS, 必须放在最后
+	notFound   *plHandler // 块中声明的NOT FOUND处理器
+}
+
+// plHandler MySQL的NOT FOUND处理器, CONTINUE处理器在FETCH和SELECT INTO没有数据时执行action,
+// EXIT处理器转换为块的WHEN NO_DATA_FOUND异常处理
+type plHandler struct {
+	exit   bool
+	action []string // 缩进为空的语句, 使用时加上缩进
+}
+
+// plTranslator 将MySQL的存储程序转换为YashanDB的PL, 无法转换的内容记录在issues中, 需要人工修改
+type plTranslator struct {
+	tokens  []plToken
+	pos     int
+	trigger bool // 触发器中NEW.和OLD.转换为:NEW.和:OLD.
+	issues  []string
+	blocks  []*plBlock
+	loops   []string // 当前所在循环的标签
+	label   string   // 程序体BEGIN块的标签, LEAVE该标签转换为RETURN
+}
+
+func newPLTranslator(tokens []plToken, trigger bool) *plTranslator {
+	return &plTranslator{tokens: tokens, trigger: trigger}
+}
+
+func (p *plTranslator) issue(format string, a ...interface{}) {
+	msg := fmt.Sprintf(format, a...)
+	for _, issue := range p.issues {
+		if issue == msg {
+			return
+		}
+	}
+	p.issues = append(p.issues, msg)
+}
+
+func (p *plTranslator) eof() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *plTranslator) peek() plToken {
+	return p.peekAt(0)
+}
+
+func (p *plTranslator) peekAt(offset int) plToken {
+	if p.pos+offset >= len(p.tokens) {
+		return plToken{kind: pl_token_symbol}
+	}
+	return p.tokens[p.pos+offset]
+}
+
+func (p *plTranslator) next() plToken {
+	t := p.peek()
+	if !p.eof() {
+		p.pos++
+	}
+	return t
+}
+
+// accept 下一个词法单元是关键字word时跳过并返回true
+func (p *plTranslator) accept(word string) bool {
+	if p.peek().is(word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *plTranslator) acceptSymbol(symbol string) bool {
+	if p.peek().isSymbol(symbol) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *plTranslator) expect(word string) {
+	if !p.accept(word) {
+		p.issue("缺少关键字 %s, 实际为 %s", word, p.peek().text)
+	}
+}
+
+// until 读取到括号和CASE表达式之外的关键字words之前, 不包括该关键字
+func (p *plTranslator) until(words ...string) []plToken {
+	start := p.pos
+	depth, caseDepth := 0, 0
+	for ; !p.eof(); p.pos++ {
+		t := p.peek()
+		switch {
+		case t.isSymbol("("):
+			depth++
+		case t.isSymbol(")"):
+			depth--
+		case t.isSymbol(";") && depth == 0:
+			return p.tokens[start:p.pos]
+		case t.is("CASE"):
+			caseDepth++
+		case t.is("END") && caseDepth > 0:
+			caseDepth--
+		case depth == 0 && caseDepth == 0 && t.is(words...):
+			return p.tokens[start:p.pos]
+		}
+	}
+	return p.tokens[start:p.pos]
+}
+
+// statementTokens 读取到语句结尾的分号, 跳过分号
+func (p *plTranslator) statementTokens() []plToken {
+	tokens := p.until()
+	p.acceptSymbol(";")
+	return tokens
+}
+
+// statements 转换一组语句, 遇到END、ELSE等结束关键字时返回
+func (p *plTranslator) statements(indent string) []string {
+	var lines []string
+	for !p.eof() && !p.peek().is(plTerminators...) {
+		lines = append(lines, p.statement(indent)...)
+	}
+	return lines
+}
+
+func (p *plTranslator) statement(indent string) []string {
+	var label string
+	if p.peek().kind != pl_token_symbol && p.peekAt(1).isSymbol(":") {
+		label = p.next().text
+		p.next()
+	}
+	t := p.peek()
+	switch {
+	case t.isSymbol(";"):
+		p.next()
+		return nil
+	case t.is("BEGIN"):
+		p.next()
+		lines := p.nestedBlock(indent, label)
+		p.acceptSymbol(";")
+		return lines
+	case t.is("IF"):
+		p.next()
+		return p.ifStatement(indent)
+	case t.is("SET"):
+		p.next()
+		return p.setStatement(indent)
+	case t.is("DECLARE"):
+		p.issue("DECLARE只能出现在BEGIN块的开头")
+		p.statementTokens()
+		return nil
+	case t.is("SIGNAL", "RESIGNAL"):
+		p.next()
+		return p.signalStatement(indent, t)
+	case t.is("RETURN"):
+		p.next()
+		return []string{indent + "RETURN " + p.expr(p.statementTokens()) + ";"}
+	case t.is("WHILE", "LOOP", "REPEAT"):
+		p.next()
+		return p.loopStatement(indent, label, t)
+	case t.is("LEAVE", "ITERATE"):
+		p.next()
+		return p.leaveStatement(indent, t)
+	case t.is("CASE"):
+		p.next()
+		return p.caseStatement(indent)
+	case t.is("OPEN", "CLOSE"):
+		return []string{indent + p.expr(p.statementTokens()) + ";"}
+	case t.is("FETCH"):
+		p.next()
+		return p.fetchStatement(indent)
+	case t.is("CALL"):
+		p.next()
+		return []string{indent + p.expr(p.statementTokens()) + ";"}
+	case t.is("START") && p.peekAt(1).is("TRANSACTION"):
+		// YashanDB的事务是隐式开始的
+		p.statementTokens()
+		return nil
+	case t.is("PREPARE", "EXECUTE", "DEALLOCATE"):
+		tokens := p.statementTokens()
+		p.issue("动态SQL需要改写为EXECUTE IMMEDIATE: %s", renderPLTokens(tokens))
+		return []string{indent + renderPLTokens(tokens) + ";"}
+	case t.is("SELECT"):
+		return p.selectStatement(indent)
+	case t.is("INSERT", "UPDATE", "DELETE", "COMMIT", "ROLLBACK", "SAVEPOINT"):
+		return []string{indent + p.expr(p.statementTokens()) + ";"}
+	case t.is("REPLACE"):
+		p.issue("REPLACE INTO需要改写为MERGE INTO")
+		return []string{indent + p.expr(p.statementTokens()) + ";"}
+	}
+	tokens := p.statementTokens()
+	p.issue("不支持的语句: %s", renderPLTokens(tokens))
+	return []string{indent + renderPLTokens(tokens) + ";"}
+}
+
+// body 转换触发器或存储过程的程序体, 程序体可以是BEGIN...END块或者一条语句
+func (p *plTranslator) body() *plBlock {
+	var label string
+	if p.peek().kind != pl_token_symbol && p.peekAt(1).isSymbol(":") && p.peekAt(2).is("BEGIN") {
+		label = p.next().text
+		p.next()
+	}
+	var block *plBlock
+	if p.accept("BEGIN") {
+		p.label = label
+		block = p.block(pl_indent, label)
+		p.acceptSymbol(";")
+	} else {
+		block = &plBlock{}
+		p.blocks = append(p.blocks, block)
+		block.body = p.statement(pl_indent)
+		p.blocks = p.blocks[:len(p.blocks)-1]
+	}
+	if !p.eof() {
+		p.issue("无法解析的内容: %s", renderPLTokens(p.tokens[p.pos:]))
+	}
+	return block
+}
+
+// nestedBlock 转换嵌套的BEGIN...END块, BEGIN已读取
+func (p *plTranslator) nestedBlock(indent, label string) []string {
+	block := p.block(indent+pl_indent, label)
+	var lines []string
+	if len(label) != 0 {
+		lines = append(lines, indent+"<<"+label+">>")
+	}
+	if len(block.decls) != 0 {
+		lines = append(lines, indent+"DECLARE")
+		lines = append(lines, block.decls...)
+	}
+	lines = append(lines, block.render(indent)...)
+	return append(lines, strings.TrimRight(indent+"END "+label, " ")+";")
+}
+
+// block 读取BEGIN块的声明和语句, 直到END
+func (p *plTranslator) block(indent, label string) *plBlock {
+	block := &plBlock{label: label}
+	p.blocks = append(p.blocks, block)
+	defer func() { p.blocks = p.blocks[:len(p.blocks)-1] }()
+	for p.accept("DECLARE") {
+		p.declare(indent, block)
+	}
+	body := p.statements(indent)
+	if !p.accept("END") {
+		p.issue("BEGIN块没有以END结束, 实际为 %s", p.peek().text)
+		for !p.eof() && !p.accept("END") {
+			p.next()
+		}
+	}
+	if len(label) != 0 && p.peek().kind != pl_token_symbol && strings.EqualFold(p.peek().text, label) {
+		p.next()
+	}
+	block.body = body
+	return block
+}
+
+// render 输出块从BEGIN开始的语句和异常处理部分, 不包括END
+func (b *plBlock) render(indent string) []string {
+	lines := []string{indent + "BEGIN"}
+	if len(b.body) == 0 {
+		lines = append(lines, indent+pl_indent+"NULL;")
+	}
+	lines = append(lines, b.body...)
+	if len(b.exceptions) != 0 || len(b.others) != 0 {
+		lines = append(lines, indent+"EXCEPTION")
+		lines = append(lines, b.exceptions...)
+		lines = append(lines, b.others...)
+	}
+	return lines
+}
+
+// declare 转换DECLARE语句, DECLARE已读取
+func (p *plTranslator) declare(indent string, block *plBlock) {
+	switch {
+	case p.peek().is("CONTINUE", "EXIT", "UNDO") && p.peekAt(1).is("HANDLER"):
+		p.declareHandler(indent, block)
+		return
+	case p.peekAt(1).is("CURSOR"):
+		name := p.identifier(p.next())
+		p.next()
+		p.expect("FOR")
+		block.decls = append(block.decls, indent+"CURSOR "+name+" IS "+p.expr(p.statementTokens())+";")
+		return
+	case p.peekAt(1).is("CONDITION"):
+		tokens := p.statementTokens()
+		p.issue("不支持自定义条件: DECLARE %s", renderPLTokens(tokens))
+		return
+	}
+	var names []string
+	for {
+		names = append(names, p.identifier(p.next()))
+		if !p.acceptSymbol(",") {
+			break
+		}
+	}
+	typeTokens := p.until("DEFAULT")
+	var defaultExpr string
+	if p.accept("DEFAULT") {
+		defaultExpr = p.expr(p.until())
+	}
+	p.acceptSymbol(";")
+	yasType := p.translateType(typeTokens)
+	for _, name := range names {
+		decl := indent + name + " " + yasType
+		if len(defaultExpr) != 0 {
+			decl += " := " + defaultExpr
+		}
+		block.decls = append(block.decls, decl+";")
+	}
+}
+
+// declareHandler 转换DECLARE ... HANDLER, NOT FOUND和SQLEXCEPTION以外的条件需要人工修改
+func (p *plTranslator) declareHandler(indent string, block *plBlock) {
+	kind := strings.ToUpper(p.next().text)
+	p.next()
+	p.expect("FOR")
+	var conditions [][]plToken
+	for {
+		start := p.pos
+		switch {
+		case p.accept("NOT"):
+			p.expect("FOUND")
+		case p.accept("SQLSTATE"):
+			p.accept("VALUE")
+			p.next()
+		default:
+			p.next()
+		}
+		conditions = append(conditions, p.tokens[start:p.pos])
+		if !p.acceptSymbol(",") {
+			break
+		}
+	}
+	// 处理器的动作是一条语句, 可以是BEGIN...END块
+	action := p.statement("")
+	if kind == "UNDO" {
+		p.issue("不支持UNDO HANDLER")
+		return
+	}
+	exit := kind == "EXIT"
+	indentLines := func(prefix string) []string {
+		var lines []string
+		for _, line := range action {
+			lines = append(lines, prefix+line)
+		}
+		return lines
+	}
+	for _, condition := range conditions {
+		text := strings.ToUpper(renderPLTokens(condition))
+		switch {
+		case text == "NOT FOUND" || text == "SQLSTATE '02000'" || text == "1329":
+			block.notFound = &plHandler{exit: exit, action: action}
+			if exit {
+				block.exceptions = append(block.exceptions, indent+"WHEN NO_DATA_FOUND THEN")
+				block.exceptions = append(block.exceptions, indentLines(indent+pl_indent)...)
+			}
+		case (text == "SQLEXCEPTION" || text == "SQLWARNING") && exit:
+			if text == "SQLWARNING" {
+				p.issue("YashanDB没有警告异常, EXIT HANDLER FOR SQLWARNING按SQLEXCEPTION处理")
+			}
+			if len(block.others) == 0 {
+				block.others = append(block.others, indent+"WHEN OTHERS THEN")
+				block.others = append(block.others, indentLines(indent+pl_indent)...)
+			}
+		case (text == "SQLSTATE '23000'" || text == "1062") && exit:
+			block.exceptions = append(block.exceptions, indent+"WHEN DUP_VAL_ON_INDEX THEN")
+			block.exceptions = append(block.exceptions, indentLines(indent+pl_indent)...)
+		default:
+			p.issue("不支持的处理器: DECLARE %s HANDLER FOR %s", kind, renderPLTokens(condition))
+		}
+	}
+}
+
+// notFoundHandler 返回当前生效的NOT FOUND处理器, 内层块的处理器优先
+func (p *plTranslator) notFoundHandler() *plHandler {
+	for i := len(p.blocks) - 1; i >= 0; i-- {
+		if p.blocks[i].notFound != nil {
+			return p.blocks[i].notFound
+		}
+	}
+	return nil
+}
+
+// fetchStatement FETCH不会因为没有数据报错, 转换后检查%NOTFOUND并执行NOT FOUND处理器,
+// 没有CONTINUE处理器时与MySQL一样抛出异常
+func (p *plTranslator) fetchStatement(indent string) []string {
+	tokens := p.statementTokens()
+	if len(tokens) > 0 && tokens[0].is("NEXT") {
+		tokens = tokens[1:]
+	}
+	if len(tokens) > 0 && tokens[0].is("FROM") {
+		tokens = tokens[1:]
+	}
+	if len(tokens) == 0 {
+		p.issue("无法解析的FETCH语句")
+		return nil
+	}
+	cursor := p.identifier(tokens[0])
+	lines := []string{indent + "FETCH " + p.expr(tokens) + ";"}
+	lines = append(lines, indent+"IF "+cursor+"%NOTFOUND THEN")
+	if handler := p.notFoundHandler(); handler != nil && !handler.exit {
+		for _, line := range handler.action {
+			lines = append(lines, indent+pl_indent+line)
+		}
+	} else {
+		lines = append(lines, indent+pl_indent+"RAISE NO_DATA_FOUND;")
+	}
+	return append(lines, indent+"END IF;")
+}
+
+// loopStatement 转换WHILE、LOOP和REPEAT循环, 关键字已读取
+func (p *plTranslator) loopStatement(indent, label string, keyword plToken) []string {
+	var lines []string
+	if len(label) != 0 {
+		lines = append(lines, indent+"<<"+label+">>")
+	}
+	p.loops = append(p.loops, strings.ToUpper(label))
+	defer func() { p.loops = p.loops[:len(p.loops)-1] }()
+	switch {
+	case keyword.is("WHILE"):
+		lines = append(lines, indent+"WHILE "+p.cond(p.until("DO"))+" LOOP")
+		p.expect("DO")
+		lines = append(lines, p.statements(indent+pl_indent)...)
+	case keyword.is("LOOP"):
+		lines = append(lines, indent+"LOOP")
+		lines = append(lines, p.statements(indent+pl_indent)...)
+	default:
+		lines = append(lines, indent+"LOOP")
+		lines = append(lines, p.statements(indent+pl_indent)...)
+		p.expect("UNTIL")
+		lines = append(lines, indent+pl_indent+"EXIT WHEN "+p.cond(p.until("END"))+";")
+	}
+	p.expect("END")
+	p.expect(strings.ToUpper(keyword.text))
+	if len(label) != 0 && p.peek().kind != pl_token_symbol && strings.EqualFold(p.peek().text, label) {
+		p.next()
+	}
+	p.acceptSymbol(";")
+	return append(lines, strings.TrimRight(indent+"END LOOP "+label, " ")+";")
+}
+
+// leaveStatement LEAVE循环转换为EXIT, ITERATE转换为CONTINUE, LEAVE程序体的标签转换为RETURN
+func (p *plTranslator) leaveStatement(indent string, keyword plToken) []string {
+	tokens := p.statementTokens()
+	if len(tokens) != 1 {
+		p.issue("无法解析的%s语句", strings.ToUpper(keyword.text))
+		return nil
+	}
+	label := tokens[0].text
+	if inArrayStr(strings.ToUpper(label), p.loops) {
+		if keyword.is("LEAVE") {
+			return []string{indent + "EXIT " + label + ";"}
+		}
+		return []string{indent + "CONTINUE " + label + ";"}
+	}
+	if keyword.is("LEAVE") && len(p.label) != 0 && strings.EqualFold(label, p.label) {
+		return []string{indent + "RETURN;"}
+	}
+	p.issue("%s %s 不是循环的标签, 需要改写", strings.ToUpper(keyword.text), label)
+	return []string{indent + "-- " + strings.ToUpper(keyword.text) + " " + label + ";"}
+}
+
+// caseStatement 转换CASE语句, CASE已读取, 与YashanDB的写法相同
+func (p *plTranslator) caseStatement(indent string) []string {
+	head := "CASE"
+	if operand := p.until("WHEN"); len(operand) != 0 {
+		head += " " + p.expr(operand)
+	}
+	lines := []string{indent + head}
+	for {
+		switch {
+		case p.accept("WHEN"):
+			lines = append(lines, indent+pl_indent+"WHEN "+p.expr(p.until("THEN"))+" THEN")
+			p.expect("THEN")
+			lines = append(lines, p.statements(indent+pl_indent+pl_indent)...)
+			continue
+		case p.accept("ELSE"):
+			lines = append(lines, indent+pl_indent+"ELSE")
+			lines = append(lines, p.statements(indent+pl_indent+pl_indent)...)
+			continue
+		case p.accept("END"):
+			p.expect("CASE")
+			p.acceptSymbol(";")
+		default:
+			p.issue("CASE语句没有以END CASE结束, 实际为 %s", p.peek().text)
+			p.next()
+		}
+		return append(lines, indent+"END CASE;")
+	}
+}
+
+// ifStatement 转换IF语句, IF已读取, ELSEIF转换为ELSIF
+func (p *plTranslator) ifStatement(indent string) []string {
+	lines := []string{indent + "IF " + p.cond(p.until("THEN")) + " THEN"}
+	p.expect("THEN")
+	for {
+		lines = append(lines, p.statements(indent+pl_indent)...)
+		switch {
+		case p.accept("ELSEIF"):
+			lines = append(lines, indent+"ELSIF "+p.cond(p.until("THEN"))+" THEN")
+			p.expect("THEN")
+			continue
+		case p.accept("ELSE"):
+			lines = append(lines, indent+"ELSE")
+			continue
+		case p.accept("END"):
+			p.expect("IF")
+			p.acceptSymbol(";")
+		default:
+			p.issue("IF语句没有以END IF结束, 实际为 %s", p.peek().text)
+			p.next()
+		}
+		return append(lines, indent+"END IF;")
+	}
+}
+
+// setStatement 转换SET语句, SET已读取, 每个赋值转换为一条:=语句
+func (p *plTranslator) setStatement(indent string) []string {
+	var lines []string
+	for _, assignment := range splitPLTokens(p.statementTokens(), ",") {
+		var target, value []plToken
+		for i, t := range assignment {
+			if t.isSymbol("=") || t.isSymbol(":=") {
+				target, value = assignment[:i], assignment[i+1:]
+				break
+			}
+		}
+		if len(target) == 0 {
+			p.issue("无法解析的SET语句: %s", renderPLTokens(assignment))
+			lines = append(lines, indent+"-- SET "+renderPLTokens(assignment))
+			continue
+		}
+		if target[0].is("SESSION", "GLOBAL", "LOCAL", "PERSIST") || target[0].kind == pl_token_variable {
+			p.issue("不支持设置会话变量或用户变量: %s", renderPLTokens(target))
+		}
+		lines = append(lines, indent+p.expr(target)+" := "+p.expr(value)+";")
+	}
+	return lines
+}
+
+// signalStatement SIGNAL SQLSTATE转换为RAISE_APPLICATION_ERROR
+func (p *plTranslator) signalStatement(indent string, keyword plToken) []string {
+	tokens := p.statementTokens()
+	if keyword.is("RESIGNAL") {
+		if len(tokens) == 0 {
+			return []string{indent + "RAISE;"}
+		}
+		p.issue("不支持带参数的RESIGNAL")
+	}
+	if len(tokens) == 0 || !tokens[0].is("SQLSTATE") {
+		p.issue("SIGNAL只支持SQLSTATE, 不支持自定义的条件")
+		return []string{indent + "RAISE_APPLICATION_ERROR(-20000, " + renderPLTokens(tokens) + ");"}
+	}
+	tokens = tokens[1:]
+	if len(tokens) > 0 && tokens[0].is("VALUE") {
+		tokens = tokens[1:]
+	}
+	var sqlstate string
+	if len(tokens) > 0 && tokens[0].kind == pl_token_string {
+		sqlstate = tokens[0].text
+	}
+	message := renderPLTokens([]plToken{{kind: pl_token_string, text: "SQLSTATE " + sqlstate}})
+	for i, t := range tokens {
+		if !t.is("SET") {
+			continue
+		}
+		for _, item := range splitPLTokens(tokens[i+1:], ",") {
+			if len(item) > 2 && item[0].is("MESSAGE_TEXT") && item[1].isSymbol("=") {
+				message = p.expr(item[2:])
+			}
+		}
+	}
+	return []string{indent + "RAISE_APPLICATION_ERROR(-20000, " + message + ");"}
+}
+
+// selectStatement SELECT ... FROM ... INTO ... 转换为 SELECT ... INTO ... FROM ...
+func (p *plTranslator) selectStatement(indent string) []string {
+	tokens := p.statementTokens()
+	from, into := -1, -1
+	depth := 0
+	for i, t := range tokens {
+		switch {
+		case t.isSymbol("("):
+			depth++
+		case t.isSymbol(")"):
+			depth--
+		case depth == 0 && t.is("FROM") && from < 0:
+			from = i
+		case depth == 0 && t.is("INTO"):
+			into = i
+		}
+	}
+	if into < 0 {
+		p.issue("存储程序中的SELECT语句需要使用INTO: %s", renderPLTokens(tokens))
+	} else if from >= 0 && into > from {
+		end := len(tokens)
+		for i := into + 1; i < len(tokens); i++ {
+			if tokens[i].is("FOR", "LOCK") {
+				end = i
+				break
+			}
+		}
+		reordered := append([]plToken{}, tokens[:from]...)
+		reordered = append(reordered, tokens[into:end]...)
+		reordered = append(reordered, tokens[from:into]...)
+		tokens = append(reordered, tokens[end:]...)
+	}
+	lines := []string{indent + p.expr(tokens) + ";"}
+	// MySQL的SELECT INTO没有数据时执行CONTINUE处理器, YashanDB会抛出NO_DATA_FOUND
+	if handler := p.notFoundHandler(); into >= 0 && handler != nil && !handler.exit {
+		wrapped := []string{indent + "BEGIN", pl_indent + lines[0], indent + "EXCEPTION", indent + pl_indent + "WHEN NO_DATA_FOUND THEN"}
+		for _, line := range handler.action {
+			wrapped = append(wrapped, indent+pl_indent+pl_indent+line)
+		}
+		return append(wrapped, indent+"END;")
+	}
+	return lines
+}
+
+// cond 转换IF、WHILE等语句的条件, MySQL中整数可以直接作为条件, YashanDB需要比较运算
+func (p *plTranslator) cond(tokens []plToken) string {
+	switch {
+	case len(tokens) == 1 && tokens[0].kind != pl_token_symbol && tokens[0].kind != pl_token_string:
+		return p.expr(tokens) + " <> 0"
+	case len(tokens) == 2 && tokens[0].is("NOT") && tokens[1].kind != pl_token_symbol && tokens[1].kind != pl_token_string:
+		return p.expr(tokens[1:]) + " = 0"
+	}
+	return p.expr(tokens)
+}
+
+// expr 转换表达式或语句中的词法单元
+func (p *plTranslator) expr(tokens []plToken) string {
+	return renderPLTokens(p.translateTokens(tokens))
+}
+
+// translateSQLExpression 转换information_schema中保存的MySQL表达式, 如生成列的表达式,
+// 去掉字符串的字符集前缀后与PL中的表达式使用相同的转换, 返回转换结果和无法转换的原因
+func translateSQLExpression(expression string) (string, []string) {
+	tokens, err := tokenizePL(charsetIntroducerRegexp.ReplaceAllString(expression, "$1'"))
+	if err != nil {
+		return "", []string{err.Error()}
+	}
+	p := newPLTranslator(tokens, false)
+	return p.expr(tokens), p.issues
+}
+
+func (p *plTranslator) translateTokens(tokens []plToken) []plToken {
+	var out []plToken
+	word := func(text string) plToken { return plToken{kind: pl_token_word, text: text} }
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		nextIs := func(symbol string) bool { return i+1 < len(tokens) && tokens[i+1].isSymbol(symbol) }
+		switch t.kind {
+		case pl_token_ident:
+			if nextIs(".") {
+				if schema, ok := remapMySQLSchema(t.text); ok {
+					out = append(out, word(formatKeyWord(schema)))
+					continue
+				}
+			}
+			out = append(out, word(p.identifier(t)))
+		case pl_token_variable:
+			p.issue("不支持用户变量或系统变量 %s", t.text)
+			out = append(out, t)
+		case pl_token_symbol:
+			switch t.text {
+			case "||":
+				out = append(out, word("OR"))
+			case "&&":
+				out = append(out, word("AND"))
+			case "!":
+				out = append(out, word("NOT"))
+			case "<=>", "->", "->>":
+				p.issue("不支持运算符 %s", t.text)
+				out = append(out, t)
+			default:
+				out = append(out, t)
+			}
+		case pl_token_word:
+			upper := strings.ToUpper(t.text)
+			// MySQL的布尔值就是整数
+			if upper == "TRUE" || upper == "FALSE" {
+				out = append(out, word(map[string]string{"TRUE": "1", "FALSE": "0"}[upper]))
+				continue
+			}
+			if p.trigger && (upper == "NEW" || upper == "OLD") && nextIs(".") {
+				out = append(out, word(":"+upper))
+				continue
+			}
+			if upper == "INTERVAL" && isMySQLIntervalExpr(tokens, i) {
+				if translated, end, ok := p.translateInterval(tokens, i); ok {
+					out = append(out, translated...)
+					i = end
+					continue
+				}
+			}
+			if nextIs("(") {
+				if args, end, ok := splitPLArgs(tokens, i+1); ok {
+					if translated, ok := p.translateFunction(upper, args); ok {
+						out = append(out, translated...)
+						i = end
+						continue
+					}
+				}
+			}
+			if nextIs(".") {
+				if schema, ok := remapMySQLSchema(t.text); ok {
+					out = append(out, word(formatKeyWord(schema)))
+					continue
+				}
+			}
+			out = append(out, t)
+		default:
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// translateFunction 转换MySQL特有的函数, 不需要转换时返回false
+func (p *plTranslator) translateFunction(name string, args [][]plToken) ([]plToken, bool) {
+	word := func(text string) plToken { return plToken{kind: pl_token_word, text: text} }
+	symbol := func(text string) plToken { return plToken{kind: pl_token_symbol, text: text} }
+	call := func(name string, args [][]plToken) []plToken {
+		out := []plToken{word(name), symbol("(")}
+		for i, arg := range args {
+			if i > 0 {
+				out = append(out, symbol(","))
+			}
+			out = append(out, p.translateTokens(arg)...)
+		}
+		return append(out, symbol(")"))
+	}
+	if newName, ok := plFunctionNames[name]; ok {
+		return call(newName, args), true
+	}
+	switch name {
+	case "NOW", "SYSDATE", "CURRENT_TIMESTAMP", "LOCALTIME", "LOCALTIMESTAMP":
+		if len(args) == 0 {
+			return []plToken{word("SYSDATE")}, true
+		}
+		return []plToken{word("SYSTIMESTAMP")}, true
+	case "CURDATE", "CURRENT_DATE":
+		return []plToken{word("TRUNC"), symbol("("), word("SYSDATE"), symbol(")")}, true
+	case "CONCAT":
+		if len(args) < 2 {
+			return nil, false
+		}
+		out := []plToken{symbol("(")}
+		for i, arg := range args {
+			if i > 0 {
+				out = append(out, symbol("||"))
+			}
+			out = append(out, p.translateTokens(arg)...)
+		}
+		return append(out, symbol(")")), true
+	case "IF":
+		if len(args) != 3 {
+			return nil, false
+		}
+		out := []plToken{word("CASE"), word("WHEN")}
+		out = append(out, p.translateTokens(args[0])...)
+		out = append(out, word("THEN"))
+		out = append(out, p.translateTokens(args[1])...)
+		out = append(out, word("ELSE"))
+		out = append(out, p.translateTokens(args[2])...)
+		return append(out, word("END")), true
+	case "ISNULL":
+		if len(args) != 1 {
+			return nil, false
+		}
+		out := []plToken{symbol("(")}
+		out = append(out, p.translateTokens(args[0])...)
+		return append(out, word("IS"), word("NULL"), symbol(")")), true
+	case "DATE_FORMAT", "STR_TO_DATE":
+		if len(args) != 2 || len(args[1]) != 1 || args[1][0].kind != pl_token_string {
+			p.issue("%s 的格式不是字符串常量, 无法转换", name)
+			return nil, false
+		}
+		format, ok := translateDateFormat(args[1][0].text)
+		if !ok {
+			p.issue("%s 的格式 '%s' 无法转换", name, args[1][0].text)
+			return nil, false
+		}
+		newName := "TO_CHAR"
+		if name == "STR_TO_DATE" {
+			newName = "TO_DATE"
+		}
+		return call(newName, [][]plToken{args[0], {{kind: pl_token_string, text: format}}}), true
+	case "GROUP_CONCAT":
+		return p.translateGroupConcat(args)
+	case "LOCATE":
+		if len(args) != 2 && len(args) != 3 {
+			return nil, false
+		}
+		return call("INSTR", append([][]plToken{args[1], args[0]}, args[2:]...)), true
+	case "YEAR", "MONTH", "DAY", "DAYOFMONTH":
+		if len(args) != 1 {
+			return nil, false
+		}
+		field := name
+		if name == "DAYOFMONTH" {
+			field = "DAY"
+		}
+		out := []plToken{word("EXTRACT"), symbol("("), word(field), word("FROM")}
+		out = append(out, p.translateTokens(args[0])...)
+		return append(out, symbol(")")), true
+	case "DATEDIFF":
+		if len(args) != 2 {
+			return nil, false
+		}
+		out := []plToken{symbol("(")}
+		out = append(out, call("TRUNC", args[:1])...)
+		out = append(out, symbol("-"))
+		out = append(out, call("TRUNC", args[1:])...)
+		return append(out, symbol(")")), true
+	case "CAST":
+		if len(args) != 1 {
+			return nil, false
+		}
+		return p.translateCast(args[0])
+	case "DATE_ADD", "DATE_SUB", "ADDDATE", "SUBDATE":
+		// DATE_ADD(d, INTERVAL n unit)转换为(d + NUMTODSINTERVAL(n, 'unit')), ADDDATE(d, n)的n为天数
+		if len(args) != 2 {
+			break
+		}
+		interval := args[1]
+		if len(interval) == 0 || !interval[0].is("INTERVAL") {
+			if name == "DATE_ADD" || name == "DATE_SUB" {
+				break
+			}
+			interval = append([]plToken{word("INTERVAL")}, append(append([]plToken{}, interval...), word("DAY"))...)
+		}
+		translated, end, ok := p.translateInterval(interval, 0)
+		if !ok || end != len(interval)-1 {
+			break
+		}
+		op := "+"
+		if name == "DATE_SUB" || name == "SUBDATE" {
+			op = "-"
+		}
+		out := []plToken{symbol("(")}
+		out = append(out, p.translateTokens(args[0])...)
+		out = append(out, symbol(op))
+		out = append(out, translated...)
+		return append(out, symbol(")")), true
+	case "TIMESTAMPADD":
+		// TIMESTAMPADD(unit, n, d)转换为(d + NUMTODSINTERVAL(n, 'unit'))
+		if len(args) != 3 || len(args[0]) != 1 || args[0][0].kind != pl_token_word {
+			break
+		}
+		if _, ok := plIntervalUnits[strings.ToUpper(args[0][0].text)]; !ok {
+			break
+		}
+		interval := append([]plToken{word("INTERVAL")}, append(append([]plToken{}, args[1]...), args[0][0])...)
+		translated, _, ok := p.translateInterval(interval, 0)
+		if !ok {
+			break
+		}
+		out := []plToken{symbol("(")}
+		out = append(out, p.translateTokens(args[2])...)
+		out = append(out, symbol("+"))
+		out = append(out, translated...)
+		return append(out, symbol(")")), true
+	}
+	if _, ok := plUnsupportedFunctions[name]; ok {
+		p.issue("不支持函数 %s", name)
+	}
+	return nil, false
+}
+
+// translateInterval 将start位置开始的INTERVAL expr unit转换为NUMTODSINTERVAL或NUMTOYMINTERVAL,
+// 复合单位只支持字符串常量, 换算成最小的单位, 返回转换结果和unit的位置, 无法转换时记录原因
+func (p *plTranslator) translateInterval(tokens []plToken, start int) ([]plToken, int, bool) {
+	word := func(text string) plToken { return plToken{kind: pl_token_word, text: text} }
+	symbol := func(text string) plToken { return plToken{kind: pl_token_symbol, text: text} }
+	end, unitPos := len(tokens), -1
+	depth := 0
+	for i := start + 1; i < len(tokens) && unitPos < 0 && end == len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.isSymbol("("):
+			depth++
+		case t.isSymbol(")"):
+			depth--
+			if depth < 0 {
+				end = i
+			}
+		case depth == 0 && (t.isSymbol(",") || t.isSymbol(";")):
+			end = i
+		case depth == 0 && i > start+1 && isMySQLIntervalUnit(t) && !(i+1 < len(tokens) && tokens[i+1].isSymbol("(")):
+			unitPos = i
+		}
+	}
+	if unitPos < 0 {
+		p.issue("无法转换的INTERVAL表达式: %s", renderPLTokens(tokens[start:end]))
+		return nil, 0, false
+	}
+	value := tokens[start+1 : unitPos]
+	unit := strings.ToUpper(tokens[unitPos].text)
+	if _, ok := mysqlCompoundIntervals[unit]; ok {
+		negative := len(value) == 2 && value[0].isSymbol("-")
+		if negative {
+			value = value[1:]
+		}
+		if len(value) != 1 || value[0].kind != pl_token_string {
+			p.issue("无法转换的INTERVAL表达式, 复合单位的值需要是字符串常量: %s", renderPLTokens(tokens[start:unitPos+1]))
+			return nil, 0, false
+		}
+		n, smallest, ok := parseMySQLInterval(value[0].text, unit)
+		if !ok {
+			p.issue("无法转换的INTERVAL表达式: %s", renderPLTokens(tokens[start:unitPos+1]))
+			return nil, 0, false
+		}
+		if negative != strings.HasPrefix(strings.TrimSpace(value[0].text), "-") {
+			n = -n
+		}
+		value = []plToken{word(strconv.Itoa(n))}
+		unit = smallest
+	}
+	target, ok := plIntervalUnits[unit]
+	if !ok {
+		p.issue("不支持的INTERVAL单位: %s", renderPLTokens(tokens[start:unitPos+1]))
+		return nil, 0, false
+	}
+	out := []plToken{word(target.function), symbol("(")}
+	if len(target.factor) != 0 {
+		// 单个值和已有括号的表达式不需要再加括号
+		_, closing, _ := splitPLArgs(value, 0)
+		wrap := len(value) > 1 && !(value[0].isSymbol("(") && closing == len(value)-1)
+		if wrap {
+			out = append(out, symbol("("))
+		}
+		out = append(out, p.translateTokens(value)...)
+		if wrap {
+			out = append(out, symbol(")"))
+		}
+		out = append(out, symbol(target.factor[0]), word(target.factor[1]))
+	} else {
+		out = append(out, p.translateTokens(value)...)
+	}
+	out = append(out, symbol(","), plToken{kind: pl_token_string, text: target.unit}, symbol(")"))
+	return out, unitPos, true
+}
+
+// isMySQLIntervalExpr start位置的INTERVAL是时间间隔而不是INTERVAL()函数时返回true,
+// INTERVAL后面是括号时, 括号后面是单位的为时间间隔, 如INTERVAL (n + 1) DAY
+func isMySQLIntervalExpr(tokens []plToken, start int) bool {
+	if start+1 >= len(tokens) || !tokens[start+1].isSymbol("(") {
+		return true
+	}
+	_, end, ok := splitPLArgs(tokens, start+1)
+	return ok && end+1 < len(tokens) && isMySQLIntervalUnit(tokens[end+1])
+}
+
+// isMySQLIntervalUnit 词法单元是INTERVAL的单位时返回true, 包括YashanDB无法换算的带MICROSECOND的复合单位
+func isMySQLIntervalUnit(t plToken) bool {
+	if t.kind != pl_token_word {
+		return false
+	}
+	unit := strings.ToUpper(t.text)
+	if _, ok := plIntervalUnits[unit]; ok {
+		return true
+	}
+	if _, ok := mysqlCompoundIntervals[unit]; ok {
+		return true
+	}
+	return strings.HasSuffix(unit, "_MICROSECOND")
+}
+
+// translateGroupConcat GROUP_CONCAT([DISTINCT] expr, ... [ORDER BY ...] [SEPARATOR sep])转换为
+// LISTAGG(expr, sep) WITHIN GROUP (ORDER BY ...), 没有ORDER BY时按expr排序
+func (p *plTranslator) translateGroupConcat(args [][]plToken) ([]plToken, bool) {
+	word := func(text string) plToken { return plToken{kind: pl_token_word, text: text} }
+	symbol := func(text string) plToken { return plToken{kind: pl_token_symbol, text: text} }
+	var tokens []plToken
+	for i, arg := range args {
+		if i > 0 {
+			tokens = append(tokens, symbol(","))
+		}
+		tokens = append(tokens, arg...)
+	}
+	distinct := len(tokens) > 0 && tokens[0].is("DISTINCT")
+	if distinct {
+		p.issue("GROUP_CONCAT(DISTINCT ...) 需要确认YashanDB的LISTAGG是否支持DISTINCT")
+		tokens = tokens[1:]
+	}
+	// MySQL的语法中ORDER BY在SEPARATOR之前
+	orderStart, separatorStart := -1, -1
+	depth := 0
+	for i, t := range tokens {
+		switch {
+		case t.isSymbol("("):
+			depth++
+		case t.isSymbol(")"):
+			depth--
+		case depth == 0 && t.is("ORDER") && i+1 < len(tokens) && tokens[i+1].is("BY"):
+			orderStart = i
+		case depth == 0 && t.is("SEPARATOR"):
+			separatorStart = i
+		}
+	}
+	exprEnd, orderEnd := len(tokens), len(tokens)
+	separator := []plToken{{kind: pl_token_string, text: ","}}
+	if separatorStart >= 0 {
+		exprEnd, orderEnd = separatorStart, separatorStart
+		separator = tokens[separatorStart+1:]
+	}
+	if orderStart >= 0 {
+		exprEnd = orderStart
+	}
+	if exprEnd == 0 {
+		return nil, false
+	}
+	var exprs []plToken
+	for i, expr := range splitPLTokens(tokens[:exprEnd], ",") {
+		if i > 0 {
+			exprs = append(exprs, symbol("||"))
+		}
+		exprs = append(exprs, p.translateTokens(expr)...)
+	}
+	order := exprs
+	if orderStart >= 0 {
+		order = p.translateTokens(tokens[orderStart+2 : orderEnd])
+	}
+	out := []plToken{word("LISTAGG"), symbol("(")}
+	if distinct {
+		out = append(out, word("DISTINCT"))
+	}
+	out = append(out, exprs...)
+	out = append(out, symbol(","))
+	out = append(out, p.translateTokens(separator)...)
+	out = append(out, symbol(")"), word("WITHIN"), word("GROUP"), symbol("("), word("ORDER"), word("BY"))
+	out = append(out, order...)
+	return append(out, symbol(")")), true
+}
+
+// translateCast 转换CAST(expr AS type)中的数据类型, SIGNED、UNSIGNED转换为整数, CHAR转换为VARCHAR
+func (p *plTranslator) translateCast(arg []plToken) ([]plToken, bool) {
+	as := -1
+	depth := 0
+	for i, t := range arg {
+		switch {
+		case t.isSymbol("("):
+			depth++
+		case t.isSymbol(")"):
+			depth--
+		case depth == 0 && t.is("AS"):
+			as = i
+		}
+	}
+	if as <= 0 || as == len(arg)-1 {
+		return nil, false
+	}
+	typeTokens := arg[as+1:]
+	var yasType string
+	switch {
+	case typeTokens[0].is("SIGNED"):
+		yasType = strings.ToUpper(typedef.Y_BIGINT)
+	case typeTokens[0].is("UNSIGNED"):
+		yasType = strings.ToUpper(typedef.Y_NUMBER)
+	case typeTokens[0].is("CHAR", "NCHAR"):
+		yasType = "VARCHAR(4000)"
+		if len(typeTokens) >= 4 && typeTokens[1].isSymbol("(") {
+			yasType = "VARCHAR(" + typeTokens[2].text + ")"
+		}
+	default:
+		yasType = p.translateType(typeTokens)
+	}
+	out := []plToken{{kind: pl_token_word, text: "CAST"}, {kind: pl_token_symbol, text: "("}}
+	out = append(out, p.translateTokens(arg[:as])...)
+	out = append(out, plToken{kind: pl_token_word, text: "AS"}, plToken{kind: pl_token_word, text: yasType})
+	return append(out, plToken{kind: pl_token_symbol, text: ")"}), true
+}
+
+// translateDateFormat 将MySQL的日期格式转换为YashanDB的格式, 格式中的字母原样输出时需要加双引号
+func translateDateFormat(format string) (string, bool) {
+	var sb strings.Builder
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() == 0 {
+			return
+		}
+		text := literal.String()
+		if strings.IndexFunc(text, unicode.IsLetter) >= 0 {
+			text = "\"" + text + "\""
+		}
+		sb.WriteString(text)
+		literal.Reset()
+	}
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 == len(format) {
+			literal.WriteByte(format[i])
+			continue
+		}
+		i++
+		if format[i] == '%' {
+			literal.WriteByte('%')
+			continue
+		}
+		yasFormat, ok := mysqlDateFormats[format[i]]
+		if !ok {
+			return "", false
+		}
+		flush()
+		sb.WriteString(yasFormat)
+	}
+	flush()
+	return sb.String(), true
+}
+
+// identifier 转换标识符, 规则与导出表结构时相同
+func (p *plTranslator) identifier(t plToken) string {
+	if t.kind != pl_token_ident {
+		return formatKeyWord(t.text)
+	}
+	if confdef.GetM2YConfig().Yashan.CaseSensitive {
+		return "\"" + t.text + "\""
+	}
+	return "\"" + strings.ToUpper(t.text) + "\""
+}
+
+// translateType 转换变量和参数的数据类型, 忽略字符集、排序规则和整数的显示宽度
+func (p *plTranslator) translateType(tokens []plToken) string {
+	if len(tokens) == 0 {
+		p.issue("缺少数据类型")
+		return ""
+	}
+	mysqlType := strings.ToLower(tokens[0].text)
+	typeWords := 1
+	// 两个单词的类型, 如DOUBLE PRECISION、CHARACTER VARYING、NATIONAL CHAR、LONG VARCHAR
+	if len(tokens) > 1 && tokens[0].is("DOUBLE", "CHARACTER", "NATIONAL", "LONG") && tokens[1].is("PRECISION", "VARYING", "CHAR", "VARCHAR", "VARBINARY") {
+		mysqlType += " " + strings.ToLower(tokens[1].text)
+		typeWords = 2
+	}
+	var params []plToken
+	rest := tokens[typeWords:]
+	if len(rest) > 0 && rest[0].isSymbol("(") {
+		args, end, ok := splitPLArgs(tokens, typeWords)
+		if ok {
+			for i, arg := range args {
+				if i > 0 {
+					params = append(params, plToken{kind: pl_token_symbol, text: ","})
+				}
+				params = append(params, arg...)
+			}
+			rest = tokens[end+1:]
+		}
+	}
+	if len(rest) > 0 && rest[0].is("UNSIGNED") {
+		mysqlType += " unsigned"
+	}
+	// 与表的列使用相同的全局类型映射
+	dataType := strings.TrimSuffix(mysqlType, " unsigned")
+	columnType := dataType
+	if len(params) != 0 {
+		columnType += "(" + renderPLTokens(params) + ")"
+	}
+	if dataType != mysqlType {
+		columnType += " unsigned"
+	}
+	if yasType, ok := confdef.GetTypeMapping(dataType, columnType); ok {
+		return strings.ToUpper(yasType)
+	}
+	yasType, err := typedef.MySQLToYasType(mysqlType)
+	if err != nil {
+		p.issue("不支持的数据类型 %s", renderPLTokens(tokens))
+		return renderPLTokens(tokens)
+	}
+	yasType = strings.ToUpper(yasType)
+	switch yasType {
+	case strings.ToUpper(typedef.Y_VARCHAR), strings.ToUpper(typedef.Y_CHAR), strings.ToUpper(typedef.Y_NCHAR), strings.ToUpper(typedef.Y_NVARCHAR):
+		if mysqlType == typedef.M_ENUM || mysqlType == typedef.M_SET {
+			return fmt.Sprintf("%s(%d)", yasType, getPLEnumLength(params, mysqlType == typedef.M_SET))
+		}
+		if len(params) != 0 {
+			return yasType + "(" + renderPLTokens(params) + ")"
+		}
+	case strings.ToUpper(typedef.Y_NUMBER), strings.ToUpper(typedef.Y_RAW), strings.ToUpper(typedef.Y_BIT):
+		if len(params) != 0 {
+			return yasType + "(" + renderPLTokens(params) + ")"
+		}
+	}
+	return yasType
+}
+
+// getPLEnumLength ENUM取最长的元素长度, SET取所有元素和分隔符的总长度
+func getPLEnumLength(params []plToken, isSet bool) int {
+	var maxLength, totalLength int
+	for _, t := range params {
+		if t.kind != pl_token_string {
+			continue
+		}
+		n := len([]rune(t.text))
+		if n > maxLength {
+			maxLength = n
+		}
+		totalLength += n + 1
+	}
+	length := maxLength
+	if isSet {
+		length = totalLength
+	}
+	if length == 0 {
+		return 1
+	}
+	return length
+}
+
+// splitPLArgs 读取从open位置的左括号开始的参数列表, 返回各参数和右括号的位置
+func splitPLArgs(tokens []plToken, open int) ([][]plToken, int, bool) {
+	depth := 0
+	for i := open; i < len(tokens); i++ {
+		switch {
+		case tokens[i].isSymbol("("):
+			depth++
+		case tokens[i].isSymbol(")"):
+			depth--
+			if depth == 0 {
+				inner := tokens[open+1 : i]
+				if len(inner) == 0 {
+					return nil, i, true
+				}
+				return splitPLTokens(inner, ","), i, true
+			}
+		}
+	}
+	return nil, 0, false
+}
+
+// splitPLTokens 按括号之外的分隔符切分词法单元
+func splitPLTokens(tokens []plToken, separator string) [][]plToken {
+	var parts [][]plToken
+	depth, start := 0, 0
+	for i, t := range tokens {
+		switch {
+		case t.isSymbol("("):
+			depth++
+		case t.isSymbol(")"):
+			depth--
+		case depth == 0 && t.isSymbol(separator):
+			parts = append(parts, tokens[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, tokens[start:])
+}
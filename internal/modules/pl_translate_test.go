@@ -0,0 +1,250 @@
+package modules
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// plCase MySQL的表达式或存储程序, issues为期望的无法转换的原因
+type plCase struct {
+	source   string
+	expected string
+	issues   []string
+}
+
+func checkPLTranslated(t *testing.T, c plCase, actual string, issues []string) {
+	t.Helper()
+	if actual != c.expected {
+		t.Errorf("%s\n转换为\n%s\n期望\n%s", c.source, actual, c.expected)
+	}
+	if !reflect.DeepEqual(issues, c.issues) {
+		t.Errorf("%s 的问题为 %q, 期望 %q", c.source, issues, c.issues)
+	}
+}
+
+func TestTranslateSQLExpression(t *testing.T) {
+	cases := []plCase{
+		{source: "IFNULL(`amount`, 0) * 2", expected: `NVL("AMOUNT", 0) * 2`},
+		{source: "concat(_utf8mb4'a', `name`)", expected: `('a' || "NAME")`},
+		{source: "if((`qty` > 0), `price`, NULL)", expected: `CASE WHEN ("QTY" > 0) THEN "PRICE" ELSE NULL END`},
+		{source: "DATE_FORMAT(created, '%Y-%m-%d')", expected: "TO_CHAR(created, 'YYYY-MM-DD')"},
+		{source: "DATE(created) = CURDATE()", expected: "TRUNC(created) = TRUNC(SYSDATE)"},
+		{source: "created < NOW() - INTERVAL 30 DAY", expected: "created < SYSDATE - NUMTODSINTERVAL(30, 'DAY')"},
+		{source: "created + interval 1 year", expected: "created + NUMTOYMINTERVAL(1, 'YEAR')"},
+		{source: "created + INTERVAL 2 WEEK", expected: "created + NUMTODSINTERVAL(2 * 7, 'DAY')"},
+		{source: "created + INTERVAL (n + 1) QUARTER", expected: "created + NUMTOYMINTERVAL((n + 1) * 3, 'MONTH')"},
+		{source: "created + INTERVAL n + 1 MINUTE", expected: "created + NUMTODSINTERVAL(n + 1, 'MINUTE')"},
+		{source: "created + INTERVAL 500 MICROSECOND", expected: "created + NUMTODSINTERVAL(500 / 1000000, 'SECOND')"},
+		{source: "created + INTERVAL '1:30' HOUR_MINUTE", expected: "created + NUMTODSINTERVAL(90, 'MINUTE')"},
+		{source: "created + INTERVAL -'1 12' DAY_HOUR", expected: "created + NUMTODSINTERVAL(-36, 'HOUR')"},
+		{source: "created + INTERVAL '2-6' YEAR_MONTH", expected: "created + NUMTOYMINTERVAL(30, 'MONTH')"},
+		{source: "DATE_ADD(`created`, INTERVAL 1 MONTH)", expected: `("CREATED" + NUMTOYMINTERVAL(1, 'MONTH'))`},
+		{source: "DATE_SUB(created, INTERVAL '1:30' HOUR_MINUTE)", expected: "(created - NUMTODSINTERVAL(90, 'MINUTE'))"},
+		{source: "ADDDATE(created, 7)", expected: "(created + NUMTODSINTERVAL(7, 'DAY'))"},
+		{source: "SUBDATE(created, INTERVAL 1 HOUR)", expected: "(created - NUMTODSINTERVAL(1, 'HOUR'))"},
+		{source: "TIMESTAMPADD(MINUTE, 5, created)", expected: "(created + NUMTODSINTERVAL(5, 'MINUTE'))"},
+		// 无法转换的INTERVAL和MySQL特有的函数保留原样并记录原因
+		{source: "DATE_ADD(created, INTERVAL n DAY_HOUR)", expected: "DATE_ADD(created, INTERVAL n DAY_HOUR)",
+			issues: []string{"无法转换的INTERVAL表达式, 复合单位的值需要是字符串常量: INTERVAL n DAY_HOUR", "不支持函数 DATE_ADD"}},
+		{source: "created + INTERVAL '1.5' SECOND_MICROSECOND", expected: "created + INTERVAL '1.5' SECOND_MICROSECOND",
+			issues: []string{"不支持的INTERVAL单位: INTERVAL '1.5' SECOND_MICROSECOND"}},
+		{source: "INTERVAL(5, 1, 10)", expected: "INTERVAL(5, 1, 10)", issues: []string{"不支持函数 INTERVAL"}},
+		{source: "FROM_UNIXTIME(ts) + SUBSTRING_INDEX(a, ',', 1)", expected: "FROM_UNIXTIME(ts) + SUBSTRING_INDEX(a, ',', 1)",
+			issues: []string{"不支持函数 FROM_UNIXTIME", "不支持函数 SUBSTRING_INDEX"}},
+		{source: "UNIX_TIMESTAMP()", expected: "UNIX_TIMESTAMP()", issues: []string{"不支持函数 UNIX_TIMESTAMP"}},
+		{source: "a <=> b", expected: "a <=> b", issues: []string{"不支持运算符 <=>"}},
+	}
+	for _, c := range cases {
+		actual, issues := translateSQLExpression(c.source)
+		checkPLTranslated(t, c, actual, issues)
+	}
+}
+
+func TestTranslateTrigger(t *testing.T) {
+	trigger := mysqlTrigger{name: "trg_orders_bi", event: "INSERT", table: "orders", timing: "BEFORE"}
+	cases := []plCase{
+		{
+			source: "BEGIN\n  IF NEW.amount < 0 THEN\n    SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'amount';\n  END IF;\n" +
+				"  SET NEW.updated = NOW() + INTERVAL 1 HOUR;\nEND",
+			expected: "CREATE OR REPLACE TRIGGER SHOP.trg_orders_bi\nBEFORE INSERT ON SHOP.orders\nFOR EACH ROW\nBEGIN\n" +
+				"    IF :NEW.amount < 0 THEN\n        RAISE_APPLICATION_ERROR(-20000, 'amount');\n    END IF;\n" +
+				"    :NEW.updated := SYSDATE + NUMTODSINTERVAL(1, 'HOUR');\nEND;",
+		},
+		{
+			source: "SET NEW.created_at = FROM_UNIXTIME(NEW.ts)",
+			expected: "CREATE OR REPLACE TRIGGER SHOP.trg_orders_bi\nBEFORE INSERT ON SHOP.orders\nFOR EACH ROW\nBEGIN\n" +
+				"    :NEW.created_at := FROM_UNIXTIME(:NEW.ts);\nEND;",
+			issues: []string{"不支持函数 FROM_UNIXTIME"},
+		},
+	}
+	for _, c := range cases {
+		trigger.statement = c.source
+		actual, issues := translateTrigger(trigger, "SHOP")
+		checkPLTranslated(t, c, actual, issues)
+	}
+}
+
+func TestTranslateRoutine(t *testing.T) {
+	cases := []struct {
+		routine mysqlRoutine
+		plCase
+	}{
+		{
+			routine: mysqlRoutine{name: "purge_logs", routineType: "PROCEDURE"},
+			plCase: plCase{
+				source: "CREATE DEFINER=`root`@`%` PROCEDURE `purge_logs`(IN days INT)\nBEGIN\n  DECLARE done INT DEFAULT FALSE;\n" +
+					"  DECLARE v_id BIGINT;\n  DECLARE cur CURSOR FOR SELECT id FROM logs WHERE created < NOW() - INTERVAL days DAY;\n" +
+					"  DECLARE CONTINUE HANDLER FOR NOT FOUND SET done = TRUE;\n  OPEN cur;\n  read_loop: LOOP\n    FETCH cur INTO v_id;\n" +
+					"    IF done THEN\n      LEAVE read_loop;\n    END IF;\n    DELETE FROM logs WHERE id = v_id;\n  END LOOP;\n  CLOSE cur;\nEND",
+				expected: "CREATE OR REPLACE PROCEDURE SHOP.purge_logs(days IN BIGINT) AS\n    done BIGINT := 0;\n    v_id BIGINT;\n" +
+					"    CURSOR cur IS SELECT id FROM logs WHERE created < SYSDATE - NUMTODSINTERVAL(days, 'DAY');\nBEGIN\n    OPEN cur;\n" +
+					"    <<read_loop>>\n    LOOP\n        FETCH cur INTO v_id;\n        IF cur%NOTFOUND THEN\n            done := 1;\n        END IF;\n" +
+					"        IF done <> 0 THEN\n            EXIT read_loop;\n        END IF;\n        DELETE FROM logs WHERE id = v_id;\n" +
+					"    END LOOP read_loop;\n    CLOSE cur;\nEND;",
+			},
+		},
+		{
+			routine: mysqlRoutine{name: "order_total", routineType: "FUNCTION"},
+			plCase: plCase{
+				source: "CREATE DEFINER=`root`@`%` FUNCTION `order_total`(p_id BIGINT) RETURNS decimal(10,2)\n    READS SQL DATA\nBEGIN\n" +
+					"  DECLARE total DECIMAL(10,2);\n  SELECT IFNULL(SUM(amount), 0) INTO total FROM orders WHERE user_id = p_id;\n  RETURN total;\nEND",
+				expected: "CREATE OR REPLACE FUNCTION SHOP.order_total(p_id IN BIGINT) RETURN NUMBER AS\n    total NUMBER(10, 2);\nBEGIN\n" +
+					"    SELECT NVL(SUM(amount), 0) INTO total FROM orders WHERE user_id = p_id;\n    RETURN total;\nEND;",
+			},
+		},
+		{
+			routine: mysqlRoutine{name: "first_tag", routineType: "FUNCTION"},
+			plCase: plCase{
+				source: "CREATE FUNCTION `first_tag`(tags VARCHAR(255)) RETURNS varchar(255)\nRETURN SUBSTRING_INDEX(tags, ',', 1)",
+				expected: "CREATE OR REPLACE FUNCTION SHOP.first_tag(tags IN VARCHAR) RETURN VARCHAR AS\nBEGIN\n" +
+					"    RETURN SUBSTRING_INDEX(tags, ',', 1);\nEND;",
+				issues: []string{"不支持函数 SUBSTRING_INDEX"},
+			},
+		},
+	}
+	for _, c := range cases {
+		c.routine.definition = c.source
+		actual, issues := translateRoutine(c.routine, "SHOP")
+		checkPLTranslated(t, c.plCase, actual, issues)
+	}
+}
+
+func TestTranslateEvent(t *testing.T) {
+	cases := []struct {
+		event    mysqlEvent
+		contains []string
+		issues   []string
+	}{
+		{
+			event: mysqlEvent{name: "purge_logs", definition: "DELETE FROM logs WHERE created < NOW() - INTERVAL 30 DAY", eventType: "RECURRING",
+				intervalValue: sql.NullString{String: "1", Valid: true}, intervalField: sql.NullString{String: "DAY", Valid: true},
+				starts: sql.NullString{String: "2024-01-01 00:00:00", Valid: true}, status: "ENABLED", onCompletion: "PRESERVE", timeZone: "SYSTEM"},
+			contains: []string{
+				"job_name        => 'SHOP.purge_logs'",
+				"DELETE FROM logs WHERE created < SYSDATE - NUMTODSINTERVAL(30, ''DAY'');",
+				"start_date      => TIMESTAMP '2024-01-01 00:00:00'",
+				"repeat_interval => 'FREQ=DAILY;INTERVAL=1'",
+				"enabled         => TRUE",
+				"auto_drop       => FALSE",
+			},
+		},
+		{
+			event: mysqlEvent{name: "refresh", definition: "BEGIN\n  UPDATE stats SET ts = UNIX_TIMESTAMP();\nEND", eventType: "RECURRING",
+				intervalValue: sql.NullString{String: "1:30", Valid: true}, intervalField: sql.NullString{String: "HOUR_MINUTE", Valid: true},
+				status: "DISABLED", onCompletion: "NOT PRESERVE", timeZone: "SYSTEM"},
+			contains: []string{
+				"UPDATE stats SET ts = UNIX_TIMESTAMP();",
+				"repeat_interval => 'FREQ=MINUTELY;INTERVAL=90'",
+				"enabled         => FALSE",
+				"auto_drop       => TRUE",
+			},
+			issues: []string{"不支持函数 UNIX_TIMESTAMP"},
+		},
+		{
+			event: mysqlEvent{name: "once", definition: "DELETE FROM logs", eventType: "ONE TIME",
+				executeAt: sql.NullString{String: "2024-06-01 12:00:00", Valid: true}, status: "ENABLED", onCompletion: "NOT PRESERVE", timeZone: "SYSTEM"},
+			contains: []string{
+				"start_date      => TIMESTAMP '2024-06-01 12:00:00'",
+				"repeat_interval => NULL",
+			},
+		},
+		{
+			event: mysqlEvent{name: "tick", definition: "DELETE FROM logs", eventType: "RECURRING",
+				intervalValue: sql.NullString{String: "10", Valid: true}, intervalField: sql.NullString{String: "MICROSECOND", Valid: true},
+				status: "ENABLED", onCompletion: "PRESERVE", timeZone: "SYSTEM"},
+			issues: []string{"不支持的事件间隔单位: EVERY '10' MICROSECOND"},
+		},
+	}
+	for _, c := range cases {
+		ddl, issues := translateEvent(c.event, "SHOP")
+		for _, s := range c.contains {
+			if !strings.Contains(ddl, s) {
+				t.Errorf("事件 %s 转换为\n%s\n缺少 %s", c.event.name, ddl, s)
+			}
+		}
+		if !reflect.DeepEqual(issues, c.issues) {
+			t.Errorf("事件 %s 的问题为 %q, 期望 %q", c.event.name, issues, c.issues)
+		}
+	}
+}
+
+func TestGetEventRepeatInterval(t *testing.T) {
+	cases := []struct {
+		value, field, expected string
+	}{
+		{"1", "DAY", "'FREQ=DAILY;INTERVAL=1'"},
+		{"2", "QUARTER", "'FREQ=MONTHLY;INTERVAL=6'"},
+		{"1:30", "HOUR_MINUTE", "'FREQ=MINUTELY;INTERVAL=90'"},
+		{"12:00:00", "DAY_SECOND", "'FREQ=SECONDLY;INTERVAL=43200'"},
+		{"1-6", "year_month", "'FREQ=MONTHLY;INTERVAL=18'"},
+		{"0", "DAY", ""},
+		{"1:2:3", "HOUR_MINUTE", ""},
+	}
+	for _, c := range cases {
+		actual, err := getEventRepeatInterval(c.value, c.field)
+		if len(c.expected) == 0 {
+			if err == nil {
+				t.Errorf("EVERY '%s' %s 转换为 %s, 期望返回错误", c.value, c.field, actual)
+			}
+			continue
+		}
+		if err != nil || actual != c.expected {
+			t.Errorf("EVERY '%s' %s 转换为 %s %v, 期望 %s", c.value, c.field, actual, err, c.expected)
+		}
+	}
+}
+
+func TestTranslateViewDefinition(t *testing.T) {
+	viewNames := []string{"orders", "v_other"}
+	cases := []struct {
+		plCase
+		depends []string
+	}{
+		{
+			plCase: plCase{
+				source: "select `shop`.`orders`.`id` AS `id` from `shop`.`orders` where (`shop`.`orders`.`created` > (now() - interval 7 day)) " +
+					"order by `shop`.`orders`.`id` limit 10",
+				expected: `select SHOP."ORDERS"."ID" AS "ID" from SHOP."ORDERS" where (SHOP."ORDERS"."CREATED" > (SYSDATE - NUMTODSINTERVAL(7, 'DAY'))) ` +
+					`order by SHOP."ORDERS"."ID" FETCH FIRST 10 ROWS ONLY`,
+			},
+			depends: []string{"orders"},
+		},
+		{
+			plCase: plCase{
+				source:   "select `v_other`.`id` AS `id` from `shop`.`v_other` where `v_other`.`x` = substring_index(`v_other`.`y`, ',', 1)",
+				expected: `select "V_OTHER"."ID" AS "ID" from SHOP."V_OTHER" where "V_OTHER"."X" = substring_index("V_OTHER"."Y", ',', 1)`,
+				issues:   []string{"不支持函数 SUBSTRING_INDEX"},
+			},
+			depends: []string{"v_other"},
+		},
+	}
+	for _, c := range cases {
+		actual, depends, issues := translateViewDefinition(c.source, "shop", viewNames)
+		checkPLTranslated(t, c.plCase, actual, issues)
+		if !reflect.DeepEqual(depends, c.depends) {
+			t.Errorf("%s 依赖的视图为 %q, 期望 %q", c.source, depends, c.depends)
+		}
+	}
+}
@@ -0,0 +1,385 @@
+package modules
+
+import (
+	"database/sql"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"m2y/defs/confdef"
+	"m2y/defs/sqldef"
+	"m2y/defs/typedef"
+	"m2y/log"
+)
+
+// numericRange 数值类型可以保存的最小值和最大值, scale为小数位数
+type numericRange struct {
+	min   *big.Rat
+	max   *big.Rat
+	scale int
+}
+
+// contains r的范围是否包含other
+func (r *numericRange) contains(other *numericRange) bool {
+	return other != nil && r.min.Cmp(other.min) <= 0 && r.max.Cmp(other.max) >= 0
+}
+
+var (
+	numericPrecisionRegexp = regexp.MustCompile(`\(\s*(\d+)\s*(?:,\s*(\d+)\s*)?\)`)
+
+	// MySQL整数类型的范围
+	mysqlIntegerRanges = map[string][2]string{
+		"tinyint":            {"-128", "127"},
+		"tinyint unsigned":   {"0", "255"},
+		"smallint":           {"-32768", "32767"},
+		"smallint unsigned":  {"0", "65535"},
+		"mediumint":          {"-8388608", "8388607"},
+		"mediumint unsigned": {"0", "16777215"},
+		"int":                {"-2147483648", "2147483647"},
+		"int unsigned":       {"0", "4294967295"},
+		"bigint":             {"-9223372036854775808", "9223372036854775807"},
+		"bigint unsigned":    {"0", "18446744073709551615"},
+	}
+
+	// YashanDB整数类型的范围
+	yashanIntegerRanges = map[string][2]string{
+		"tinyint":          {"-128", "127"},
+		typedef.Y_SMALLINT: {"-32768", "32767"},
+		typedef.Y_INTEGER:  {"-2147483648", "2147483647"},
+		"int":              {"-2147483648", "2147483647"},
+		typedef.Y_BIGINT:   {"-9223372036854775808", "9223372036854775807"},
+	}
+)
+
+func newIntegerRange(bounds [2]string) *numericRange {
+	r := &numericRange{min: new(big.Rat), max: new(big.Rat)}
+	r.min.SetString(bounds[0])
+	r.max.SetString(bounds[1])
+	return r
+}
+
+// newDecimalRange 精度为precision、小数位数为scale的定点数的范围, 即±(10^precision - 1) / 10^scale
+func newDecimalRange(precision, scale int64) *numericRange {
+	ten := big.NewInt(10)
+	upper := new(big.Int).Exp(ten, big.NewInt(precision), nil)
+	upper.Sub(upper, big.NewInt(1))
+	r := &numericRange{max: new(big.Rat).SetFrac(upper, new(big.Int).Exp(ten, big.NewInt(scale), nil)), scale: int(scale)}
+	r.min = new(big.Rat).Neg(r.max)
+	return r
+}
+
+// getMySQLNumericRange 返回MySQL数值列可以保存的范围, 浮点数返回nil, 表示范围不受限制
+func getMySQLNumericRange(dataType, columnType string, precision, scale sql.NullInt64) *numericRange {
+	if bounds, ok := mysqlIntegerRanges[typedef.NormalizeMySQLType(columnType)]; ok {
+		return newIntegerRange(bounds)
+	}
+	switch strings.ToLower(dataType) {
+	case typedef.M_DECIMAL, typedef.M_NUMERIC:
+		if precision.Valid && scale.Valid {
+			r := newDecimalRange(precision.Int64, scale.Int64)
+			if strings.Contains(strings.ToLower(columnType), "unsigned") {
+				r.min = new(big.Rat)
+			}
+			return r
+		}
+	}
+	return nil
+}
+
+// getYashanNumericRange 返回YashanDB类型可以保存的范围, 没有精度的number、浮点数和非数值类型返回false
+func getYashanNumericRange(yasType string) (*numericRange, bool) {
+	baseName := getYashanTypeBaseName(yasType)
+	if bounds, ok := yashanIntegerRanges[baseName]; ok {
+		return newIntegerRange(bounds), true
+	}
+	switch baseName {
+	case typedef.Y_NUMBER, "decimal", "numeric":
+		matches := numericPrecisionRegexp.FindStringSubmatch(yasType)
+		if matches == nil {
+			return nil, false
+		}
+		precision, _ := strconv.ParseInt(matches[1], 10, 64)
+		var scale int64
+		if len(matches[2]) != 0 {
+			scale, _ = strconv.ParseInt(matches[2], 10, 64)
+		}
+		return newDecimalRange(precision, scale), true
+	}
+	return nil, false
+}
+
+// getPrecheckTargetType 返回列在YashanDB中的类型, 与导出DDL时一致, 精度超过上限时按截断后的类型检查
+func getPrecheckTargetType(mysqlSchema, tableName, columnName, dataType, columnType string, precision, scale sql.NullInt64) (string, error) {
+	yasType, err := getYashanColumnType(mysqlSchema, tableName, columnName, dataType, columnType)
+	if err != nil {
+		return "", err
+	}
+	if yasType != typedef.Y_NUMBER || !precision.Valid || !scale.Valid {
+		return yasType, nil
+	}
+	if precision.Int64 > sqldef.Y_MAX_NUMERIC_PRECISION {
+		if confdef.GetM2YConfig().Yashan.DecimalOverflow == confdef.DECIMAL_OVERFLOW_VARCHAR {
+			return fmt.Sprintf(sqldef.Y_CHAR_FORMAT, typedef.Y_VARCHAR, precision.Int64+2), nil
+		}
+		precision.Int64 = sqldef.Y_MAX_NUMERIC_PRECISION
+	}
+	return fmt.Sprintf(sqldef.Y_FLOAT_FORMAT, yasType, precision.Int64, scale.Int64), nil
+}
+
+// precheckNumericColumns 检查表中数值列的数据是否超出YashanDB中对应类型的范围,
+// 只查询MySQL类型的范围大于YashanDB类型的列, 返回超出范围的列及行数
+func precheckNumericColumns(mysql *sql.DB, mysqlSchema, tableName string) ([][]string, error) {
+	rows, err := mysql.Query(sqldef.M_SQL_QUERY_NUMERIC_COLUMNS, mysqlSchema, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("查询表属性 information_schema.columns 出错: %v", err)
+	}
+	type numericColumn struct {
+		name, columnType, yasType string
+		target                    *numericRange
+	}
+	var columns []numericColumn
+	for rows.Next() {
+		var (
+			columnName, dataType, columnType string
+			precision, scale                 sql.NullInt64
+		)
+		if err := rows.Scan(&columnName, &dataType, &columnType, &precision, &scale); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("查询表属性 information_schema.columns 出错: %v", err)
+		}
+		yasType, err := getPrecheckTargetType(mysqlSchema, tableName, columnName, dataType, columnType, precision, scale)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		target, ok := getYashanNumericRange(yasType)
+		if !ok || target.contains(getMySQLNumericRange(dataType, columnType, precision, scale)) {
+			continue
+		}
+		columns = append(columns, numericColumn{name: columnName, columnType: columnType, yasType: yasType, target: target})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询表属性 information_schema.columns 出错: %v", err)
+	}
+	var results [][]string
+	for _, column := range columns {
+		query := fmt.Sprintf(sqldef.M_SQL_QUERY_OVERFLOW_COUNT, mysqlSchema, tableName,
+			column.name, column.target.min.FloatString(column.target.scale), column.name, column.target.max.FloatString(column.target.scale))
+		var count int64
+		if err := mysql.QueryRow(query).Scan(&count); err != nil {
+			return nil, fmt.Errorf("查询列 %s.%s.%s 超出范围的数据出错: %v", mysqlSchema, tableName, column.name, err)
+		}
+		if count > 0 {
+			results = append(results, []string{mysqlSchema, tableName, column.name, column.columnType, column.yasType, strconv.FormatInt(count, 10), "超出YashanDB类型的范围"})
+		}
+	}
+	return results, nil
+}
+
+// precheckTemporalColumns 检查表中的零值日期, 以及目标端为TIME时超出一天范围的TIME值
+func precheckTemporalColumns(mysql *sql.DB, mysqlSchema, tableName string) ([][]string, error) {
+	rows, err := mysql.Query(sqldef.M_SQL_QUERY_TEMPORAL_COLUMNS, mysqlSchema, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("查询表属性 information_schema.columns 出错: %v", err)
+	}
+	type temporalColumn struct {
+		name, dataType, columnType, yasType string
+	}
+	var columns []temporalColumn
+	for rows.Next() {
+		var column temporalColumn
+		if err := rows.Scan(&column.name, &column.dataType, &column.columnType); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("查询表属性 information_schema.columns 出错: %v", err)
+		}
+		if column.yasType, err = getYashanColumnType(mysqlSchema, tableName, column.name, column.dataType, column.columnType); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询表属性 information_schema.columns 出错: %v", err)
+	}
+	var results [][]string
+	for _, column := range columns {
+		var query, reason string
+		switch {
+		case column.dataType == typedef.M_TIME && getYashanTypeBaseName(column.yasType) == typedef.Y_TIME:
+			query = fmt.Sprintf(sqldef.M_SQL_QUERY_TIME_OVERFLOW_COUNT, mysqlSchema, tableName, column.name, column.name)
+			reason = getTimeOverflowReason(mysqlSchema, tableName, column.name)
+		case column.dataType != typedef.M_TIME:
+			query = fmt.Sprintf(sqldef.M_SQL_QUERY_ZERO_DATE_COUNT, mysqlSchema, tableName, column.name, column.name)
+			reason = fmt.Sprintf("零值日期, 按zero_date=%s处理", confdef.GetM2YConfig().Yashan.ZeroDate)
+		default:
+			continue
+		}
+		var count int64
+		if err := mysql.QueryRow(query).Scan(&count); err != nil {
+			return nil, fmt.Errorf("查询列 %s.%s.%s 的数据出错: %v", mysqlSchema, tableName, column.name, err)
+		}
+		if count > 0 {
+			results = append(results, []string{mysqlSchema, tableName, column.name, column.columnType, column.yasType, strconv.FormatInt(count, 10), reason})
+		}
+	}
+	return results, nil
+}
+
+// getTimeOverflowReason YashanDB的TIME无法保存超出一天的值, 导出DDL和同步时不会自动改变列的类型,
+// 需要按列配置类型映射, 输出可以直接添加到配置文件中的规则
+func getTimeOverflowReason(mysqlSchema, tableName, columnName string) string {
+	return fmt.Sprintf("超出YashanDB TIME的范围, 需要在[[type_mapping.columns]]中配置 pattern = \"%s.%s.%s\", type = \"%s\"",
+		mysqlSchema, tableName, columnName, yashan_interval_type)
+}
+
+// precheckCharsetColumns 按原始字节读取字符列, 检查是否有在列声明的字符集中无效的字节,
+// 这些数据转换为UTF-8后会变成乱码或被替换为U+FFFD
+func precheckCharsetColumns(mysql *sql.DB, mysqlSchema, tableName string) ([][]string, error) {
+	rows, err := mysql.Query(sqldef.M_SQL_QUERY_CHARSET_COLUMNS, mysqlSchema, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("查询表属性 information_schema.columns 出错: %v", err)
+	}
+	type charsetColumn struct {
+		name, dataType, columnType, charset, yasType string
+	}
+	var (
+		columns []charsetColumn
+		selects []string
+	)
+	for rows.Next() {
+		var column charsetColumn
+		if err := rows.Scan(&column.name, &column.dataType, &column.columnType, &column.charset); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("查询表属性 information_schema.columns 出错: %v", err)
+		}
+		if !isCharsetCheckRequired(column.charset) {
+			continue
+		}
+		if column.yasType, err = getYashanColumnType(mysqlSchema, tableName, column.name, column.dataType, column.columnType); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		columns = append(columns, column)
+		selects = append(selects, fmt.Sprintf(sqldef.M_SQL_CAST_BINARY_FORMAT, column.name))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询表属性 information_schema.columns 出错: %v", err)
+	}
+	if len(columns) == 0 {
+		return nil, nil
+	}
+	values, err := mysql.Query(fmt.Sprintf(sqldef.M_SQL_QUERY_BINARY_VALUES, strings.Join(selects, ", "), mysqlSchema, tableName))
+	if err != nil {
+		return nil, fmt.Errorf("查询表 %s.%s 的字符数据出错: %v", mysqlSchema, tableName, err)
+	}
+	defer values.Close()
+	counts := make([]int64, len(columns))
+	raws := make([]sql.RawBytes, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range raws {
+		dest[i] = &raws[i]
+	}
+	for values.Next() {
+		if err := values.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("查询表 %s.%s 的字符数据出错: %v", mysqlSchema, tableName, err)
+		}
+		for i, raw := range raws {
+			if raw != nil && !isValidInCharset(raw, columns[i].charset) {
+				counts[i]++
+			}
+		}
+	}
+	if err := values.Err(); err != nil {
+		return nil, fmt.Errorf("查询表 %s.%s 的字符数据出错: %v", mysqlSchema, tableName, err)
+	}
+	var results [][]string
+	for i, column := range columns {
+		if counts[i] > 0 {
+			results = append(results, []string{mysqlSchema, tableName, column.name, column.columnType, column.yasType, strconv.FormatInt(counts[i], 10), fmt.Sprintf("字节在字符集%s中无效", column.charset)})
+		}
+	}
+	return results, nil
+}
+
+func PrecheckTables(mysql *sql.DB, mysqlSchema string, tables []string, parallel int) ([][]string, error) {
+	sts := []schemaTable{}
+	for _, table := range tables {
+		sts = append(sts, schemaTable{table: table, mysqlSchema: mysqlSchema})
+	}
+	return precheckTables(mysql, sts, parallel)
+}
+
+func PrecheckSchemas(mysql *sql.DB, mysqlSchemas, excludeTables []string, parallel int) ([][]string, error) {
+	mysqlDbs, err := getMySQLAllDbs(mysql)
+	if err != nil {
+		return nil, err
+	}
+	sts := []schemaTable{}
+	for _, mysqlSchema := range mysqlSchemas {
+		if !inArrayStr(mysqlSchema, mysqlDbs) {
+			log.Logger.Errorf("MySQL Database %s 不存在,请检查配置文件或MySQL环境\n", mysqlSchema)
+			continue
+		}
+		tables, err := getMySQLSchemaTables(mysql, mysqlSchema)
+		if err != nil {
+			return nil, err
+		}
+		for _, table := range tables {
+			if containsString(excludeTables, table) {
+				continue
+			}
+			sts = append(sts, schemaTable{mysqlSchema: mysqlSchema, table: table})
+		}
+	}
+	return precheckTables(mysql, sts, parallel)
+}
+
+func precheckTables(mysql *sql.DB, tables []schemaTable, parallel int) ([][]string, error) {
+	var (
+		results [][]string
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+	)
+	if len(tables) < parallel {
+		parallel = len(tables)
+	}
+	semaphore := make(chan bool, parallel)
+	for _, st := range tables {
+		wg.Add(1)
+		semaphore <- true
+		go func(mysqlSchema, tableName string) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+			log.Logger.Infof("开始检查 MySQL表 %s.%s ...\n", mysqlSchema, tableName)
+			for _, precheck := range []func(*sql.DB, string, string) ([][]string, error){precheckNumericColumns, precheckTemporalColumns, precheckCharsetColumns} {
+				result, err := precheck(mysql, mysqlSchema, tableName)
+				if err != nil {
+					log.Logger.Errorf("MySQL表 %s.%s 检查失败: %v\n", mysqlSchema, tableName, err)
+					return
+				}
+				mu.Lock()
+				results = append(results, result...)
+				mu.Unlock()
+			}
+		}(st.mysqlSchema, st.table)
+	}
+	wg.Wait()
+	sort.SliceStable(results, func(i, j int) bool {
+		return strings.Join(results[i][:3], ".") < strings.Join(results[j][:3], ".")
+	})
+	return results, nil
+}
+
+func PrintPrecheckResults(results [][]string) {
+	header := []string{"MySQL-Database", "Table-Name", "Column-Name", "MySQL-Type", "YashanDB-Type", "Rows", "Reason"}
+	printTable("数据无法直接写入YashanDB的列如下：", header, results)
+}
@@ -0,0 +1,39 @@
+package modules
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"m2y/defs/sqldef"
+)
+
+// TestPrecheckTemporalColumns 超出TIME范围的列输出需要添加的[[type_mapping.columns]]规则
+func TestPrecheckTemporalColumns(t *testing.T) {
+	mysql, mysqlFake := newFakeDB(t)
+	mysqlFake.addRows(sqldef.M_SQL_QUERY_TEMPORAL_COLUMNS, []interface{}{"shop", "shifts"}, []string{"column_name", "data_type", "column_type"},
+		[]driver.Value{"started", "datetime", "datetime"},
+		[]driver.Value{"duration", "time", "time"},
+		[]driver.Value{"break_time", "time", "time(3)"},
+	)
+	count := []string{"COUNT(*)"}
+	mysqlFake.addRows(fmt.Sprintf(sqldef.M_SQL_QUERY_ZERO_DATE_COUNT, "shop", "shifts", "started", "started"), nil, count, []driver.Value{int64(0)})
+	mysqlFake.addRows(fmt.Sprintf(sqldef.M_SQL_QUERY_TIME_OVERFLOW_COUNT, "shop", "shifts", "duration", "duration"), nil, count, []driver.Value{int64(3)})
+	mysqlFake.addRows(fmt.Sprintf(sqldef.M_SQL_QUERY_TIME_OVERFLOW_COUNT, "shop", "shifts", "break_time", "break_time"), nil, count, []driver.Value{int64(0)})
+
+	results, err := precheckTemporalColumns(mysql, "shop", "shifts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unmatched := mysqlFake.getUnmatched(); len(unmatched) != 0 {
+		t.Fatalf("没有预设结果的查询: %v", unmatched)
+	}
+	expected := [][]string{
+		{"shop", "shifts", "duration", "time", "time", "3",
+			`超出YashanDB TIME的范围, 需要在[[type_mapping.columns]]中配置 pattern = "shop.shifts.duration", type = "interval day to second"`},
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("检查结果为 %q, 期望 %q", results, expected)
+	}
+}
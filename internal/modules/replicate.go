@@ -0,0 +1,684 @@
+package modules
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"m2y/defs/confdef"
+	"m2y/defs/sqldef"
+	"m2y/defs/typedef"
+	"m2y/log"
+
+	gomysql "github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	"golang.org/x/text/encoding"
+)
+
+const (
+	// 没有数据变更时保存binlog位置的最小间隔
+	position_save_interval = time.Second
+)
+
+// YashanDB中不能用等值条件比较的类型, 没有主键的表匹配行时跳过这些列
+var yasdbIncomparableDataType = map[string]struct{}{
+	"BLOB":        {},
+	"CLOB":        {},
+	"NCLOB":       {},
+	"JSON":        {},
+	"ST_GEOMETRY": {},
+}
+
+// ReplicateOptions replicate命令的参数
+type ReplicateOptions struct {
+	ServerID   uint32
+	UseGTID    bool
+	BinlogFile string // 不为空时应用本地binlog文件, 不连接MySQL
+}
+
+// replicateTargets 需要增量同步的表, 与sync的同步范围一致
+type replicateTargets struct {
+	schemas       map[string]string   // MySQL schema到YashanDB用户的映射
+	tables        map[string]struct{} // 按表同步时的表名, 为nil时同步schema下的所有表
+	excludeTables []string
+}
+
+// binlogColumn binlog中一列的类型信息
+type binlogColumn struct {
+	name       string
+	dataType   string   // information_schema.columns.data_type
+	typeName   string   // 与go-sql-driver的DatabaseTypeName一致的类型名, 用于convertValueFromMySQLToYashan
+	unsigned   bool     // binlog中的整数都按有符号数解析, 无符号列需要转换
+	elements   []string // enum或set的取值
+	generated  bool     // 生成列, binlog中有值, 但不能插入YashanDB的虚拟列
+	mappedType string   // [type_mapping]配置的YashanDB类型, 没有配置时为空
+	// 非UTF-8字符集的编码, binlog中的字符串是该字符集的原始字节, 为nil时不需要转码
+	encoding encoding.Encoding
+}
+
+// replicateTable 增量同步的一张表
+type replicateTable struct {
+	mysqlSchema  string
+	mysqlTable   string
+	yasdbSchema  string
+	yasdbTable   string
+	columns      []binlogColumn
+	yasdbColumns []ColumnInfo // 不包括生成列, 与convertRow返回的值一一对应
+	hasKey       bool
+	matchIndexes []int // 匹配行时使用的列, 有主键时为主键列, 否则为所有可比较的列
+	insertSQL    string
+}
+
+// binlogApplier 将binlog中的行变更应用到YashanDB, 每个MySQL事务对应一个YashanDB事务
+type binlogApplier struct {
+	mysql    *sql.DB
+	yasdb    *sql.DB
+	targets  *replicateTargets
+	pos      *binlogPosition
+	tables   map[string]*replicateTable
+	tx       *sql.Tx
+	rows     int64
+	lastSave time.Time
+}
+
+func ReplicateTableData(mysql, yasdb *sql.DB, mysqlSchema, yasdbSchema string, alltables []string, opts ReplicateOptions) error {
+	targets := &replicateTargets{
+		schemas: map[string]string{mysqlSchema: yasdbSchema},
+		tables:  make(map[string]struct{}),
+	}
+	for _, table := range alltables {
+		targets.tables[table] = struct{}{}
+	}
+	return replicate(mysql, yasdb, targets, opts)
+}
+
+func ReplicateSchemasData(mysql, yasdb *sql.DB, mysqlSchemas, yasdbSchemas []string, excludeTables []string, opts ReplicateOptions) error {
+	targets := &replicateTargets{
+		schemas:       make(map[string]string),
+		excludeTables: excludeTables,
+	}
+	for i, schema := range mysqlSchemas {
+		targets.schemas[schema] = yasdbSchemas[i]
+	}
+	return replicate(mysql, yasdb, targets, opts)
+}
+
+// replicate 从sync记录的binlog位置开始应用行变更, 收到SIGINT或SIGTERM后回滚未提交的事务并保存位置
+func replicate(mysql, yasdb *sql.DB, targets *replicateTargets, opts ReplicateOptions) error {
+	pos, err := loadBinlogPosition()
+	if err != nil {
+		return err
+	}
+	a := &binlogApplier{
+		mysql:   mysql,
+		yasdb:   yasdb,
+		targets: targets,
+		pos:     pos,
+		tables:  make(map[string]*replicateTable),
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	start := time.Now()
+	if len(opts.BinlogFile) != 0 {
+		err = a.applyFile(ctx, opts.BinlogFile)
+	} else {
+		err = a.applyStream(ctx, opts)
+	}
+	a.rollback()
+	if saveErr := a.pos.save(); saveErr != nil {
+		log.Logger.Errorf("保存binlog位置失败: %v", saveErr)
+	}
+	log.Logger.Infof("增量同步结束, 共应用行变更: %d 耗时: %v 当前binlog位置 %s:%d", a.rows, time.Since(start), a.pos.File, a.pos.Pos)
+	return err
+}
+
+// applyFile 应用本地的binlog文件, 文件名与记录的位置一致时从记录的位置开始, 否则从文件开头开始
+func (a *binlogApplier) applyFile(ctx context.Context, binlogFile string) error {
+	offset := int64(4)
+	name := filepath.Base(binlogFile)
+	if name == a.pos.File {
+		offset = int64(a.pos.Pos)
+	} else {
+		log.Logger.Warnf("binlog文件 %s 与记录的位置 %s:%d 不一致, 从文件开头开始应用", name, a.pos.File, a.pos.Pos)
+		a.pos.File = name
+		a.pos.Pos = 4
+	}
+	parser := replication.NewBinlogParser()
+	parser.SetParseTime(false)
+	parser.SetUseDecimal(false)
+	parser.SetTimestampStringLocation(queryMySQLTimeZone(a.mysql))
+	log.Logger.Infof("开始应用binlog文件 %s, 起始位置 %d", binlogFile, offset)
+	err := parser.ParseFile(binlogFile, offset, func(ev *replication.BinlogEvent) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return a.handleEvent(ev)
+	})
+	if ctx.Err() != nil {
+		log.Logger.Infof("收到退出信号, 停止增量同步")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("应用binlog文件 %s 失败: %v", binlogFile, err)
+	}
+	return nil
+}
+
+// applyStream 作为复制客户端连接MySQL, 持续应用binlog直到收到退出信号
+func (a *binlogApplier) applyStream(ctx context.Context, opts ReplicateOptions) error {
+	if err := checkBinlogFormat(a.mysql); err != nil {
+		return err
+	}
+	conf := confdef.GetM2YConfig().MySQL
+	syncer := replication.NewBinlogSyncer(replication.BinlogSyncerConfig{
+		ServerID:                opts.ServerID,
+		Flavor:                  gomysql.MySQLFlavor,
+		Host:                    conf.Host,
+		Port:                    uint16(conf.Port),
+		User:                    conf.UserName,
+		Password:                conf.Password,
+		TimestampStringLocation: queryMySQLTimeZone(a.mysql),
+	})
+	defer syncer.Close()
+	var streamer *replication.BinlogStreamer
+	var err error
+	if opts.UseGTID {
+		if len(a.pos.GTIDSet) == 0 {
+			return fmt.Errorf("记录的binlog位置中没有GTID, 请确认MySQL已开启gtid_mode, 或不使用--gtid参数")
+		}
+		gset, err := gomysql.ParseGTIDSet(gomysql.MySQLFlavor, a.pos.GTIDSet)
+		if err != nil {
+			return fmt.Errorf("解析GTID %s 失败: %v", a.pos.GTIDSet, err)
+		}
+		log.Logger.Infof("开始增量同步, 起始GTID: %s", a.pos.GTIDSet)
+		streamer, err = syncer.StartSyncGTID(gset)
+		if err != nil {
+			return fmt.Errorf("连接MySQL读取binlog失败: %v", err)
+		}
+	} else {
+		log.Logger.Infof("开始增量同步, 起始binlog位置 %s:%d", a.pos.File, a.pos.Pos)
+		streamer, err = syncer.StartSync(gomysql.Position{Name: a.pos.File, Pos: a.pos.Pos})
+		if err != nil {
+			return fmt.Errorf("连接MySQL读取binlog失败: %v", err)
+		}
+	}
+	for {
+		ev, err := streamer.GetEvent(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				log.Logger.Infof("收到退出信号, 停止增量同步")
+				return nil
+			}
+			return fmt.Errorf("读取binlog失败: %v", err)
+		}
+		if err := a.handleEvent(ev); err != nil {
+			return err
+		}
+	}
+}
+
+func (a *binlogApplier) handleEvent(ev *replication.BinlogEvent) error {
+	switch e := ev.Event.(type) {
+	case *replication.RotateEvent:
+		a.pos.File = string(e.NextLogName)
+		a.pos.Pos = uint32(e.Position)
+		return nil
+	case *replication.RowsEvent:
+		return a.applyRows(ev.Header.EventType, e)
+	case *replication.XIDEvent:
+		return a.commit(ev.Header.LogPos, e.GSet)
+	case *replication.QueryEvent:
+		switch strings.ToUpper(strings.TrimSpace(string(e.Query))) {
+		case "BEGIN":
+			return nil
+		case "COMMIT":
+			// 非事务引擎的表没有XID事件, 以COMMIT语句结束
+			return a.commit(ev.Header.LogPos, e.GSet)
+		}
+		if _, ok := a.targets.schemas[string(e.Schema)]; ok {
+			log.Logger.Warnf("增量同步不处理DDL, 请在YashanDB手动执行对应的变更, schema: %s sql: %s", e.Schema, e.Query)
+		}
+		// 表结构可能已变化, 重新加载列信息
+		a.tables = make(map[string]*replicateTable)
+		return a.commit(ev.Header.LogPos, e.GSet)
+	}
+	return nil
+}
+
+func (a *binlogApplier) applyRows(eventType replication.EventType, e *replication.RowsEvent) error {
+	mysqlSchema, mysqlTable := string(e.Table.Schema), string(e.Table.Table)
+	yasdbSchema, ok := a.targets.match(mysqlSchema, mysqlTable)
+	if !ok {
+		return nil
+	}
+	for _, skipped := range e.SkippedColumns {
+		if len(skipped) > 0 {
+			return fmt.Errorf("表 %s.%s 增量同步失败, binlog中缺少部分列, 请将binlog_row_image设置为FULL", mysqlSchema, mysqlTable)
+		}
+	}
+	t, err := a.getTable(mysqlSchema, mysqlTable, yasdbSchema)
+	if err != nil {
+		return fmt.Errorf("表 %s.%s 增量同步失败, 获取表结构失败: %v", mysqlSchema, mysqlTable, err)
+	}
+	if a.tx == nil {
+		a.tx, err = a.yasdb.Begin()
+		if err != nil {
+			return fmt.Errorf("表 %s.%s 增量同步失败, 事务开始失败: %v", mysqlSchema, mysqlTable, err)
+		}
+	}
+	switch eventType {
+	case replication.WRITE_ROWS_EVENTv0, replication.WRITE_ROWS_EVENTv1, replication.WRITE_ROWS_EVENTv2:
+		for _, row := range e.Rows {
+			if err := t.insert(a.tx, row); err != nil {
+				return fmt.Errorf("表 %s.%s 增量同步失败, 插入数据失败: %v", mysqlSchema, mysqlTable, err)
+			}
+			a.rows++
+		}
+	case replication.UPDATE_ROWS_EVENTv0, replication.UPDATE_ROWS_EVENTv1, replication.UPDATE_ROWS_EVENTv2:
+		// 更新事件中的行按更新前、更新后成对出现
+		for i := 0; i+1 < len(e.Rows); i += 2 {
+			if err := t.update(a.tx, e.Rows[i], e.Rows[i+1]); err != nil {
+				return fmt.Errorf("表 %s.%s 增量同步失败, 更新数据失败: %v", mysqlSchema, mysqlTable, err)
+			}
+			a.rows++
+		}
+	case replication.DELETE_ROWS_EVENTv0, replication.DELETE_ROWS_EVENTv1, replication.DELETE_ROWS_EVENTv2:
+		for _, row := range e.Rows {
+			if err := t.delete(a.tx, row); err != nil {
+				return fmt.Errorf("表 %s.%s 增量同步失败, 删除数据失败: %v", mysqlSchema, mysqlTable, err)
+			}
+			a.rows++
+		}
+	}
+	return nil
+}
+
+// commit 提交当前事务并更新binlog位置, 没有数据变更时按间隔保存位置
+func (a *binlogApplier) commit(logPos uint32, gset gomysql.GTIDSet) error {
+	changed := a.tx != nil
+	if a.tx != nil {
+		err := a.tx.Commit()
+		a.tx = nil
+		if err != nil {
+			return fmt.Errorf("增量同步失败, 事务提交失败: %v", err)
+		}
+	}
+	if logPos > 0 {
+		a.pos.Pos = logPos
+	}
+	if gset != nil {
+		a.pos.GTIDSet = gset.String()
+	}
+	if !changed && time.Since(a.lastSave) < position_save_interval {
+		return nil
+	}
+	a.lastSave = time.Now()
+	if err := a.pos.save(); err != nil {
+		return fmt.Errorf("保存binlog位置失败: %v", err)
+	}
+	return nil
+}
+
+func (a *binlogApplier) rollback() {
+	if a.tx == nil {
+		return
+	}
+	_ = a.tx.Rollback()
+	a.tx = nil
+}
+
+func (a *binlogApplier) getTable(mysqlSchema, mysqlTable, yasdbSchema string) (*replicateTable, error) {
+	name := checkpointTableKey(mysqlSchema, mysqlTable)
+	if t, ok := a.tables[name]; ok {
+		return t, nil
+	}
+	t, err := loadReplicateTable(a.mysql, a.yasdb, mysqlSchema, mysqlTable, yasdbSchema)
+	if err != nil {
+		return nil, err
+	}
+	a.tables[name] = t
+	return t, nil
+}
+
+func (t *replicateTargets) match(mysqlSchema, mysqlTable string) (string, bool) {
+	yasdbSchema, ok := t.schemas[mysqlSchema]
+	if !ok {
+		return "", false
+	}
+	if t.tables != nil {
+		if _, ok := t.tables[mysqlTable]; !ok {
+			return "", false
+		}
+	}
+	if inArrayStr(mysqlTable, t.excludeTables) {
+		return "", false
+	}
+	return yasdbSchema, true
+}
+
+// loadReplicateTable 查询表的列信息和主键, binlog中的列按MySQL表中列的顺序与YashanDB表的列一一对应
+func loadReplicateTable(mysql, yasdb *sql.DB, mysqlSchema, mysqlTable, yasdbSchema string) (*replicateTable, error) {
+	rows, err := mysql.Query(sqldef.M_SQL_QUERY_BINLOG_COLUMNS, mysqlSchema, mysqlTable)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	t := &replicateTable{
+		mysqlSchema: mysqlSchema,
+		mysqlTable:  mysqlTable,
+		yasdbSchema: yasdbSchema,
+		yasdbTable:  mysqlTable,
+	}
+	for rows.Next() {
+		var name, dataType, columnType, extra, charset string
+		if err := rows.Scan(&name, &dataType, &columnType, &extra, &charset); err != nil {
+			return nil, err
+		}
+		dataType = strings.ToLower(dataType)
+		column := binlogColumn{
+			name:      name,
+			dataType:  dataType,
+			typeName:  getBinlogTypeName(dataType),
+			unsigned:  strings.Contains(strings.ToLower(columnType), "unsigned"),
+			generated: isGeneratedColumn(extra),
+		}
+		if yasType, ok := confdef.GetColumnTypeMapping(mysqlSchema, mysqlTable, name, dataType, columnType); ok {
+			column.mappedType = yasType
+		}
+		if dataType == "enum" || dataType == "set" {
+			column.elements = parseMySQLEnumElements(columnType)
+		} else {
+			enc, ok := getMySQLCharsetEncoding(charset)
+			if !ok {
+				log.Logger.Warnf("表 %s.%s 列 %s 的字符集 %s 不支持转码, 增量同步时按原始字节写入", mysqlSchema, mysqlTable, name, charset)
+			}
+			column.encoding = enc
+		}
+		t.columns = append(t.columns, column)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	t.yasdbColumns, err = getYasdbColumns(yasdb, yasdbSchema, mysqlTable)
+	if err != nil {
+		return nil, err
+	}
+	if len(t.yasdbColumns) != len(t.columns) {
+		return nil, fmt.Errorf("源表列数 %d 与目标表列数 %d 不一致", len(t.columns), len(t.yasdbColumns))
+	}
+	var generatedColumns, columnNames []string
+	for _, column := range t.columns {
+		if column.generated {
+			generatedColumns = append(generatedColumns, column.name)
+			continue
+		}
+		columnNames = append(columnNames, column.name)
+	}
+	t.yasdbColumns = excludeGeneratedColumns(t.yasdbColumns, generatedColumns)
+	key, err := getMySQLTableKey(mysql, mysqlSchema, mysqlTable)
+	if err != nil {
+		return nil, err
+	}
+	if key != nil {
+		t.hasKey = true
+		for _, keyColumn := range key.Columns {
+			for i, column := range columnNames {
+				if column == keyColumn {
+					t.matchIndexes = append(t.matchIndexes, i)
+					break
+				}
+			}
+		}
+	} else {
+		log.Logger.Warnf("表 %s.%s 没有主键或非空唯一索引, 增量同步按所有列匹配要更新和删除的行, 重复应用插入事件会产生重复数据", mysqlSchema, mysqlTable)
+		for i, column := range t.yasdbColumns {
+			if _, ok := yasdbIncomparableDataType[column.ColumnType]; !ok {
+				t.matchIndexes = append(t.matchIndexes, i)
+			}
+		}
+	}
+	t.insertSQL = buildYashanInsertSQL(yasdbSchema, t.yasdbTable, t.yasdbColumns)
+	return t, nil
+}
+
+// insert 插入一行数据, 有主键时先按主键删除, 使重复应用全量同步期间的变更不会产生主键冲突
+func (t *replicateTable) insert(tx *sql.Tx, row []interface{}) error {
+	values, err := t.convertRow(row)
+	if err != nil {
+		return err
+	}
+	if t.hasKey {
+		if _, err := t.deleteRow(tx, values); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(t.insertSQL, bindYashanValues(t.yasdbColumns, values)...); err != nil {
+		return fmt.Errorf("sql: %s value: %v, err: %v", t.insertSQL, values, err)
+	}
+	return nil
+}
+
+// update 按更新前的值匹配行, 有主键且目标端不存在该行时插入更新后的行
+func (t *replicateTable) update(tx *sql.Tx, before, after []interface{}) error {
+	beforeValues, err := t.convertRow(before)
+	if err != nil {
+		return err
+	}
+	afterValues, err := t.convertRow(after)
+	if err != nil {
+		return err
+	}
+	var sets []string
+	for _, column := range t.yasdbColumns {
+		sets = append(sets, formatYashanColumnName(column.ColumnName)+" = "+getYashanPlaceholder(column))
+	}
+	condition, conditionArgs := t.buildCondition(beforeValues)
+	formatter := getSQLFormatter(sqldef.Y_SQL_UPDATE_DATA, sqldef.Y_SQL_UPDATE_DATA_CASE_SENSITIVE)
+	query := fmt.Sprintf(formatter, formatKeyWord(t.yasdbSchema), formatKeyWord(t.yasdbTable), strings.Join(sets, ", "), condition)
+	args := append(bindYashanValues(t.yasdbColumns, afterValues), conditionArgs...)
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("sql: %s value: %v, err: %v", query, args, err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 && t.hasKey {
+		if _, err := tx.Exec(t.insertSQL, bindYashanValues(t.yasdbColumns, afterValues)...); err != nil {
+			return fmt.Errorf("sql: %s value: %v, err: %v", t.insertSQL, afterValues, err)
+		}
+	}
+	return nil
+}
+
+func (t *replicateTable) delete(tx *sql.Tx, row []interface{}) error {
+	values, err := t.convertRow(row)
+	if err != nil {
+		return err
+	}
+	_, err = t.deleteRow(tx, values)
+	return err
+}
+
+func (t *replicateTable) deleteRow(tx *sql.Tx, values []interface{}) (sql.Result, error) {
+	condition, args := t.buildCondition(values)
+	formatter := getSQLFormatter(sqldef.Y_SQL_DELETE_DATA, sqldef.Y_SQL_DELETE_DATA_CASE_SENSITIVE)
+	query := fmt.Sprintf(formatter, formatKeyWord(t.yasdbSchema), formatKeyWord(t.yasdbTable), condition)
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("sql: %s value: %v, err: %v", query, args, err)
+	}
+	return result, nil
+}
+
+// buildCondition 构建匹配一行数据的条件, 没有主键时只匹配一行
+func (t *replicateTable) buildCondition(values []interface{}) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	for _, i := range t.matchIndexes {
+		columnName := formatYashanColumnName(t.yasdbColumns[i].ColumnName)
+		if values[i] == nil {
+			conditions = append(conditions, columnName+" IS NULL")
+			continue
+		}
+		conditions = append(conditions, columnName+" = ?")
+		args = append(args, values[i])
+	}
+	if !t.hasKey {
+		conditions = append(conditions, "ROWNUM = 1")
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// convertRow 将binlog中的一行转换为YashanDB的值, 与全量同步使用相同的类型转换
+func (t *replicateTable) convertRow(row []interface{}) ([]interface{}, error) {
+	if len(row) != len(t.columns) {
+		return nil, fmt.Errorf("binlog中的列数 %d 与表结构的列数 %d 不一致, 表结构可能已变更", len(row), len(t.columns))
+	}
+	values := make([]interface{}, 0, len(row))
+	for i, value := range row {
+		if t.columns[i].generated {
+			continue
+		}
+		value, err := t.columns[i].decode(value)
+		if err != nil {
+			return nil, fmt.Errorf("列 %s 的值转码失败: %v", t.columns[i].name, err)
+		}
+		converted, err := convertValueWithTypeMapping(t.columns[i].normalize(value), t.columns[i].typeName, t.columns[i].mappedType)
+		if err != nil {
+			return nil, fmt.Errorf("列 %s 的值转换失败: %v", t.columns[i].name, err)
+		}
+		values = append(values, converted)
+	}
+	return values, nil
+}
+
+// decode 将binlog中非UTF-8字符集的字符串转换为UTF-8, 全量同步时由MySQL按连接字符集转换
+func (c *binlogColumn) decode(value interface{}) (interface{}, error) {
+	if c.encoding == nil {
+		return value, nil
+	}
+	switch v := value.(type) {
+	case string:
+		return decodeMySQLCharset([]byte(v), c.encoding)
+	case []byte:
+		return decodeMySQLCharset(v, c.encoding)
+	}
+	return value, nil
+}
+
+// normalize 将binlog中解析出的值转换为go-sql-driver查询结果中的形式
+func (c *binlogColumn) normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return []byte(v)
+	case int8:
+		if c.unsigned {
+			return uint64(uint8(v))
+		}
+		return int64(v)
+	case int16:
+		if c.unsigned {
+			return uint64(uint16(v))
+		}
+		return int64(v)
+	case int32:
+		if c.unsigned {
+			if c.dataType == "mediumint" {
+				return uint64(uint32(v) & 0xFFFFFF)
+			}
+			return uint64(uint32(v))
+		}
+		return int64(v)
+	case int64:
+		switch c.dataType {
+		case "enum":
+			// enum按取值的序号记录, 从1开始, 0表示空字符串
+			if v > 0 && int(v) <= len(c.elements) {
+				return []byte(c.elements[v-1])
+			}
+			return []byte{}
+		case "set":
+			// set按位图记录
+			var elements []string
+			for i, element := range c.elements {
+				if v&(1<<uint(i)) != 0 {
+					elements = append(elements, element)
+				}
+			}
+			return []byte(strings.Join(elements, ","))
+		case "bit":
+			b := make([]byte, 8)
+			binary.BigEndian.PutUint64(b, uint64(v))
+			return b
+		}
+		if c.unsigned {
+			return uint64(v)
+		}
+		return v
+	}
+	return value
+}
+
+// getBinlogTypeName 返回与go-sql-driver的DatabaseTypeName一致的类型名, 空间类型的子类型都返回GEOMETRY
+func getBinlogTypeName(dataType string) string {
+	if yasType, err := typedef.MySQLToYasType(dataType); err == nil && yasType == typedef.Y_GEOMETRY {
+		return strings.ToUpper(typedef.M_GEOMETRY)
+	}
+	return strings.ToUpper(dataType)
+}
+
+// parseMySQLEnumElements 解析enum('a','b')或set('a','b')中的取值
+func parseMySQLEnumElements(columnType string) []string {
+	start, end := strings.Index(columnType, "("), strings.LastIndex(columnType, ")")
+	if start < 0 || end <= start {
+		return nil
+	}
+	var elements []string
+	var element strings.Builder
+	inQuote := false
+	s := columnType[start+1 : end]
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\'' && inQuote && i+1 < len(s) && s[i+1] == '\'':
+			element.WriteByte('\'')
+			i++
+		case s[i] == '\'':
+			if inQuote {
+				elements = append(elements, element.String())
+				element.Reset()
+			}
+			inQuote = !inQuote
+		case inQuote:
+			element.WriteByte(s[i])
+		}
+	}
+	return elements
+}
+
+// checkBinlogFormat 增量同步需要完整的行格式binlog
+func checkBinlogFormat(mysql *sql.DB) error {
+	var format, rowImage string
+	if err := mysql.QueryRow(sqldef.M_SQL_QUERY_BINLOG_FORMAT).Scan(&format, &rowImage); err != nil {
+		return fmt.Errorf("查询binlog格式出错: %v", err)
+	}
+	if !strings.EqualFold(format, "ROW") || !strings.EqualFold(rowImage, "FULL") {
+		return fmt.Errorf("增量同步需要binlog_format=ROW且binlog_row_image=FULL, 当前为binlog_format=%s binlog_row_image=%s", format, rowImage)
+	}
+	return nil
+}
+
+// queryMySQLTimeZone 查询MySQL会话时区相对UTC的偏移, binlog中TIMESTAMP记录为UTC时间,
+// 按会话时区格式化后与全量同步查询到的值一致
+func queryMySQLTimeZone(mysql *sql.DB) *time.Location {
+	var offset int
+	if err := mysql.QueryRow(sqldef.M_SQL_QUERY_TIME_ZONE_OFFSET).Scan(&offset); err != nil {
+		log.Logger.Warnf("查询MySQL时区失败, TIMESTAMP按本地时区处理: %v", err)
+		return time.Local
+	}
+	return time.FixedZone("mysql", offset)
+}
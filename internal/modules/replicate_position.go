@@ -0,0 +1,133 @@
+package modules
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"m2y/defs/runtimedef"
+	"m2y/defs/sqldef"
+	"m2y/log"
+	"m2y/utils/fileutil"
+
+	"git.yasdb.com/go/yasutil/fs"
+)
+
+const (
+	binlog_position_file = "binlog_position.json"
+)
+
+// binlogPosition 增量同步的binlog位置, 全量同步开始时记录, replicate每提交一个事务后更新
+type binlogPosition struct {
+	File      string `json:"file"`
+	Pos       uint32 `json:"pos"`
+	GTIDSet   string `json:"gtid_set,omitempty"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+func getBinlogPositionFileName() string {
+	return path.Join(runtimedef.GetCheckpointPath(), binlog_position_file)
+}
+
+// recordBinlogPosition 在全量同步开始时记录MySQL当前的binlog位置, 作为replicate的起点
+// 开启一致性快照时记录快照的位置, 断点续传时保留第一次同步开始时记录的位置
+func recordBinlogPosition(mysql *sql.DB, snapshot *consistentSnapshot, resume bool) {
+	fileName := getBinlogPositionFileName()
+	if resume && fileutil.IsExist(fileName) {
+		return
+	}
+	pos := snapshot.binlogPosition()
+	if pos == nil {
+		var err error
+		if pos, err = queryBinlogPosition(mysql); err != nil {
+			log.Logger.Warnf("记录binlog位置失败, 全量同步完成后无法使用replicate增量同步: %v", err)
+			return
+		}
+	}
+	if err := pos.save(); err != nil {
+		log.Logger.Warnf("记录binlog位置失败, 全量同步完成后无法使用replicate增量同步: %v", err)
+		return
+	}
+	log.Logger.Infof("已记录binlog位置 %s:%d gtid: %s", pos.File, pos.Pos, pos.GTIDSet)
+}
+
+// queryBinlogPosition 查询MySQL当前的binlog位置, MySQL 8.4起SHOW MASTER STATUS改为SHOW BINARY LOG STATUS
+func queryBinlogPosition(mysql mysqlQueryer) (*binlogPosition, error) {
+	rows, err := mysql.Query(sqldef.M_SQL_SHOW_MASTER_STATUS)
+	if err != nil {
+		rows, err = mysql.Query(sqldef.M_SQL_SHOW_BINARY_LOG_STATUS)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询binlog位置出错: %v", err)
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("查询binlog位置出错: %v", err)
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("查询binlog位置出错: %v", err)
+		}
+		return nil, fmt.Errorf("MySQL未开启binlog")
+	}
+	values := make([]sql.NullString, len(columns))
+	valuePointers := make([]interface{}, len(columns))
+	for i := range values {
+		valuePointers[i] = &values[i]
+	}
+	if err := rows.Scan(valuePointers...); err != nil {
+		return nil, fmt.Errorf("查询binlog位置出错: %v", err)
+	}
+	pos := &binlogPosition{}
+	for i, column := range columns {
+		switch column {
+		case "File":
+			pos.File = values[i].String
+		case "Position":
+			p, err := strconv.ParseUint(values[i].String, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("解析binlog位置 %s 出错: %v", values[i].String, err)
+			}
+			pos.Pos = uint32(p)
+		case "Executed_Gtid_Set":
+			// 多个GTID之间可能带有换行
+			pos.GTIDSet = strings.ReplaceAll(values[i].String, "\n", "")
+		}
+	}
+	return pos, nil
+}
+
+// loadBinlogPosition 读取保存的binlog位置
+func loadBinlogPosition() (*binlogPosition, error) {
+	fileName := getBinlogPositionFileName()
+	if !fileutil.IsExist(fileName) {
+		return nil, fmt.Errorf("binlog位置文件 %s 不存在, 请先执行sync完成全量同步", fileName)
+	}
+	data, err := fileutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	pos := &binlogPosition{}
+	if err := json.Unmarshal(data, pos); err != nil {
+		return nil, fmt.Errorf("解析binlog位置文件 %s 失败: %v", fileName, err)
+	}
+	return pos, nil
+}
+
+// save 先写临时文件并同步到磁盘再重命名, 避免进程或系统中断时位置文件损坏
+func (p *binlogPosition) save() error {
+	if err := fs.Mkdir(runtimedef.GetCheckpointPath()); err != nil {
+		return err
+	}
+	p.UpdatedAt = time.Now().Format("2006-01-02 15:04:05")
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteFileAtomic(getBinlogPositionFileName(), data)
+}
@@ -0,0 +1,318 @@
+package modules
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/binary"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"m2y/defs/sqldef"
+
+	gomysql "github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+const (
+	binlog_fixture_server_version = "8.0.36"
+	binlog_fixture_timestamp      = 1704135845 // 2024-01-02 03:04:05 +0800
+	binlog_fixture_table_id       = 101
+	binlog_fixture_other_table_id = 102
+)
+
+var binlogFixture = filepath.Join("testdata", "replicate", "mysql-bin.000001")
+
+// TestApplyBinlogFile 应用testdata中的binlog文件, 检查在YashanDB执行的语句和参数
+func TestApplyBinlogFile(t *testing.T) {
+	if *update {
+		if err := os.WriteFile(binlogFixture, buildBinlogFixture(t), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mysql, mysqlFake := newFakeDB(t)
+	yasdb, yasdbFake := newFakeDB(t)
+	mysqlFake.addRows(sqldef.M_SQL_QUERY_TIME_ZONE_OFFSET, nil, []string{"offset"}, []driver.Value{int64(28800)})
+	mysqlFake.addRows(sqldef.M_SQL_QUERY_BINLOG_COLUMNS, []interface{}{"shop", "orders"},
+		[]string{"column_name", "data_type", "column_type", "extra", "character_set_name"},
+		[]driver.Value{"id", "int", "int", "", ""},
+		[]driver.Value{"name", "varchar", "varchar(64)", "", "gbk"},
+		[]driver.Value{"created", "datetime", "datetime", "", ""},
+	)
+	mysqlFake.addRows(sqldef.M_SQL_QUERY_UNIQUE_KEYS_5, []interface{}{"shop", "orders"},
+		[]string{"index_name", "column_name", "data_type", "is_nullable", "expression"},
+		[]driver.Value{"PRIMARY", "id", "int", "NO", nil},
+	)
+	yasdbFake.addRows("select DATA_TYPE,COLUMN_NAME from all_tab_columns where owner='SHOP' and TABLE_NAME='ORDERS' order by COLUMN_ID", nil,
+		[]string{"DATA_TYPE", "COLUMN_NAME"},
+		[]driver.Value{"INTEGER", "ID"},
+		[]driver.Value{"VARCHAR", "NAME"},
+		[]driver.Value{"TIMESTAMP", "CREATED"},
+	)
+
+	a := &binlogApplier{
+		mysql:   mysql,
+		yasdb:   yasdb,
+		targets: &replicateTargets{schemas: map[string]string{"shop": "SHOP"}},
+		pos:     &binlogPosition{File: filepath.Base(binlogFixture), Pos: 4},
+		tables:  make(map[string]*replicateTable),
+	}
+	if err := a.applyFile(context.Background(), binlogFixture); err != nil {
+		t.Fatal(err)
+	}
+	if unmatched := append(mysqlFake.getUnmatched(), yasdbFake.getUnmatched()...); len(unmatched) != 0 {
+		t.Fatalf("没有预设结果的查询: %v", unmatched)
+	}
+
+	created := convertFixtureDatetime(t, "2024-01-02 03:04:05")
+	const (
+		deleteSQL = "DELETE FROM SHOP.orders WHERE ID = ?"
+		insertSQL = "INSERT INTO SHOP.orders ( ID,NAME,CREATED ) VALUES (?,?,?)"
+		updateSQL = "UPDATE SHOP.orders SET ID = ?, NAME = ?, CREATED = ? WHERE ID = ?"
+	)
+	expected := []fakeExec{
+		{Query: "BEGIN"},
+		{Query: deleteSQL, Args: []driver.Value{int64(1)}},
+		{Query: insertSQL, Args: []driver.Value{int64(1), "苹果", created}},
+		{Query: deleteSQL, Args: []driver.Value{int64(2)}},
+		{Query: insertSQL, Args: []driver.Value{int64(2), "pear", nil}},
+		{Query: "COMMIT"},
+		{Query: "BEGIN"},
+		{Query: updateSQL, Args: []driver.Value{int64(1), "香蕉", created, int64(1)}},
+		{Query: "COMMIT"},
+		{Query: "BEGIN"},
+		{Query: deleteSQL, Args: []driver.Value{int64(2)}},
+		{Query: "COMMIT"},
+	}
+	execs := yasdbFake.getExecs()
+	if len(execs) != len(expected) {
+		t.Fatalf("执行的语句数 %d, 期望 %d: %v", len(execs), len(expected), execs)
+	}
+	for i := range expected {
+		if normalizeFakeSQL(execs[i].Query) != expected[i].Query || !equalFakeArgs(execs[i].Args, expected[i].Args) {
+			t.Errorf("第%d条语句 %s %v, 期望 %s %v", i+1, execs[i].Query, execs[i].Args, expected[i].Query, expected[i].Args)
+		}
+	}
+	if a.tx != nil {
+		t.Errorf("应用结束后仍有未提交的事务")
+	}
+	if a.rows != 4 {
+		t.Errorf("应用的行变更 %d, 期望 4", a.rows)
+	}
+	stat, err := os.Stat(binlogFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.pos.File != filepath.Base(binlogFixture) || int64(a.pos.Pos) != stat.Size() {
+		t.Errorf("binlog位置 %s:%d, 期望 %s:%d", a.pos.File, a.pos.Pos, filepath.Base(binlogFixture), stat.Size())
+	}
+	saved, err := loadBinlogPosition()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.File != a.pos.File || saved.Pos != a.pos.Pos {
+		t.Errorf("保存的binlog位置 %s:%d, 期望 %s:%d", saved.File, saved.Pos, a.pos.File, a.pos.Pos)
+	}
+}
+
+// convertFixtureDatetime 按全量同步的方式转换DATETIME的值, 增量同步的结果应与之相同
+func convertFixtureDatetime(t *testing.T, value string) driver.Value {
+	t.Helper()
+	converted, err := convertValueFromMySQLToYashan([]byte(value), "DATETIME")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := driver.DefaultParameterConverter.ConvertValue(converted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func equalFakeArgs(args, expected []driver.Value) bool {
+	if len(args) == 0 && len(expected) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(args, expected)
+}
+
+// buildBinlogFixture 生成testdata中的binlog文件, 格式与MySQL 8.0在binlog_format=ROW、binlog_checksum=CRC32时一致:
+//
+//	BEGIN; INSERT INTO shop.orders VALUES (1, '苹果', '2024-01-02 03:04:05'), (2, 'pear', NULL); COMMIT;
+//	BEGIN; INSERT INTO other.audit VALUES (7); COMMIT;
+//	BEGIN; UPDATE shop.orders SET name = '香蕉' WHERE id = 1; COMMIT;
+//	BEGIN; DELETE FROM shop.orders WHERE id = 2; COMMIT;
+//
+// shop.orders的name列为gbk字符集
+func buildBinlogFixture(t *testing.T) []byte {
+	t.Helper()
+	gbk := func(s string) []byte {
+		b, err := simplifiedchinese.GBK.NewEncoder().Bytes([]byte(s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	created := binlogDatetime2(2024, 1, 2, 3, 4, 5)
+	apple := binlogRow(binlogLong(1), binlogVarchar(gbk("苹果")), created)
+	banana := binlogRow(binlogLong(1), binlogVarchar(gbk("香蕉")), created)
+	pear := binlogRow(binlogLong(2), binlogVarchar([]byte("pear")), nil)
+	ordersTypes := []byte{gomysql.MYSQL_TYPE_LONG, gomysql.MYSQL_TYPE_VARCHAR, gomysql.MYSQL_TYPE_DATETIME2}
+	// varchar(64)的gbk字符最多128字节, datetime的精度为0
+	ordersMeta := []byte{128, 0, 0}
+
+	w := newBinlogFixtureWriter()
+	w.query("shop", "BEGIN")
+	w.tableMap(binlog_fixture_table_id, "shop", "orders", ordersTypes, ordersMeta)
+	w.rows(replication.WRITE_ROWS_EVENTv2, binlog_fixture_table_id, len(ordersTypes), apple, pear)
+	w.xid(1)
+	w.query("other", "BEGIN")
+	w.tableMap(binlog_fixture_other_table_id, "other", "audit", []byte{gomysql.MYSQL_TYPE_LONG}, nil)
+	w.rows(replication.WRITE_ROWS_EVENTv2, binlog_fixture_other_table_id, 1, binlogRow(binlogLong(7)))
+	w.xid(2)
+	w.query("shop", "BEGIN")
+	w.tableMap(binlog_fixture_table_id, "shop", "orders", ordersTypes, ordersMeta)
+	w.rows(replication.UPDATE_ROWS_EVENTv2, binlog_fixture_table_id, len(ordersTypes), apple, banana)
+	w.xid(3)
+	w.query("shop", "BEGIN")
+	w.tableMap(binlog_fixture_table_id, "shop", "orders", ordersTypes, ordersMeta)
+	w.rows(replication.DELETE_ROWS_EVENTv2, binlog_fixture_table_id, len(ordersTypes), pear)
+	w.xid(4)
+	return w.buf.Bytes()
+}
+
+type binlogFixtureWriter struct {
+	buf bytes.Buffer
+}
+
+func newBinlogFixtureWriter() *binlogFixtureWriter {
+	w := &binlogFixtureWriter{}
+	w.buf.Write(replication.BinLogFileHeader)
+	body := binary.LittleEndian.AppendUint16(nil, 4)
+	version := make([]byte, 50)
+	copy(version, binlog_fixture_server_version)
+	body = append(body, version...)
+	body = binary.LittleEndian.AppendUint32(body, binlog_fixture_timestamp)
+	body = append(body, replication.EventHeaderSize)
+	// 各类事件的post header长度, TABLE_MAP和ROWS事件使用6字节的table id
+	postHeaderLengths := make([]byte, 41)
+	postHeaderLengths[replication.QUERY_EVENT-1] = 13
+	postHeaderLengths[replication.TABLE_MAP_EVENT-1] = 8
+	postHeaderLengths[replication.WRITE_ROWS_EVENTv2-1] = 10
+	postHeaderLengths[replication.UPDATE_ROWS_EVENTv2-1] = 10
+	postHeaderLengths[replication.DELETE_ROWS_EVENTv2-1] = 10
+	body = append(body, postHeaderLengths...)
+	body = append(body, replication.BINLOG_CHECKSUM_ALG_CRC32)
+	w.writeEvent(replication.FORMAT_DESCRIPTION_EVENT, body)
+	return w
+}
+
+// writeEvent 写入事件头、事件体和CRC32校验和
+func (w *binlogFixtureWriter) writeEvent(eventType replication.EventType, body []byte) {
+	size := replication.EventHeaderSize + len(body) + replication.BinlogChecksumLength
+	event := binary.LittleEndian.AppendUint32(nil, binlog_fixture_timestamp)
+	event = append(event, byte(eventType))
+	event = binary.LittleEndian.AppendUint32(event, 1)
+	event = binary.LittleEndian.AppendUint32(event, uint32(size))
+	event = binary.LittleEndian.AppendUint32(event, uint32(w.buf.Len()+size))
+	event = binary.LittleEndian.AppendUint16(event, 0)
+	event = append(event, body...)
+	event = binary.LittleEndian.AppendUint32(event, crc32.ChecksumIEEE(event))
+	w.buf.Write(event)
+}
+
+func (w *binlogFixtureWriter) query(schema, query string) {
+	body := binary.LittleEndian.AppendUint32(nil, 1)
+	body = binary.LittleEndian.AppendUint32(body, 0)
+	body = append(body, byte(len(schema)))
+	body = binary.LittleEndian.AppendUint16(body, 0)
+	body = binary.LittleEndian.AppendUint16(body, 0)
+	body = append(body, schema...)
+	body = append(body, 0)
+	body = append(body, query...)
+	w.writeEvent(replication.QUERY_EVENT, body)
+}
+
+func (w *binlogFixtureWriter) tableMap(tableID uint64, schema, table string, columnTypes, meta []byte) {
+	body := appendBinlogTableID(nil, tableID)
+	body = binary.LittleEndian.AppendUint16(body, 1)
+	body = append(body, byte(len(schema)))
+	body = append(body, schema...)
+	body = append(body, 0, byte(len(table)))
+	body = append(body, table...)
+	body = append(body, 0, byte(len(columnTypes)))
+	body = append(body, columnTypes...)
+	body = append(body, byte(len(meta)))
+	body = append(body, meta...)
+	// 所有列都可以为NULL
+	body = append(body, allBitsSet(len(columnTypes))...)
+	w.writeEvent(replication.TABLE_MAP_EVENT, body)
+}
+
+// rows 写入行事件, 更新事件的rows按更新前、更新后成对出现
+func (w *binlogFixtureWriter) rows(eventType replication.EventType, tableID uint64, columnCount int, rows ...[]byte) {
+	body := appendBinlogTableID(nil, tableID)
+	// STMT_END_F
+	body = binary.LittleEndian.AppendUint16(body, 1)
+	body = binary.LittleEndian.AppendUint16(body, 2)
+	body = append(body, byte(columnCount))
+	body = append(body, allBitsSet(columnCount)...)
+	if eventType == replication.UPDATE_ROWS_EVENTv2 {
+		body = append(body, allBitsSet(columnCount)...)
+	}
+	for _, row := range rows {
+		body = append(body, row...)
+	}
+	w.writeEvent(eventType, body)
+}
+
+func (w *binlogFixtureWriter) xid(xid uint64) {
+	w.writeEvent(replication.XID_EVENT, binary.LittleEndian.AppendUint64(nil, xid))
+}
+
+func appendBinlogTableID(b []byte, tableID uint64) []byte {
+	return append(b, binary.LittleEndian.AppendUint64(nil, tableID)[:6]...)
+}
+
+func allBitsSet(n int) []byte {
+	b := make([]byte, (n+7)/8)
+	for i := 0; i < n; i++ {
+		b[i/8] |= 1 << uint(i%8)
+	}
+	return b
+}
+
+// binlogRow 编码一行的值, nil表示NULL
+func binlogRow(values ...[]byte) []byte {
+	row := make([]byte, (len(values)+7)/8)
+	for i, value := range values {
+		if value == nil {
+			row[i/8] |= 1 << uint(i%8)
+		}
+	}
+	for _, value := range values {
+		row = append(row, value...)
+	}
+	return row
+}
+
+func binlogLong(v int32) []byte {
+	return binary.LittleEndian.AppendUint32(nil, uint32(v))
+}
+
+// binlogVarchar 最大长度小于256字节的varchar使用1字节的长度前缀
+func binlogVarchar(v []byte) []byte {
+	return append([]byte{byte(len(v))}, v...)
+}
+
+// binlogDatetime2 编码精度为0的DATETIME, 5字节大端序, 最高位为符号位
+func binlogDatetime2(year, month, day, hour, minute, second int64) []byte {
+	ymd := (year*13+month)<<5 | day
+	hms := hour<<12 | minute<<6 | second
+	v := uint64(ymd<<17|hms) + 0x8000000000
+	return binary.BigEndian.AppendUint64(nil, v)[3:]
+}
@@ -0,0 +1,377 @@
+package modules
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	wkb_point              = 1
+	wkb_linestring         = 2
+	wkb_polygon            = 3
+	wkb_multipoint         = 4
+	wkb_multilinestring    = 5
+	wkb_multipolygon       = 6
+	wkb_geometrycollection = 7
+
+	// EWKB的类型中的标志位, MySQL只有二维的空间数据
+	ewkb_flag_z    = 0x80000000
+	ewkb_flag_m    = 0x40000000
+	ewkb_flag_srid = 0x20000000
+
+	// 空间数据嵌套的最大层数, 防止错误的数据导致无限递归
+	max_geometry_depth = 32
+)
+
+var wkbTypeNames = map[uint32]string{
+	wkb_point:              "POINT",
+	wkb_linestring:         "LINESTRING",
+	wkb_polygon:            "POLYGON",
+	wkb_multipoint:         "MULTIPOINT",
+	wkb_multilinestring:    "MULTILINESTRING",
+	wkb_multipolygon:       "MULTIPOLYGON",
+	wkb_geometrycollection: "GEOMETRYCOLLECTION",
+}
+
+// yasdbSpatialDataType YashanDB中空间数据列的类型, 与all_tab_columns.data_type一致
+var yasdbSpatialDataType = map[string]struct{}{
+	"ST_GEOMETRY": {},
+	"GEOMETRY":    {},
+}
+
+var errInvalidWKB = errors.New("无效的WKB空间数据")
+
+// spatialValue 空间数据, 写入YashanDB时使用ST_GEOMFROMTEXT(wkt, srid)
+type spatialValue struct {
+	SRID uint32
+	WKT  string
+}
+
+func (v *spatialValue) String() string {
+	return fmt.Sprintf("SRID=%d;%s", v.SRID, v.WKT)
+}
+
+// decodeMySQLGeometry 解析MySQL空间数据的存储格式, 前4个字节为小端序的SRID, 之后为WKB
+func decodeMySQLGeometry(data []byte) (*spatialValue, error) {
+	if len(data) < 4 {
+		return nil, errInvalidWKB
+	}
+	wkt, err := decodeWKB(data[4:])
+	if err != nil {
+		return nil, err
+	}
+	return &spatialValue{SRID: binary.LittleEndian.Uint32(data[:4]), WKT: wkt}, nil
+}
+
+// decodeWKB 将WKB转换为WKT, 支持EWKB中的SRID
+func decodeWKB(data []byte) (string, error) {
+	r := &wkbReader{data: data}
+	wkt, err := r.geometry(0)
+	if err != nil {
+		return "", err
+	}
+	if r.pos != len(data) {
+		return "", errInvalidWKB
+	}
+	return wkt, nil
+}
+
+type wkbReader struct {
+	data  []byte
+	pos   int
+	order binary.ByteOrder
+	srid  uint32
+}
+
+func (r *wkbReader) uint32() (uint32, error) {
+	if r.pos+4 > len(r.data) {
+		return 0, errInvalidWKB
+	}
+	v := r.order.Uint32(r.data[r.pos:])
+	r.pos += 4
+	return v, nil
+}
+
+// count 读取元素个数, 每个元素至少占用minSize个字节, 用于在分配内存前检查数据的长度
+func (r *wkbReader) count(minSize int) (int, error) {
+	n, err := r.uint32()
+	if err != nil {
+		return 0, err
+	}
+	if uint64(n)*uint64(minSize) > uint64(len(r.data)-r.pos) {
+		return 0, errInvalidWKB
+	}
+	return int(n), nil
+}
+
+func (r *wkbReader) coordinate() (string, error) {
+	if r.pos+16 > len(r.data) {
+		return "", errInvalidWKB
+	}
+	x := math.Float64frombits(r.order.Uint64(r.data[r.pos:]))
+	y := math.Float64frombits(r.order.Uint64(r.data[r.pos+8:]))
+	r.pos += 16
+	return formatWKTNumber(x) + " " + formatWKTNumber(y), nil
+}
+
+// points 读取点的列表, 如LINESTRING和POLYGON的环
+func (r *wkbReader) points() (string, error) {
+	n, err := r.count(16)
+	if err != nil {
+		return "", err
+	}
+	coordinates := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		c, err := r.coordinate()
+		if err != nil {
+			return "", err
+		}
+		coordinates = append(coordinates, c)
+	}
+	return "(" + strings.Join(coordinates, ",") + ")", nil
+}
+
+// geometry 读取一个带有字节序和类型的空间对象, 返回WKT
+func (r *wkbReader) geometry(depth int) (string, error) {
+	if depth > max_geometry_depth || r.pos >= len(r.data) {
+		return "", errInvalidWKB
+	}
+	switch r.data[r.pos] {
+	case 0:
+		r.order = binary.BigEndian
+	case 1:
+		r.order = binary.LittleEndian
+	default:
+		return "", errInvalidWKB
+	}
+	r.pos++
+	geometryType, err := r.uint32()
+	if err != nil {
+		return "", err
+	}
+	// EWKB用标志位, ISO WKB用1000、2000、3000表示Z、M、ZM
+	if geometryType&(ewkb_flag_z|ewkb_flag_m) != 0 || geometryType&^ewkb_flag_srid > 1000 {
+		return "", fmt.Errorf("不支持三维或带M值的空间数据, 类型: %d", geometryType)
+	}
+	if geometryType&ewkb_flag_srid != 0 {
+		if r.srid, err = r.uint32(); err != nil {
+			return "", err
+		}
+	}
+	geometryType &^= ewkb_flag_srid
+	name, ok := wkbTypeNames[geometryType]
+	if !ok {
+		return "", fmt.Errorf("不支持的空间数据类型: %d", geometryType)
+	}
+	var body string
+	switch geometryType {
+	case wkb_point:
+		c, err := r.coordinate()
+		if err != nil {
+			return "", err
+		}
+		body = "(" + c + ")"
+	case wkb_linestring:
+		if body, err = r.points(); err != nil {
+			return "", err
+		}
+	case wkb_polygon:
+		n, err := r.count(4)
+		if err != nil {
+			return "", err
+		}
+		rings := make([]string, 0, n)
+		for i := 0; i < n; i++ {
+			ring, err := r.points()
+			if err != nil {
+				return "", err
+			}
+			rings = append(rings, ring)
+		}
+		body = "(" + strings.Join(rings, ",") + ")"
+	default:
+		// MULTI*和GEOMETRYCOLLECTION的每个元素都是完整的WKB
+		n, err := r.count(5)
+		if err != nil {
+			return "", err
+		}
+		elements := make([]string, 0, n)
+		for i := 0; i < n; i++ {
+			element, err := r.geometry(depth + 1)
+			if err != nil {
+				return "", err
+			}
+			if geometryType != wkb_geometrycollection {
+				// MULTIPOINT((0 0),(1 1))中去掉元素的类型名
+				element = element[strings.Index(element, "("):]
+			}
+			elements = append(elements, element)
+		}
+		body = "(" + strings.Join(elements, ",") + ")"
+	}
+	if body == "()" {
+		return name + " EMPTY", nil
+	}
+	return name + body, nil
+}
+
+func formatWKTNumber(f float64) string {
+	if f == 0 {
+		// -0和0相同
+		return "0"
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// parseYashanGeometry 将YashanDB查询到的空间数据转换为spatialValue, 支持WKT、EWKT、WKB、EWKB和MySQL的存储格式
+func parseYashanGeometry(value interface{}) (*spatialValue, error) {
+	switch v := value.(type) {
+	case *spatialValue:
+		return v, nil
+	case string:
+		return parseWKT(v)
+	case []byte:
+		if len(v) == 0 {
+			return nil, errInvalidWKB
+		}
+		// 以字节序标记开头的是WKB或EWKB
+		if v[0] == 0 || v[0] == 1 {
+			r := &wkbReader{data: v}
+			if wkt, err := r.geometry(0); err == nil && r.pos == len(v) {
+				return &spatialValue{SRID: r.srid, WKT: wkt}, nil
+			}
+		}
+		if geometry, err := decodeMySQLGeometry(v); err == nil {
+			return geometry, nil
+		}
+		return parseWKT(string(v))
+	}
+	return nil, fmt.Errorf("无法解析的空间数据类型: %T", value)
+}
+
+// parseWKT 将WKT或EWKT统一为decodeWKB输出的格式, 类型名大写, 去掉多余的空格, 数值按相同的精度格式化
+func parseWKT(s string) (*spatialValue, error) {
+	s = strings.TrimSpace(s)
+	geometry := &spatialValue{}
+	if strings.HasPrefix(strings.ToUpper(s), "SRID=") {
+		i := strings.Index(s, ";")
+		if i < 0 {
+			return nil, fmt.Errorf("无效的EWKT空间数据: %s", s)
+		}
+		srid, err := strconv.ParseUint(s[len("SRID="):i], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("无效的EWKT空间数据: %s", s)
+		}
+		geometry.SRID = uint32(srid)
+		s = s[i+1:]
+	}
+	var out bytes.Buffer
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(' || c == ')' || c == ',':
+			out.WriteByte(c)
+			i++
+		case c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9'):
+			j := i + 1
+			for j < len(s) && (s[j] == '.' || s[j] == 'e' || s[j] == 'E' || s[j] == '-' || s[j] == '+' || (s[j] >= '0' && s[j] <= '9')) {
+				j++
+			}
+			f, err := strconv.ParseFloat(s[i:j], 64)
+			if err != nil {
+				return nil, fmt.Errorf("无效的WKT空间数据: %s", s)
+			}
+			// 同一个坐标中的两个数值以空格分隔
+			if b := out.Bytes(); len(b) > 0 && b[len(b)-1] != '(' && b[len(b)-1] != ',' {
+				out.WriteByte(' ')
+			}
+			out.WriteString(formatWKTNumber(f))
+			i = j
+		case c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z':
+			j := i + 1
+			for j < len(s) && (s[j] >= 'A' && s[j] <= 'Z' || s[j] >= 'a' && s[j] <= 'z') {
+				j++
+			}
+			word := strings.ToUpper(s[i:j])
+			if word == "GEOMCOLLECTION" {
+				word = "GEOMETRYCOLLECTION"
+			}
+			if word == "EMPTY" {
+				out.WriteByte(' ')
+			}
+			out.WriteString(word)
+			i = j
+		default:
+			return nil, fmt.Errorf("无效的WKT空间数据: %s", s)
+		}
+	}
+	geometry.WKT = normalizeMultiPoint(out.String())
+	return geometry, nil
+}
+
+// normalizeMultiPoint MULTIPOINT(0 0,1 1)的点可以不带括号, 统一为MULTIPOINT((0 0),(1 1))
+func normalizeMultiPoint(wkt string) string {
+	const prefix = "MULTIPOINT("
+	if !strings.HasPrefix(wkt, prefix) || strings.HasPrefix(wkt, prefix+"(") || !strings.HasSuffix(wkt, ")") {
+		return wkt
+	}
+	points := strings.Split(wkt[len(prefix):len(wkt)-1], ",")
+	return prefix + "(" + strings.Join(points, "),(") + "))"
+}
+
+// isSpatialEqual 比较两个空间数据是否相同, 有一方没有SRID时只比较坐标
+func isSpatialEqual(v1 *spatialValue, v2 interface{}) bool {
+	g2, err := parseYashanGeometry(v2)
+	if err != nil {
+		return false
+	}
+	if v1.SRID != 0 && g2.SRID != 0 && v1.SRID != g2.SRID {
+		return false
+	}
+	return v1.WKT == g2.WKT
+}
+
+// isYashanSpatialColumn YashanDB的空间数据列插入时使用ST_GEOMFROMTEXT(?, ?)
+func isYashanSpatialColumn(column ColumnInfo) bool {
+	_, ok := yasdbSpatialDataType[strings.ToUpper(column.ColumnType)]
+	return ok
+}
+
+// bindYashanValues 将一行的值转换为绑定参数, 空间数据展开为WKT和SRID两个参数
+func bindYashanValues(columns []ColumnInfo, values []interface{}) []interface{} {
+	args := make([]interface{}, 0, len(values))
+	for i, value := range values {
+		if i >= len(columns) || !isYashanSpatialColumn(columns[i]) {
+			args = append(args, value)
+			continue
+		}
+		switch v := value.(type) {
+		case nil:
+			args = append(args, nil, nil)
+		case *spatialValue:
+			args = append(args, v.WKT, v.SRID)
+		default:
+			// 无法解析的空间数据按原值插入, 由YashanDB报错并记录到日志
+			args = append(args, value, nil)
+		}
+	}
+	return args
+}
+
+// countBindParams 一行数据的绑定参数个数
+func countBindParams(columns []ColumnInfo) int {
+	n := len(columns)
+	for _, column := range columns {
+		if isYashanSpatialColumn(column) {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,180 @@
+package modules
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"m2y/defs/runtimedef"
+	"m2y/utils/fileutil"
+
+	"git.yasdb.com/go/yasutil/fs"
+)
+
+const (
+	// 每张表的断点保存在该目录下的一个文件中, 提交批次时只重写该表的断点文件
+	sync_checkpoint_dir = "sync"
+)
+
+// chunkCheckpoint 记录表内一个分片的同步进度
+type chunkCheckpoint struct {
+	Range    keyRange `json:"range"`
+	LastKey  [][]byte `json:"last_key"` // 最后一个已在YashanDB提交的行的键值
+	Rows     int64    `json:"rows"`
+	Finished bool     `json:"finished"`
+
+	table *tableCheckpoint
+}
+
+// tableCheckpoint 记录一张表的同步进度
+type tableCheckpoint struct {
+	MySQLSchema string             `json:"mysql_schema"`
+	YasdbSchema string             `json:"yasdb_schema"`
+	Table       string             `json:"table"`
+	KeyColumns  []string           `json:"key_columns"`
+	Chunks      []*chunkCheckpoint `json:"chunks"`
+	Finished    bool               `json:"finished"`
+
+	// 同一张表的多个分片并行同步时, 修改和保存该表的断点需要持有mu
+	mu       sync.Mutex
+	fileName string
+}
+
+// syncCheckpoint 保存在{M2Y_HOME}/checkpoint/sync下的同步断点信息, 每张表一个文件, 所有方法都是并发安全的
+type syncCheckpoint struct {
+	mu     sync.Mutex
+	dir    string
+	Tables map[string]*tableCheckpoint
+}
+
+// newSyncCheckpoint 创建同步断点, resume为true时加载上一次的断点, 否则清空上一次的断点
+func newSyncCheckpoint(resume bool) (*syncCheckpoint, error) {
+	cp := &syncCheckpoint{
+		dir:    path.Join(runtimedef.GetCheckpointPath(), sync_checkpoint_dir),
+		Tables: make(map[string]*tableCheckpoint),
+	}
+	if !resume {
+		if err := os.RemoveAll(cp.dir); err != nil {
+			return nil, err
+		}
+	}
+	if err := fs.Mkdir(cp.dir); err != nil {
+		return nil, err
+	}
+	if !resume {
+		return cp, nil
+	}
+	fileNames, err := filepath.Glob(path.Join(cp.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, fileName := range fileNames {
+		data, err := fileutil.ReadFile(fileName)
+		if err != nil {
+			return nil, err
+		}
+		tcp := &tableCheckpoint{}
+		if err := json.Unmarshal(data, tcp); err != nil {
+			return nil, fmt.Errorf("解析断点文件 %s 失败: %v", fileName, err)
+		}
+		tcp.fileName = fileName
+		for _, chunk := range tcp.Chunks {
+			chunk.table = tcp
+		}
+		cp.Tables[checkpointTableKey(tcp.MySQLSchema, tcp.Table)] = tcp
+	}
+	return cp, nil
+}
+
+func checkpointTableKey(mysqlSchema, table string) string {
+	return mysqlSchema + "." + table
+}
+
+// checkpointFileName 表的断点文件名, 转义schema和表名中的路径分隔符和点, 避免不同的表使用同一个文件
+func (cp *syncCheckpoint) checkpointFileName(mysqlSchema, table string) string {
+	escape := func(name string) string {
+		return strings.ReplaceAll(url.PathEscape(name), ".", "%2E")
+	}
+	return path.Join(cp.dir, escape(mysqlSchema)+"."+escape(table)+".json")
+}
+
+// getTable 返回表的断点信息, 没有记录时返回nil
+func (cp *syncCheckpoint) getTable(mysqlSchema, table string) *tableCheckpoint {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	return cp.Tables[checkpointTableKey(mysqlSchema, table)]
+}
+
+// startTable 在写入数据前记录表的分片信息
+func (cp *syncCheckpoint) startTable(mysqlSchema, yasdbSchema, table string, keyColumns []string, ranges []keyRange) (*tableCheckpoint, error) {
+	tcp := &tableCheckpoint{
+		MySQLSchema: mysqlSchema,
+		YasdbSchema: yasdbSchema,
+		Table:       table,
+		KeyColumns:  keyColumns,
+		fileName:    cp.checkpointFileName(mysqlSchema, table),
+	}
+	for _, r := range ranges {
+		tcp.Chunks = append(tcp.Chunks, &chunkCheckpoint{Range: r, table: tcp})
+	}
+	cp.mu.Lock()
+	cp.Tables[checkpointTableKey(mysqlSchema, table)] = tcp
+	cp.mu.Unlock()
+	tcp.mu.Lock()
+	defer tcp.mu.Unlock()
+	return tcp, tcp.save()
+}
+
+// commitChunk 在一个批次提交成功后记录分片的进度, 只重写分片所在表的断点文件
+func (cp *syncCheckpoint) commitChunk(chunk *chunkCheckpoint, lastKey [][]byte, rows int) error {
+	chunk.table.mu.Lock()
+	defer chunk.table.mu.Unlock()
+	if lastKey != nil {
+		chunk.LastKey = lastKey
+	}
+	chunk.Rows += int64(rows)
+	return chunk.table.save()
+}
+
+func (cp *syncCheckpoint) finishChunk(chunk *chunkCheckpoint) error {
+	chunk.table.mu.Lock()
+	defer chunk.table.mu.Unlock()
+	chunk.Finished = true
+	return chunk.table.save()
+}
+
+func (cp *syncCheckpoint) finishTable(tcp *tableCheckpoint) error {
+	tcp.mu.Lock()
+	defer tcp.mu.Unlock()
+	tcp.Finished = true
+	return tcp.save()
+}
+
+// resumeRange 返回分片剩余未同步的区间
+func (c *chunkCheckpoint) resumeRange() keyRange {
+	if len(c.LastKey) == 0 {
+		return c.Range
+	}
+	return keyRange{Lower: c.LastKey, Upper: c.Range.Upper}
+}
+
+func (tcp *tableCheckpoint) canResumeWith(key *tableKey) bool {
+	if key == nil || len(tcp.Chunks) == 0 {
+		return false
+	}
+	return strings.Join(tcp.KeyColumns, ",") == strings.Join(key.Columns, ",")
+}
+
+// save 先写临时文件并同步到磁盘再重命名, 避免进程或系统中断时断点文件损坏, 调用方需持有tcp.mu
+func (tcp *tableCheckpoint) save() error {
+	data, err := json.MarshalIndent(tcp, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteFileAtomic(tcp.fileName, data)
+}
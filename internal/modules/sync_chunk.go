@@ -0,0 +1,263 @@
+package modules
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"m2y/db"
+	"m2y/defs/sqldef"
+)
+
+const (
+	// 表行数小于该值时不做表内切分
+	min_chunk_rows = 1000
+)
+
+// 可直接按最小值/最大值等分的整数类型
+var integerKeyDataType = map[string]struct{}{
+	"tinyint":   {},
+	"smallint":  {},
+	"mediumint": {},
+	"int":       {},
+	"integer":   {},
+	"bigint":    {},
+}
+
+// tableKey 用于切分表数据的主键或非空唯一索引
+type tableKey struct {
+	IndexName string
+	Columns   []string
+	DataTypes []string
+}
+
+// keyRange 表示一个左开右闭的主键区间 (Lower, Upper], Lower或Upper为空表示无下界或无上界
+// 键值保存为MySQL返回的文本形式, 便于写入断点文件
+type keyRange struct {
+	Lower [][]byte `json:"lower"`
+	Upper [][]byte `json:"upper"`
+}
+
+// getMySQLTableKey 查询表的主键, 没有主键时使用所有列均非空的唯一索引, 都没有时返回nil
+func getMySQLTableKey(mysql *sql.DB, mysqlSchema, mysqlTable string) (*tableKey, error) {
+	query := sqldef.M_SQL_QUERY_UNIQUE_KEYS_5
+	if db.MySQLVersion == db.MYSQL_VERSION_8 {
+		query = sqldef.M_SQL_QUERY_UNIQUE_KEYS
+	}
+	rows, err := mysql.Query(query, mysqlSchema, mysqlTable)
+	if err != nil {
+		return nil, fmt.Errorf("查询唯一索引 information_schema.statistics 出错: %v", err)
+	}
+	defer rows.Close()
+
+	var keys []*tableKey
+	usable := make(map[string]bool)
+	for rows.Next() {
+		var indexName string
+		var columnName, dataType, isNullable, expression sql.NullString
+		if err := rows.Scan(&indexName, &columnName, &dataType, &isNullable, &expression); err != nil {
+			return nil, fmt.Errorf("查询唯一索引 information_schema.statistics 出错: %v", err)
+		}
+		if len(keys) == 0 || keys[len(keys)-1].IndexName != indexName {
+			keys = append(keys, &tableKey{IndexName: indexName})
+			usable[indexName] = true
+		}
+		key := keys[len(keys)-1]
+		// 包含函数索引列的唯一索引只在表达式上唯一, 与可空列以及lob列一样不能用来切分数据
+		if expression.Valid || !columnName.Valid || isNullable.String != "NO" {
+			usable[indexName] = false
+			continue
+		}
+		if _, ok := cannotUsedPrimaryDateType[dataType.String]; ok {
+			usable[indexName] = false
+			continue
+		}
+		key.Columns = append(key.Columns, columnName.String)
+		key.DataTypes = append(key.DataTypes, dataType.String)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询唯一索引 information_schema.statistics 出错: %v", err)
+	}
+	for _, key := range keys {
+		if usable[key.IndexName] && len(key.Columns) > 0 {
+			return key, nil
+		}
+	}
+	return nil, nil
+}
+
+// splitTableByKey 将表按主键切分为最多parallel个区间
+func splitTableByKey(mysql *sql.DB, mysqlSchema, mysqlTable string, key *tableKey, count, parallel int) ([]keyRange, error) {
+	if parallel <= 1 || count < min_chunk_rows {
+		return []keyRange{{}}, nil
+	}
+	if len(key.Columns) == 1 {
+		if _, ok := integerKeyDataType[key.DataTypes[0]]; ok {
+			ranges, ok, err := splitTableByMinMax(mysql, mysqlSchema, mysqlTable, key, parallel)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				return ranges, nil
+			}
+		}
+	}
+	return splitTableBySample(mysql, mysqlSchema, mysqlTable, key, count, parallel)
+}
+
+// splitTableByMinMax 按整数主键的最小值和最大值等分区间, 无法按整数处理时返回false
+func splitTableByMinMax(mysql *sql.DB, mysqlSchema, mysqlTable string, key *tableKey, parallel int) ([]keyRange, bool, error) {
+	column := quoteMySQLIdentifier(key.Columns[0])
+	var minStr, maxStr sql.NullString
+	query := fmt.Sprintf(sqldef.M_SQL_QUERY_KEY_MIN_MAX, column, column, mysqlSchema, mysqlTable)
+	if err := mysql.QueryRow(query).Scan(&minStr, &maxStr); err != nil {
+		return nil, false, fmt.Errorf("查询表 %s.%s 主键范围出错: %v", mysqlSchema, mysqlTable, err)
+	}
+	if !minStr.Valid || !maxStr.Valid {
+		return []keyRange{{}}, true, nil
+	}
+	minValue, err := strconv.ParseInt(minStr.String, 10, 64)
+	if err != nil {
+		return nil, false, nil
+	}
+	maxValue, err := strconv.ParseInt(maxStr.String, 10, 64)
+	if err != nil {
+		return nil, false, nil
+	}
+	if maxValue-minValue < 0 {
+		// 差值溢出, 改用采样的方式切分
+		return nil, false, nil
+	}
+	step := (maxValue-minValue)/int64(parallel) + 1
+	var boundaries [][][]byte
+	for i := 1; i < parallel; i++ {
+		boundary := minValue + step*int64(i) - 1
+		if boundary >= maxValue {
+			break
+		}
+		boundaries = append(boundaries, [][]byte{[]byte(strconv.FormatInt(boundary, 10))})
+	}
+	return buildKeyRanges(boundaries), true, nil
+}
+
+// splitTableBySample 按主键顺序采样出区间边界
+func splitTableBySample(mysql *sql.DB, mysqlSchema, mysqlTable string, key *tableKey, count, parallel int) ([]keyRange, error) {
+	columns := quoteMySQLIdentifiers(key.Columns)
+	step := count / parallel
+	var boundaries [][][]byte
+	for i := 1; i < parallel; i++ {
+		query := fmt.Sprintf(sqldef.M_SQL_QUERY_KEY_BOUNDARY, columns, mysqlSchema, mysqlTable, columns, step*i-1)
+		values := make([]sql.RawBytes, len(key.Columns))
+		valuePointers := make([]interface{}, len(key.Columns))
+		for j := range values {
+			valuePointers[j] = &values[j]
+		}
+		rows, err := mysql.Query(query)
+		if err != nil {
+			return nil, fmt.Errorf("查询表 %s.%s 主键边界出错: %v", mysqlSchema, mysqlTable, err)
+		}
+		var boundary [][]byte
+		if rows.Next() {
+			if err := rows.Scan(valuePointers...); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("查询表 %s.%s 主键边界出错: %v", mysqlSchema, mysqlTable, err)
+			}
+			for _, value := range values {
+				// RawBytes在下一次Next或Close后失效, 需要复制
+				boundary = append(boundary, append([]byte{}, value...))
+			}
+		}
+		rows.Close()
+		if boundary == nil {
+			break
+		}
+		boundaries = append(boundaries, boundary)
+	}
+	return buildKeyRanges(boundaries), nil
+}
+
+func buildKeyRanges(boundaries [][][]byte) []keyRange {
+	var ranges []keyRange
+	var lower [][]byte
+	for _, boundary := range boundaries {
+		ranges = append(ranges, keyRange{Lower: lower, Upper: boundary})
+		lower = boundary
+	}
+	ranges = append(ranges, keyRange{Lower: lower})
+	return ranges
+}
+
+// buildKeyRangeQuery 构建按主键顺序读取区间数据的SQL
+func buildKeyRangeQuery(mysqlSchema, mysqlTable string, key *tableKey, r keyRange) (string, []interface{}) {
+	columns := quoteMySQLIdentifiers(key.Columns)
+	if len(key.Columns) > 1 {
+		columns = "(" + columns + ")"
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(key.Columns)), ",")
+	if len(key.Columns) > 1 {
+		placeholders = "(" + placeholders + ")"
+	}
+	var conditions []string
+	var args []interface{}
+	if len(r.Lower) > 0 {
+		conditions = append(conditions, fmt.Sprintf("%s > %s", columns, placeholders))
+		args = append(args, key.toArgs(r.Lower)...)
+	}
+	if len(r.Upper) > 0 {
+		conditions = append(conditions, fmt.Sprintf("%s <= %s", columns, placeholders))
+		args = append(args, key.toArgs(r.Upper)...)
+	}
+	var where string
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+	return fmt.Sprintf(sqldef.M_SQL_QUERY_TABLE_KEY_RANGE, mysqlSchema, mysqlTable, where, quoteMySQLIdentifiers(key.Columns)), args
+}
+
+// toArgs 将键值转换为查询参数, 整数列按整数传参, 避免MySQL将整数和字符串按浮点数比较而丢失精度
+func (k *tableKey) toArgs(values [][]byte) []interface{} {
+	var args []interface{}
+	for i, value := range values {
+		if _, ok := integerKeyDataType[k.DataTypes[i]]; ok {
+			if v, err := strconv.ParseInt(string(value), 10, 64); err == nil {
+				args = append(args, v)
+				continue
+			}
+			if v, err := strconv.ParseUint(string(value), 10, 64); err == nil {
+				args = append(args, v)
+				continue
+			}
+		}
+		args = append(args, string(value))
+	}
+	return args
+}
+
+// keyValueToBytes 将从结果集中读取的键值转换为文本形式
+func keyValueToBytes(value interface{}) []byte {
+	switch v := value.(type) {
+	case []byte:
+		return append([]byte{}, v...)
+	case string:
+		return []byte(v)
+	case int64:
+		return []byte(strconv.FormatInt(v, 10))
+	case uint64:
+		return []byte(strconv.FormatUint(v, 10))
+	default:
+		return []byte(fmt.Sprint(v))
+	}
+}
+
+func quoteMySQLIdentifier(s string) string {
+	return "`" + strings.ReplaceAll(s, "`", "``") + "`"
+}
+
+func quoteMySQLIdentifiers(arr []string) string {
+	var quoted []string
+	for _, s := range arr {
+		quoted = append(quoted, quoteMySQLIdentifier(s))
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"m2y/defs/confdef"
@@ -20,19 +21,38 @@ type ColumnInfo struct {
 	ColumnType string
 }
 
+// rangeQuery 表内一个并行分片的查询语句, 按主键切分时记录分片的断点
+type rangeQuery struct {
+	query string
+	args  []interface{}
+	key   *tableKey
+	chunk *chunkCheckpoint
+}
+
 type schemaTable struct {
 	mysqlSchema string
 	yasdbSchema string
 	table       string
 }
 
-func DealTableData(mysql, yasdb *sql.DB, mysqlSchema, yasdbSchema string, alltables []string, parallel, tableParallel, batchSize int) error {
+func DealTableData(mysql, yasdb *sql.DB, mysqlSchema, yasdbSchema string, alltables []string, parallel, tableParallel, batchSize int, resume, consistentSnapshot bool) error {
+	cp, err := newSyncCheckpoint(resume)
+	if err != nil {
+		return fmt.Errorf("初始化同步断点失败: %v", err)
+	}
+	snapshot, err := openConsistentSnapshot(mysql, consistentSnapshot, resume, parallel*tableParallel)
+	if err != nil {
+		return fmt.Errorf("创建一致性快照失败: %v", err)
+	}
+	defer snapshot.close()
+	recordBinlogPosition(mysql, snapshot, resume)
 	taskCount := len(alltables)
 	start := time.Now() // 记录开始时间
 	// 创建一个带有缓冲区的通道，用于控制并发数量
 	semaphore := make(chan bool, parallel)
 	// 创建一个等待组，用于等待所有goroutine完成
 	var wg sync.WaitGroup
+	var totalRows int64
 	log.Logger.Infof("开始同步mysql中表数据到yashandb......")
 	for i := 0; i < taskCount; i++ {
 		wg.Add(1)
@@ -40,7 +60,8 @@ func DealTableData(mysql, yasdb *sql.DB, mysqlSchema, yasdbSchema string, alltab
 		semaphore <- true
 		go func(mysdb, yasdDb *sql.DB, mysqlSchema, yasdbSchema, mysqlTable, yasdbTable string) {
 			defer wg.Done()
-			syncTableDataFromMySQLToYasdb(0, mysdb, yasdDb, mysqlSchema, yasdbSchema, mysqlTable, yasdbTable, tableParallel, batchSize)
+			rows := syncTableDataFromMySQLToYasdb(0, mysdb, yasdDb, cp, snapshot, mysqlSchema, yasdbSchema, mysqlTable, yasdbTable, tableParallel, batchSize)
+			atomic.AddInt64(&totalRows, rows)
 			// 任务完成后释放信号量
 			<-semaphore
 
@@ -49,32 +70,25 @@ func DealTableData(mysql, yasdb *sql.DB, mysqlSchema, yasdbSchema string, alltab
 	// 等待所有goroutine完成
 	wg.Wait()
 	elapsed := time.Since(start) // 计算经过的时间
-	log.Logger.Infof("任务完成, 共耗时: %v\n", elapsed)
+	log.Logger.Infof("任务完成, 共迁移数据量: %d 共耗时: %v 速度: %.0f 行/秒\n", totalRows, elapsed, rowsPerSecond(totalRows, elapsed))
+	logSnapshotPosition(snapshot)
 	return nil
 }
 
-func DealSchemasData(mysql, yasdb *sql.DB, mysqlSchemas, yasdbSchemas []string, excludeTables []string, parallel, tableParallel, batchSize int) error {
-	// 查询表的信息
-	mysqDbs, err := getMySQLAllDbs(mysql)
+func DealSchemasData(mysql, yasdb *sql.DB, mysqlSchemas, yasdbSchemas []string, excludeTables []string, parallel, tableParallel, batchSize int, resume, consistentSnapshot bool) error {
+	cp, err := newSyncCheckpoint(resume)
 	if err != nil {
-		return fmt.Errorf("获取mysql所有schema失败: %v", err)
+		return fmt.Errorf("初始化同步断点失败: %v", err)
 	}
-	sts := []schemaTable{}
-	for i, schema := range mysqlSchemas {
-		if !inArrayStr(schema, mysqDbs) {
-			log.Logger.Infof("schema%s不存在, 请检查配置文件或mysql环境信息", schema)
-			continue
-		}
-		tables, err := getMySQLSchemaTables(mysql, schema)
-		if err != nil {
-			return err
-		}
-		for _, table := range tables {
-			if inArrayStr(table, excludeTables) {
-				continue
-			}
-			sts = append(sts, schemaTable{table: table, mysqlSchema: schema, yasdbSchema: yasdbSchemas[i]})
-		}
+	snapshot, err := openConsistentSnapshot(mysql, consistentSnapshot, resume, parallel*tableParallel)
+	if err != nil {
+		return fmt.Errorf("创建一致性快照失败: %v", err)
+	}
+	defer snapshot.close()
+	recordBinlogPosition(mysql, snapshot, resume)
+	sts, err := getSchemaTables(mysql, mysqlSchemas, yasdbSchemas, excludeTables)
+	if err != nil {
+		return err
 	}
 	taskCount := len(sts)
 	start := time.Now() // 记录开始时间
@@ -82,6 +96,7 @@ func DealSchemasData(mysql, yasdb *sql.DB, mysqlSchemas, yasdbSchemas []string,
 	semaphore := make(chan bool, parallel)
 	// 创建一个等待组，用于等待所有goroutine完成
 	var wg sync.WaitGroup
+	var totalRows int64
 	log.Logger.Infof("开始同步mysql数据到yashandb......")
 	for i := 0; i < taskCount; i++ {
 		wg.Add(1)
@@ -89,7 +104,8 @@ func DealSchemasData(mysql, yasdb *sql.DB, mysqlSchemas, yasdbSchemas []string,
 		semaphore <- true
 		go func(i int, mysdb, yasdDb *sql.DB, mysqlSchema, yasdbSchema, mysqlTable, yasdbTable string, tableParallel, batchSize int) {
 			defer wg.Done()
-			syncTableDataFromMySQLToYasdb(i, mysdb, yasdDb, mysqlSchema, yasdbSchema, mysqlTable, yasdbTable, tableParallel, batchSize)
+			rows := syncTableDataFromMySQLToYasdb(i, mysdb, yasdDb, cp, snapshot, mysqlSchema, yasdbSchema, mysqlTable, yasdbTable, tableParallel, batchSize)
+			atomic.AddInt64(&totalRows, rows)
 			// 任务完成后释放信号量
 			<-semaphore
 
@@ -98,60 +114,183 @@ func DealSchemasData(mysql, yasdb *sql.DB, mysqlSchemas, yasdbSchemas []string,
 	// 等待所有goroutine完成
 	wg.Wait()
 	elapsed := time.Since(start) // 计算经过的时间
-	log.Logger.Infof("数据同步任务完成, 共耗时: %v", elapsed)
+	log.Logger.Infof("数据同步任务完成, 共迁移数据量: %d 共耗时: %v 速度: %.0f 行/秒", totalRows, elapsed, rowsPerSecond(totalRows, elapsed))
+	logSnapshotPosition(snapshot)
 	return nil
 }
 
-func syncTableDataFromMySQLToYasdb(i int, mysql, yasdb *sql.DB, mysqlSchema, yasdbSchema, mysqlTable, yasdbTable string, tableParallel, batchSize int) {
+// getSchemaTables 查询需要迁移的schema下的所有表
+func getSchemaTables(mysql *sql.DB, mysqlSchemas, yasdbSchemas []string, excludeTables []string) ([]schemaTable, error) {
+	mysqDbs, err := getMySQLAllDbs(mysql)
+	if err != nil {
+		return nil, fmt.Errorf("获取mysql所有schema失败: %v", err)
+	}
+	sts := []schemaTable{}
+	for i, schema := range mysqlSchemas {
+		if !inArrayStr(schema, mysqDbs) {
+			log.Logger.Infof("schema%s不存在, 请检查配置文件或mysql环境信息", schema)
+			continue
+		}
+		tables, err := getMySQLSchemaTables(mysql, schema)
+		if err != nil {
+			return nil, err
+		}
+		for _, table := range tables {
+			if inArrayStr(table, excludeTables) {
+				continue
+			}
+			sts = append(sts, schemaTable{table: table, mysqlSchema: schema, yasdbSchema: yasdbSchemas[i]})
+		}
+	}
+	return sts, nil
+}
+
+// syncTableDataFromMySQLToYasdb 同步一张表的数据, 返回同步的行数
+func syncTableDataFromMySQLToYasdb(i int, mysql, yasdb *sql.DB, cp *syncCheckpoint, snapshot *consistentSnapshot, mysqlSchema, yasdbSchema, mysqlTable, yasdbTable string, tableParallel, batchSize int) int64 {
 	// 记录开始时间
 	if i == 100 {
 		fmt.Println(i)
 	}
 	start := time.Now()
+	tcp := cp.getTable(mysqlSchema, mysqlTable)
+	if tcp != nil && tcp.Finished {
+		log.Logger.Infof("表 %s.%s 已同步完成, 跳过", mysqlSchema, mysqlTable)
+		return 0
+	}
 	log.Logger.Infof("开始同步mysql表 %s.%s", mysqlSchema, mysqlTable)
 	//处理总行数
-	var totalCount int
-	count, err := getMySQLTableCount(mysql, mysqlSchema, mysqlTable)
+	session, release := snapshot.acquire(mysql)
+	count, err := getMySQLTableCount(session, mysqlSchema, mysqlTable)
+	release()
 	if err != nil {
 		log.Logger.Errorf("表 %s.%s 同步失败, 获取mysql端表数据失败: %v", mysqlSchema, mysqlTable, err)
-		return
+		return 0
 	}
 	yasdbColumns, err := getYasdbColumns(yasdb, yasdbSchema, yasdbTable)
 	if err != nil {
 		log.Logger.Errorf("表 %s.%s 同步失败, 获取yashandb端表结构失败: %v", mysqlSchema, mysqlTable, err)
-		return
+		return 0
 	}
-	//设置当前表并行度
-	//设置limit大小
-	var limit int
-	if count < 1000 {
-		tableParallel = 1
-		limit = 1000
+	generatedColumns, err := getMySQLGeneratedColumns(mysql, mysqlSchema, mysqlTable)
+	if err != nil {
+		log.Logger.Errorf("表 %s.%s 同步失败, 获取mysql端生成列失败: %v", mysqlSchema, mysqlTable, err)
+		return 0
+	}
+	typeMappings, err := getMySQLColumnTypeMappings(mysql, mysqlSchema, mysqlTable)
+	if err != nil {
+		log.Logger.Errorf("表 %s.%s 同步失败, 获取列的类型映射失败: %v", mysqlSchema, mysqlTable, err)
+		return 0
+	}
+	key, err := getMySQLTableKey(mysql, mysqlSchema, mysqlTable)
+	if err != nil {
+		log.Logger.Errorf("表 %s.%s 同步失败, 获取mysql端表主键失败: %v", mysqlSchema, mysqlTable, err)
+		return 0
+	}
+	var queries []rangeQuery
+	if tcp != nil && tcp.canResumeWith(key) {
+		log.Logger.Infof("表 %s.%s 从上一次提交的主键位置继续同步", mysqlSchema, mysqlTable)
 	} else {
-		limit = count/tableParallel + 1
+		if tcp != nil {
+			// 表已部分同步但无法按主键续传, 清空目标表后重新同步
+			log.Logger.Warnf("表 %s.%s 无法从断点续传, 清空目标表后重新同步", mysqlSchema, mysqlTable)
+			if err := truncateYasdbTable(yasdb, yasdbSchema, yasdbTable); err != nil {
+				log.Logger.Errorf("表 %s.%s 同步失败, 清空目标表失败: %v", mysqlSchema, mysqlTable, err)
+				return 0
+			}
+		}
+		var ranges []keyRange
+		var keyColumns []string
+		if key != nil {
+			ranges, err = splitTableByKey(mysql, mysqlSchema, mysqlTable, key, count, tableParallel)
+			if err != nil {
+				log.Logger.Errorf("表 %s.%s 同步失败, 按主键切分数据失败: %v", mysqlSchema, mysqlTable, err)
+				return 0
+			}
+			keyColumns = key.Columns
+		}
+		tcp, err = cp.startTable(mysqlSchema, yasdbSchema, mysqlTable, keyColumns, ranges)
+		if err != nil {
+			log.Logger.Errorf("表 %s.%s 同步失败, 记录断点失败: %v", mysqlSchema, mysqlTable, err)
+			return 0
+		}
+	}
+	if key != nil {
+		for _, chunk := range tcp.Chunks {
+			if chunk.Finished {
+				continue
+			}
+			query, args := buildKeyRangeQuery(mysqlSchema, mysqlTable, key, chunk.resumeRange())
+			queries = append(queries, rangeQuery{query: query, args: args, key: key, chunk: chunk})
+		}
+	} else {
+		log.Logger.Warnf("表 %s.%s 没有主键或非空唯一索引, 使用LIMIT/OFFSET方式切分数据, 中断后无法断点续传", mysqlSchema, mysqlTable)
+		queries = splitTableByLimit(mysqlSchema, mysqlTable, count, tableParallel)
+	}
+	if len(queries) < tableParallel {
+		tableParallel = len(queries)
 	}
+	if tableParallel < 1 {
+		tableParallel = 1
+	}
+	var totalCount, failedCount int64
 	// 创建一个带有缓冲区的通道，用于控制并发数量
 	semaphore := make(chan bool, tableParallel)
 	// 创建一个等待组，用于等待所有goroutine完成
 	var wg sync.WaitGroup
-	for i := 0; i < tableParallel && i*limit <= count; i++ {
+	for _, q := range queries {
 		wg.Add(1)
-		// 分批读取数据
-		offset := i * limit
 		// 在每次循环开始前获取一个信号量
 		semaphore <- true
-		go func(mysqlSchema, yasdbSchema, mysqlTable, yasdbTable string, yasdbColumns []ColumnInfo, limit, offset int) {
+		go func(mysqlSchema, yasdbSchema, mysqlTable, yasdbTable string, yasdbColumns []ColumnInfo, q rangeQuery) {
 			defer wg.Done()
-			resultCount := syncTableDataFromMySQLToYasdbParallel(mysql, yasdb, mysqlSchema, yasdbSchema, mysqlTable, yasdbTable, yasdbColumns, limit, offset, batchSize)
-			totalCount = totalCount + resultCount
+			resultCount, ok := syncTableDataFromMySQLToYasdbParallel(mysql, yasdb, cp, snapshot, mysqlSchema, yasdbSchema, mysqlTable, yasdbTable, yasdbColumns, generatedColumns, typeMappings, q, batchSize)
+			atomic.AddInt64(&totalCount, int64(resultCount))
+			if !ok {
+				atomic.AddInt64(&failedCount, 1)
+			}
 			// 任务完成后释放信号量
 			<-semaphore
-		}(mysqlSchema, yasdbSchema, mysqlTable, yasdbTable, yasdbColumns, limit, offset)
+		}(mysqlSchema, yasdbSchema, mysqlTable, yasdbTable, yasdbColumns, q)
 	}
 	// 等待所有goroutine完成
 	wg.Wait()
 	elapsed := time.Since(start) // 计算经过的时间
-	log.Logger.Infof("表 %s.%s 同步完成, 迁移数据量: %d 耗时 %v\n", mysqlSchema, mysqlTable, totalCount, elapsed)
+	if failedCount > 0 {
+		log.Logger.Errorf("表 %s.%s 同步未完成, 迁移数据量: %d 耗时 %v, 可使用 sync --resume 继续同步\n", mysqlSchema, mysqlTable, totalCount, elapsed)
+		return totalCount
+	}
+	if err := cp.finishTable(tcp); err != nil {
+		log.Logger.Errorf("表 %s.%s 记录断点失败: %v", mysqlSchema, mysqlTable, err)
+	}
+	log.Logger.Infof("表 %s.%s 同步完成, 迁移数据量: %d 耗时 %v 速度: %.0f 行/秒\n", mysqlSchema, mysqlTable, totalCount, elapsed, rowsPerSecond(totalCount, elapsed))
+	return totalCount
+}
+
+// rowsPerSecond 计算每秒同步的行数
+func rowsPerSecond(rows int64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(rows) / elapsed.Seconds()
+}
+
+// splitTableByLimit 没有可用主键时, 按LIMIT/OFFSET切分数据
+func splitTableByLimit(mysqlSchema, mysqlTable string, count, tableParallel int) []rangeQuery {
+	//设置limit大小
+	var limit int
+	if count < min_chunk_rows {
+		tableParallel = 1
+		limit = min_chunk_rows
+	} else {
+		limit = count/tableParallel + 1
+	}
+	var queries []rangeQuery
+	for i := 0; i < tableParallel && i*limit <= count; i++ {
+		// 分批读取数据
+		offset := i * limit
+		queries = append(queries, rangeQuery{query: fmt.Sprintf(sqldef.M_SQL_QUERY_TABLE_DATA, mysqlSchema, mysqlTable, limit, offset)})
+	}
+	return queries
 }
 
 func getYasdbColumns(yasdb *sql.DB, yasdbSchema, yasdbTable string) ([]ColumnInfo, error) {
@@ -189,20 +328,23 @@ func getYasdbColumns(yasdb *sql.DB, yasdbSchema, yasdbTable string) ([]ColumnInf
 	return yasdbColumns, err
 }
 
-func syncTableDataFromMySQLToYasdbParallel(mysdb, yasdb *sql.DB, mysqlSchema, yasdbSchema, mysqlTable, yasdbTable string, yasdbColumns []ColumnInfo, limit, offset, batchSize int) int {
-	var resultCount int
-	var batchCount int
+// syncTableDataFromMySQLToYasdbParallel 同步一个分片的数据, 返回同步的行数以及分片是否完整同步
+func syncTableDataFromMySQLToYasdbParallel(mysdb, yasdb *sql.DB, cp *syncCheckpoint, snapshot *consistentSnapshot, mysqlSchema, yasdbSchema, mysqlTable, yasdbTable string, yasdbColumns []ColumnInfo, generatedColumns []string, typeMappings map[string]string, q rangeQuery, batchSize int) (int, bool) {
+	var resultCount, batchCount, readCount int
 	// 开始事务
 	targetTx, err := yasdb.Begin()
 	if err != nil {
 		log.Logger.Errorf("表 %s.%s 同步失败, 事务开始失败: %v", mysqlSchema, mysqlTable, err)
-		return 0
+		return 0, false
 	}
-	// 查询源表数据
-	rows, err := mysdb.Query(fmt.Sprintf(sqldef.M_SQL_QUERY_TABLE_DATA, mysqlSchema, mysqlTable, limit, offset))
+	// 查询源表数据, 开启一致性快照时使用快照会话查询
+	session, release := snapshot.acquire(mysdb)
+	defer release()
+	rows, err := session.Query(q.query, q.args...)
 	if err != nil {
+		_ = targetTx.Rollback()
 		log.Logger.Errorf("表 %s.%s 同步失败, 源端数据查询失败: %v", mysqlSchema, mysqlTable, err)
-		return 0
+		return 0, false
 	}
 	defer rows.Close()
 
@@ -210,8 +352,9 @@ func syncTableDataFromMySQLToYasdbParallel(mysdb, yasdb *sql.DB, mysqlSchema, ya
 	columns := []ColumnInfo{}
 	columnTypes, err := rows.ColumnTypes()
 	if err != nil {
+		_ = targetTx.Rollback()
 		log.Logger.Errorf("表 %s.%s 同步失败, 源端数据列信息获取失败: %v", mysqlSchema, mysqlTable, err)
-		return 0
+		return 0, false
 	}
 	for _, columnType := range columnTypes {
 		column := ColumnInfo{
@@ -220,6 +363,20 @@ func syncTableDataFromMySQLToYasdbParallel(mysdb, yasdb *sql.DB, mysqlSchema, ya
 		}
 		columns = append(columns, column)
 	}
+	// 配置了类型映射的列按映射后的类型转换
+	mappedTypes := make([]string, len(columns))
+	for i, column := range columns {
+		mappedTypes[i] = typeMappings[column.ColumnName]
+	}
+	inserter, err := newBatchInserter(yasdb, mysqlSchema, yasdbSchema, mysqlTable, yasdbTable, yasdbColumns, generatedColumns, batchSize)
+	if err != nil {
+		_ = targetTx.Rollback()
+		log.Logger.Errorf("表 %s.%s 同步失败, 目标端插入语句预编译失败: %v", mysqlSchema, mysqlTable, err)
+		return 0, false
+	}
+	defer inserter.close()
+	keyIndexes := q.keyIndexes(columns)
+	ok := true
 	for rows.Next() {
 		// 准备值的切片
 		values := make([]interface{}, len(columns))
@@ -230,57 +387,131 @@ func syncTableDataFromMySQLToYasdbParallel(mysdb, yasdb *sql.DB, mysqlSchema, ya
 		err := rows.Scan(valuePointers...)
 		if err != nil {
 			log.Logger.Errorf("表 %s.%s 同步失败, 源端数据查询失败: %v", mysqlSchema, mysqlTable, err)
+			ok = false
 			break
 		}
 		yashanValues := make([]interface{}, len(values))
 		for i, value := range values {
 			// fmt.Println(columns[i].ColumnType)
-			yashanValues[i] = convertValueFromMySQLToYashan(value, columns[i].ColumnType)
+			yashanValues[i], err = convertValueWithTypeMapping(value, columns[i].ColumnType, mappedTypes[i])
+			if err != nil {
+				log.Logger.Errorf("表 %s.%s 同步失败, 列 %s 的值转换失败: %v", mysqlSchema, mysqlTable, columns[i].ColumnName, err)
+				ok = false
+				break
+			}
+		}
+		if !ok {
+			break
 		}
-		// 构建YashanDB插入语句
-		yashanInsertSQL := buildYashanInsertSQL(yasdbSchema, yasdbTable, yasdbColumns)
-		_, err = targetTx.Exec(yashanInsertSQL, yashanValues...)
+		var key [][]byte
+		if keyIndexes != nil {
+			key = make([][]byte, 0, len(keyIndexes))
+			for _, idx := range keyIndexes {
+				key = append(key, keyValueToBytes(values[idx]))
+			}
+		}
+		inserted, err := inserter.add(targetTx, yashanValues, key)
+		batchCount += inserted
 		if err != nil {
-			log.Logger.Errorf("表 %s.%s 同步失败, 目标端数据插入失败, sql: %s value: %v, err: %v", mysqlSchema, mysqlTable, yashanInsertSQL, yashanValues, err)
-			continue
+			ok = false
+			break
 		}
 		// 计数器递增
-		batchCount++
-		resultCount++
+		readCount++
 		// 达到批次提交的数据量上限时,执行提交操作
-		if batchCount >= batchSize {
+		if readCount >= batchSize {
+			inserted, err = inserter.flush(targetTx)
+			batchCount += inserted
+			if err != nil {
+				ok = false
+				break
+			}
 			err = targetTx.Commit()
 			if err != nil {
 				log.Logger.Errorf("表 %s.%s 同步失败, 事务提交失败: %v", mysqlSchema, mysqlTable, err)
-				break
+				return resultCount, false
 			}
+			q.commit(cp, inserter.lastKey, batchCount)
+			resultCount += batchCount
 			// 重置计数器
 			batchCount = 0
+			readCount = 0
 			// 开始新的事务
 			targetTx, err = yasdb.Begin()
 			if err != nil {
 				log.Logger.Errorf("表 %s.%s 同步失败, 事务开始失败: %v", mysqlSchema, mysqlTable, err)
-				break
+				return resultCount, false
 			}
 		}
 	}
+	if err := rows.Err(); err != nil {
+		log.Logger.Errorf("表 %s.%s 同步失败, 源端数据查询失败: %v", mysqlSchema, mysqlTable, err)
+		ok = false
+	}
+	// 出错时也写入出错之前缓存的行, 断点记录到最后一行写入成功的数据
+	inserted, err := inserter.flush(targetTx)
+	batchCount += inserted
+	if err != nil {
+		ok = false
+	}
 	err = targetTx.Commit()
 	if err != nil {
 		log.Logger.Errorf("表 %s.%s 同步失败, 事务提交失败: %v", mysqlSchema, mysqlTable, err)
-		return resultCount
-	}
-	// 执行最后一批数据的提交操作
-	// if batchCount > 0 {
-	// err = targetTx.Commit()
-	// if err != nil {
-	// 	log.Logger.Errorf("表 %s.%s 同步失败, 事务提交失败: %v", mysqlSchema, mysqlTable, err)
-	// 	return resultCount
-	// }
-	// }
-	return resultCount
+		return resultCount, false
+	}
+	q.commit(cp, inserter.lastKey, batchCount)
+	resultCount += batchCount
+	if ok {
+		q.finish(cp)
+	}
+	return resultCount, ok
 }
 
-func getMySQLTableCount(mysdb *sql.DB, schema, table string, opts ...queryFunc) (count int, err error) {
+// keyIndexes 返回主键列在结果集中的位置, 不按主键切分时返回nil
+func (q rangeQuery) keyIndexes(columns []ColumnInfo) []int {
+	if q.key == nil || q.chunk == nil {
+		return nil
+	}
+	var indexes []int
+	for _, keyColumn := range q.key.Columns {
+		for i, column := range columns {
+			if strings.EqualFold(column.ColumnName, keyColumn) {
+				indexes = append(indexes, i)
+				break
+			}
+		}
+	}
+	if len(indexes) != len(q.key.Columns) {
+		return nil
+	}
+	return indexes
+}
+
+func (q rangeQuery) commit(cp *syncCheckpoint, lastKey [][]byte, rows int) {
+	if q.chunk == nil {
+		return
+	}
+	if err := cp.commitChunk(q.chunk, lastKey, rows); err != nil {
+		log.Logger.Errorf("记录断点失败: %v", err)
+	}
+}
+
+func (q rangeQuery) finish(cp *syncCheckpoint) {
+	if q.chunk == nil {
+		return
+	}
+	if err := cp.finishChunk(q.chunk); err != nil {
+		log.Logger.Errorf("记录断点失败: %v", err)
+	}
+}
+
+func truncateYasdbTable(yasdb *sql.DB, yasdbSchema, yasdbTable string) error {
+	formatter := getSQLFormatter(sqldef.Y_SQL_TRUNCATE_TABLE, sqldef.Y_SQL_TRUNCATE_TABLE_CASE_SENSITIVE)
+	_, err := yasdb.Exec(fmt.Sprintf(formatter, formatKeyWord(yasdbSchema), formatKeyWord(yasdbTable)))
+	return err
+}
+
+func getMySQLTableCount(mysdb mysqlQueryer, schema, table string, opts ...queryFunc) (count int, err error) {
 	sql := fmt.Sprintf(sqldef.M_SQL_QUERY_TABLE_COUNT, schema, table)
 	for _, opt := range opts {
 		sql = opt(sql)
@@ -307,41 +538,51 @@ func unWarpQuote(s string) string {
 }
 
 // 将值转换为YashanDB类型
-func convertValueFromMySQLToYashan(value interface{}, columnType string) interface{} {
+func convertValueFromMySQLToYashan(value interface{}, columnType string) (interface{}, error) {
 	if value != nil {
 		switch columnType {
-		case "DATETIME", "TIMESTAMP":
-			t, _ := time.Parse("2006-01-02 15:04:05", string(value.([]uint8)))
-			cstLocation, _ := time.LoadLocation("Asia/Shanghai")
-			return t.In(cstLocation)
-		case "DATE":
-			t, _ := time.Parse("2006-01-02", string(value.([]uint8)))
-			cstLocation, _ := time.LoadLocation("Asia/Shanghai")
-			return t.In(cstLocation)
+		case "DATETIME", "TIMESTAMP", "DATE":
+			if str, ok := value.([]uint8); ok {
+				return convertMySQLDateTime(string(str), columnType)
+			}
+			return value, nil
+		case "TIME":
+			if str, ok := value.([]uint8); ok {
+				return convertMySQLTime(string(str))
+			}
+			return value, nil
 		case "YEAR":
 			var v string
 			switch val := value.(type) {
 			case []uint8:
 				v = string(value.([]uint8))
 			case uint, uint8, uint16, uint32, uint64, int, int8, int16, int32, int64:
-				return val
+				return val, nil
 			default:
 				v = fmt.Sprint(value)
 			}
 			year, _ := strconv.ParseInt(v, 10, 64)
-			return year
-		case "JSON", "BLOB", "VARBINARY", "BINARY", "MEDIUMBLOB", "LONGBLOB":
-			return value
+			return year, nil
+		case "JSON", "BLOB", "VARBINARY", "BINARY", "TINYBLOB", "MEDIUMBLOB", "LONGBLOB":
+			return value, nil
 		case "BIT":
-			return uint8SliceToInt(value.([]uint8))
+			return uint8SliceToInt(value.([]uint8)), nil
+		case "GEOMETRY":
+			// MySQL的存储格式为SRID+WKB, 转换为WKT和SRID, 无法解析时按原值插入
+			if data, ok := value.([]uint8); ok {
+				if geometry, err := decodeMySQLGeometry(data); err == nil {
+					return geometry, nil
+				}
+			}
+			return value, nil
 		default:
 			if str, ok := value.([]uint8); ok {
-				return string(str)
+				return string(str), nil
 			}
-			return value
+			return value, nil
 		}
 	} else {
-		return value
+		return value, nil
 	}
 }
 
@@ -352,19 +593,3 @@ func uint8SliceToInt(slice []uint8) int {
 	}
 	return result
 }
-
-// 构建YashanDB插入语句
-func buildYashanInsertSQL(yasdbSchema, tableName string, columns []ColumnInfo) string {
-	caseSensitive := confdef.GetM2YConfig().Yashan.CaseSensitive
-	var columnNames, placeholders []string
-	for _, column := range columns {
-		columnName := column.ColumnName
-		if caseSensitive {
-			columnName = fmt.Sprintf("\"%s\"", column.ColumnName)
-		}
-		columnNames = append(columnNames, formatKeyWord(columnName))
-		placeholders = append(placeholders, "?")
-	}
-	formatter := getSQLFormatter(sqldef.Y_SQL_INSERT_DATA, sqldef.Y_SQL_INSERT_DATA_CASE_SENSITIVE)
-	return fmt.Sprintf(formatter, formatKeyWord(yasdbSchema), formatKeyWord(tableName), strings.Join(columnNames, ","), strings.Join(placeholders, ","))
-}
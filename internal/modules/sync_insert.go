@@ -0,0 +1,212 @@
+package modules
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"m2y/defs/confdef"
+	"m2y/defs/sqldef"
+	"m2y/log"
+)
+
+const (
+	// 单条INSERT语句中绑定参数个数的上限
+	max_insert_bind_params = 65535
+)
+
+// batchInserter 将多行数据合并为一条多行VALUES的INSERT语句写入YashanDB, 每个并行任务使用一个实例
+type batchInserter struct {
+	mysqlSchema   string
+	mysqlTable    string
+	yasdbSchema   string
+	yasdbTable    string
+	yasdbColumns  []ColumnInfo
+	generated     []bool // 与源端的列一一对应, 生成列的值不插入
+	rowSQL        string
+	batchStmt     *sql.Stmt
+	rowsPerInsert int
+	pending       [][]interface{}
+	pendingKeys   [][][]byte // 与pending一一对应的主键值, 不按主键切分时为nil
+	lastKey       [][]byte   // 最后一行写入成功的数据的主键值, 用于记录断点
+}
+
+// newBatchInserter 预编译多行插入语句, 一条语句包含的行数不超过batchSize且绑定参数个数不超过上限,
+// generatedColumns为MySQL表的生成列, 插入时跳过目标表中对应的虚拟列
+func newBatchInserter(yasdb *sql.DB, mysqlSchema, yasdbSchema, mysqlTable, yasdbTable string, yasdbColumns []ColumnInfo, generatedColumns []string, batchSize int) (*batchInserter, error) {
+	var generated []bool
+	if len(generatedColumns) != 0 {
+		generated = markGeneratedColumns(yasdbColumns, generatedColumns)
+		yasdbColumns = excludeGeneratedColumns(yasdbColumns, generatedColumns)
+	}
+	rowsPerInsert := batchSize
+	if maxRows := max_insert_bind_params / countBindParams(yasdbColumns); rowsPerInsert > maxRows {
+		rowsPerInsert = maxRows
+	}
+	if rowsPerInsert < 1 {
+		rowsPerInsert = 1
+	}
+	b := &batchInserter{
+		mysqlSchema:   mysqlSchema,
+		mysqlTable:    mysqlTable,
+		yasdbSchema:   yasdbSchema,
+		yasdbTable:    yasdbTable,
+		yasdbColumns:  yasdbColumns,
+		generated:     generated,
+		rowSQL:        buildYashanInsertSQL(yasdbSchema, yasdbTable, yasdbColumns),
+		rowsPerInsert: rowsPerInsert,
+	}
+	stmt, err := yasdb.Prepare(buildYashanBatchInsertSQL(yasdbSchema, yasdbTable, yasdbColumns, rowsPerInsert))
+	if err != nil {
+		return nil, err
+	}
+	b.batchStmt = stmt
+	return b, nil
+}
+
+// add 缓存一行数据, key为该行的主键值, 缓存的行数达到一条语句的行数时写入YashanDB, 返回写入成功的行数
+func (b *batchInserter) add(tx *sql.Tx, values []interface{}, key [][]byte) (int, error) {
+	if b.generated != nil {
+		values = skipGeneratedValues(values, b.generated)
+	}
+	b.pending = append(b.pending, values)
+	b.pendingKeys = append(b.pendingKeys, key)
+	if len(b.pending) < b.rowsPerInsert {
+		return 0, nil
+	}
+	return b.flush(tx)
+}
+
+// flush 将缓存的数据写入YashanDB, 返回写入成功的行数
+// 多行插入失败时逐行重新插入, 以便定位出错的行, 遇到出错的行时不再插入之后的行并返回错误,
+// 断点只记录到出错的行之前, 断点续传时从出错的行开始重新同步
+func (b *batchInserter) flush(tx *sql.Tx) (int, error) {
+	if len(b.pending) == 0 {
+		return 0, nil
+	}
+	defer func() {
+		b.pending = b.pending[:0]
+		b.pendingKeys = b.pendingKeys[:0]
+	}()
+	args := make([]interface{}, 0, len(b.pending)*countBindParams(b.yasdbColumns))
+	for _, row := range b.pending {
+		args = append(args, bindYashanValues(b.yasdbColumns, row)...)
+	}
+	var err error
+	if len(b.pending) == b.rowsPerInsert {
+		_, err = tx.Stmt(b.batchStmt).Exec(args...)
+	} else {
+		_, err = tx.Exec(buildYashanBatchInsertSQL(b.yasdbSchema, b.yasdbTable, b.yasdbColumns, len(b.pending)), args...)
+	}
+	if err == nil {
+		b.lastKey = b.pendingKeys[len(b.pendingKeys)-1]
+		return len(b.pending), nil
+	}
+	log.Logger.Warnf("表 %s.%s 批量插入失败, 改为逐行插入: %v", b.mysqlSchema, b.mysqlTable, err)
+	for i, row := range b.pending {
+		if _, err := tx.Exec(b.rowSQL, bindYashanValues(b.yasdbColumns, row)...); err != nil {
+			log.Logger.Errorf("表 %s.%s 同步失败, 目标端数据插入失败, sql: %s value: %v, err: %v", b.mysqlSchema, b.mysqlTable, b.rowSQL, row, err)
+			return i, fmt.Errorf("目标端数据插入失败: %v", err)
+		}
+		b.lastKey = b.pendingKeys[i]
+	}
+	return len(b.pending), nil
+}
+
+func (b *batchInserter) close() {
+	if b.batchStmt != nil {
+		_ = b.batchStmt.Close()
+	}
+}
+
+// getMySQLGeneratedColumns 查询表的生成列, 生成列在YashanDB中是虚拟列, 同步时不能插入
+func getMySQLGeneratedColumns(mysql *sql.DB, mysqlSchema, tableName string) ([]string, error) {
+	rows, err := mysql.Query(sqldef.M_SQL_QUERY_GENERATED_COLUMNS, mysqlSchema, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("查询生成列 information_schema.columns 出错: %v", err)
+	}
+	defer rows.Close()
+	var columns []string
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return nil, fmt.Errorf("查询生成列 information_schema.columns 出错: %v", err)
+		}
+		columns = append(columns, column)
+	}
+	return columns, rows.Err()
+}
+
+// markGeneratedColumns 返回每一列是否为生成列, 大小写不敏感时YashanDB的列名为大写, 按忽略大小写比较
+func markGeneratedColumns(columns []ColumnInfo, generatedColumns []string) []bool {
+	generated := make([]bool, len(columns))
+	for i, column := range columns {
+		for _, generatedColumn := range generatedColumns {
+			if strings.EqualFold(column.ColumnName, generatedColumn) {
+				generated[i] = true
+				break
+			}
+		}
+	}
+	return generated
+}
+
+// excludeGeneratedColumns 去掉生成列, 返回需要插入的列
+func excludeGeneratedColumns(columns []ColumnInfo, generatedColumns []string) []ColumnInfo {
+	generated := markGeneratedColumns(columns, generatedColumns)
+	var result []ColumnInfo
+	for i, column := range columns {
+		if !generated[i] {
+			result = append(result, column)
+		}
+	}
+	return result
+}
+
+// skipGeneratedValues 去掉一行中生成列的值
+func skipGeneratedValues(values []interface{}, generated []bool) []interface{} {
+	result := make([]interface{}, 0, len(values))
+	for i, value := range values {
+		if i >= len(generated) || !generated[i] {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// 构建YashanDB插入语句
+func buildYashanInsertSQL(yasdbSchema, tableName string, columns []ColumnInfo) string {
+	return buildYashanBatchInsertSQL(yasdbSchema, tableName, columns, 1)
+}
+
+// 构建YashanDB多行插入语句
+func buildYashanBatchInsertSQL(yasdbSchema, tableName string, columns []ColumnInfo, rows int) string {
+	var columnNames, placeholders []string
+	for _, column := range columns {
+		columnNames = append(columnNames, formatYashanColumnName(column.ColumnName))
+		placeholders = append(placeholders, getYashanPlaceholder(column))
+	}
+	rowPlaceholder := strings.Join(placeholders, ",")
+	values := make([]string, rows)
+	for i := range values {
+		values[i] = rowPlaceholder
+	}
+	formatter := getSQLFormatter(sqldef.Y_SQL_INSERT_DATA, sqldef.Y_SQL_INSERT_DATA_CASE_SENSITIVE)
+	return fmt.Sprintf(formatter, formatKeyWord(yasdbSchema), formatKeyWord(tableName), strings.Join(columnNames, ","), strings.Join(values, "),("))
+}
+
+// getYashanPlaceholder 返回列的绑定参数, 空间数据列按WKT和SRID构造
+func getYashanPlaceholder(column ColumnInfo) string {
+	if isYashanSpatialColumn(column) {
+		return sqldef.Y_SQL_GEOMETRY_PLACEHOLDER
+	}
+	return "?"
+}
+
+// formatYashanColumnName 按大小写配置格式化YashanDB列名
+func formatYashanColumnName(columnName string) string {
+	if confdef.GetM2YConfig().Yashan.CaseSensitive {
+		columnName = fmt.Sprintf("\"%s\"", columnName)
+	}
+	return formatKeyWord(columnName)
+}
@@ -0,0 +1,135 @@
+package modules
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"m2y/defs/sqldef"
+	"m2y/log"
+)
+
+// mysqlQueryer 读取MySQL数据的接口, *sql.DB和一致性快照的会话都实现了该接口
+type mysqlQueryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// mysqlSession 固定使用连接池中的一个连接, 会话级的事务在该连接上一直有效
+type mysqlSession struct {
+	conn *sql.Conn
+}
+
+func (s *mysqlSession) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return s.conn.QueryContext(context.Background(), query, args...)
+}
+
+func (s *mysqlSession) QueryRow(query string, args ...interface{}) *sql.Row {
+	return s.conn.QueryRowContext(context.Background(), query, args...)
+}
+
+func (s *mysqlSession) exec(query string) error {
+	_, err := s.conn.ExecContext(context.Background(), query)
+	return err
+}
+
+// consistentSnapshot 与mydumper相同, 在全局读锁下为每个并行任务开启一个一致性快照事务,
+// 所有会话读到的是同一时间点的数据, 同时记录该时间点的binlog位置
+type consistentSnapshot struct {
+	sessions chan *mysqlSession
+	all      []*mysqlSession
+	pos      *binlogPosition
+}
+
+// newConsistentSnapshot 开启count个共享同一快照的会话, 需要RELOAD权限执行FLUSH TABLES WITH READ LOCK
+func newConsistentSnapshot(mysql *sql.DB, count int) (*consistentSnapshot, error) {
+	conn, err := mysql.Conn(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	lock := &mysqlSession{conn: conn}
+	defer conn.Close()
+	log.Logger.Infof("开始创建一致性快照, 会话数: %d", count)
+	if err := lock.exec(sqldef.M_SQL_FLUSH_TABLES_WITH_READ_LOCK); err != nil {
+		return nil, fmt.Errorf("加全局读锁失败, 请确认用户有RELOAD权限: %v", err)
+	}
+	// 所有会话都开启快照之后再释放全局读锁, 保证各会话的快照在同一时间点
+	defer func() {
+		if err := lock.exec(sqldef.M_SQL_UNLOCK_TABLES); err != nil {
+			log.Logger.Errorf("释放全局读锁失败: %v", err)
+		}
+	}()
+	s := &consistentSnapshot{sessions: make(chan *mysqlSession, count)}
+	for i := 0; i < count; i++ {
+		conn, err := mysql.Conn(context.Background())
+		if err != nil {
+			s.close()
+			return nil, err
+		}
+		session := &mysqlSession{conn: conn}
+		s.all = append(s.all, session)
+		if err := session.exec(sqldef.M_SQL_SET_REPEATABLE_READ); err != nil {
+			s.close()
+			return nil, fmt.Errorf("设置事务隔离级别失败: %v", err)
+		}
+		if err := session.exec(sqldef.M_SQL_START_CONSISTENT_SNAPSHOT); err != nil {
+			s.close()
+			return nil, fmt.Errorf("开启一致性快照失败: %v", err)
+		}
+		s.sessions <- session
+	}
+	s.pos, err = queryBinlogPosition(lock)
+	if err != nil {
+		log.Logger.Warnf("查询一致性快照的binlog位置失败: %v", err)
+	} else {
+		log.Logger.Infof("一致性快照创建完成, binlog位置 %s:%d gtid: %s", s.pos.File, s.pos.Pos, s.pos.GTIDSet)
+	}
+	return s, nil
+}
+
+// acquire 返回读取表数据使用的会话, 使用完后需要调用release归还, 未开启一致性快照时直接使用连接池
+func (s *consistentSnapshot) acquire(mysql *sql.DB) (mysqlQueryer, func()) {
+	if s == nil {
+		return mysql, func() {}
+	}
+	session := <-s.sessions
+	return session, func() { s.sessions <- session }
+}
+
+// binlogPosition 返回快照对应的binlog位置, 未开启一致性快照或查询失败时返回nil
+func (s *consistentSnapshot) binlogPosition() *binlogPosition {
+	if s == nil {
+		return nil
+	}
+	return s.pos
+}
+
+// close 结束快照事务并归还连接
+func (s *consistentSnapshot) close() {
+	if s == nil {
+		return
+	}
+	for _, session := range s.all {
+		_ = session.exec(sqldef.M_SQL_COMMIT)
+		_ = session.conn.Close()
+	}
+	s.all = nil
+}
+
+// openConsistentSnapshot 按需开启一致性快照, 未开启时返回nil
+func openConsistentSnapshot(mysql *sql.DB, enabled, resume bool, count int) (*consistentSnapshot, error) {
+	if !enabled {
+		return nil, nil
+	}
+	if resume {
+		log.Logger.Warnf("断点续传时会创建新的一致性快照, 已同步完成的数据与本次同步的数据不在同一时间点")
+	}
+	return newConsistentSnapshot(mysql, count)
+}
+
+// logSnapshotPosition 在同步结束时输出一致性快照的binlog位置
+func logSnapshotPosition(snapshot *consistentSnapshot) {
+	if pos := snapshot.binlogPosition(); pos != nil {
+		log.Logger.Infof("一致性快照的binlog位置: %s:%d gtid: %s", pos.File, pos.Pos, pos.GTIDSet)
+	}
+}
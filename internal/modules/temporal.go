@@ -0,0 +1,173 @@
+package modules
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"m2y/defs/confdef"
+)
+
+const (
+	mysql_datetime_layout = "2006-01-02 15:04:05.999999"
+	mysql_date_layout     = "2006-01-02"
+
+	// YashanDB的TIME只能保存一天之内的时间, 超出范围的列需要映射为yashan_interval_type
+	yashan_max_time      = 24 * time.Hour
+	yashan_interval_type = "interval day to second"
+)
+
+var mysqlTimeRegexp = regexp.MustCompile(`^(-)?(\d+):(\d{2}):(\d{2})(?:\.(\d{1,6}))?$`)
+
+// timeValue MySQL TIME的值, 数据校验时按时长比较, 作为绑定参数时使用原始文本
+type timeValue struct {
+	text     string
+	duration time.Duration
+}
+
+func (t *timeValue) String() string {
+	return t.text
+}
+
+func (t *timeValue) Value() (driver.Value, error) {
+	return t.text, nil
+}
+
+// isMySQLZeroDate 是否为MySQL的零值日期, 年月日中月或日为0的日期在YashanDB中都无法保存
+func isMySQLZeroDate(text string) bool {
+	if len(text) < len(mysql_date_layout) {
+		return false
+	}
+	return text[5:7] == "00" || text[8:10] == "00"
+}
+
+// convertMySQLDateTime 将MySQL的DATE、DATETIME、TIMESTAMP文本转换为time.Time, 保留微秒,
+// DATETIME和TIMESTAMP从source_time_zone转换到target_time_zone, DATE按target_time_zone解析, 不转换时区,
+// 零值日期按zero_date处理, 返回nil表示写入NULL
+func convertMySQLDateTime(text, columnType string) (interface{}, error) {
+	text = strings.TrimSpace(text)
+	if isMySQLZeroDate(text) {
+		switch confdef.GetM2YConfig().Yashan.ZeroDate {
+		case confdef.ZERO_DATE_MIN:
+			return time.Date(1, time.January, 1, 0, 0, 0, 0, confdef.GetTargetLocation()), nil
+		case confdef.ZERO_DATE_FAIL:
+			return nil, fmt.Errorf("零值日期 %s 无法写入YashanDB, 可以配置zero_date为null或min", text)
+		}
+		return nil, nil
+	}
+	if columnType == "DATE" {
+		t, err := time.ParseInLocation(mysql_date_layout, text, confdef.GetTargetLocation())
+		if err != nil {
+			return nil, fmt.Errorf("无法解析日期 %s: %v", text, err)
+		}
+		return t, nil
+	}
+	t, err := time.ParseInLocation(mysql_datetime_layout, text, confdef.GetSourceLocation())
+	if err != nil {
+		return nil, fmt.Errorf("无法解析时间 %s: %v", text, err)
+	}
+	return t.In(confdef.GetTargetLocation()), nil
+}
+
+// parseMySQLTime 解析MySQL的TIME, 取值范围为-838:59:59.000000到838:59:59.000000, 不只是一天之内的时间
+func parseMySQLTime(text string) (time.Duration, error) {
+	matches := mysqlTimeRegexp.FindStringSubmatch(strings.TrimSpace(text))
+	if matches == nil {
+		return 0, fmt.Errorf("无法解析时间 %s", text)
+	}
+	hours, _ := strconv.ParseInt(matches[2], 10, 64)
+	minutes, _ := strconv.ParseInt(matches[3], 10, 64)
+	seconds, _ := strconv.ParseInt(matches[4], 10, 64)
+	var micros int64
+	if len(matches[5]) != 0 {
+		micros, _ = strconv.ParseInt((matches[5] + "00000")[:6], 10, 64)
+	}
+	d := time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second + time.Duration(micros)*time.Microsecond
+	if len(matches[1]) != 0 {
+		d = -d
+	}
+	return d, nil
+}
+
+// convertMySQLTime 将MySQL的TIME转换为YashanDB的TIME, 超出一天的范围时无法写入,
+// 需要在[[type_mapping.columns]]中将该列映射为interval day to second
+func convertMySQLTime(text string) (interface{}, error) {
+	d, err := parseMySQLTime(text)
+	if err != nil {
+		return nil, err
+	}
+	if d < 0 || d >= yashan_max_time {
+		return nil, fmt.Errorf("TIME值 %s 超出YashanDB TIME的范围, 请在[[type_mapping.columns]]中将该列映射为%s, precheck可以列出所有超出范围的列", text, yashan_interval_type)
+	}
+	return formatTimeOfDay(d), nil
+}
+
+// formatTimeOfDay 将一天之内的时间格式化为HH:MI:SS.FFFFFF
+func formatTimeOfDay(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d:%02d.%06d", int64(d/time.Hour), int64(d%time.Hour/time.Minute), int64(d%time.Minute/time.Second), int64(d%time.Second/time.Microsecond))
+}
+
+// formatIntervalDayToSecond 将时长格式化为INTERVAL DAY TO SECOND的文本, 如+01 10:59:59.000000
+func formatIntervalDayToSecond(d time.Duration) string {
+	sign := "+"
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	days := d / (24 * time.Hour)
+	return fmt.Sprintf("%s%02d %s", sign, int64(days), formatTimeOfDay(d-days*24*time.Hour))
+}
+
+// isYashanIntervalType 是否为INTERVAL DAY TO SECOND类型
+func isYashanIntervalType(yasType string) bool {
+	return strings.HasPrefix(getYashanTypeBaseName(yasType), "interval day")
+}
+
+// parseYashanInterval 解析YashanDB返回的INTERVAL DAY TO SECOND文本, 如+01 10:59:59.000000
+func parseYashanInterval(text string) (time.Duration, error) {
+	text = strings.TrimSpace(text)
+	negative := strings.HasPrefix(text, "-")
+	text = strings.TrimLeft(text, "+-")
+	var days int64
+	if i := strings.Index(text, " "); i >= 0 {
+		var err error
+		if days, err = strconv.ParseInt(text[:i], 10, 64); err != nil {
+			return 0, fmt.Errorf("无法解析时间间隔 %s", text)
+		}
+		text = strings.TrimSpace(text[i+1:])
+	}
+	d, err := parseMySQLTime(text)
+	if err != nil {
+		return 0, err
+	}
+	d += time.Duration(days) * 24 * time.Hour
+	if negative {
+		d = -d
+	}
+	return d, nil
+}
+
+// isTimeEqual 比较MySQL的TIME与YashanDB的TIME或INTERVAL DAY TO SECOND
+func isTimeEqual(d time.Duration, v interface{}) bool {
+	switch value := v.(type) {
+	case time.Duration:
+		return d == value
+	case time.Time:
+		// TIME只比较一天之内的时间, 不比较日期部分
+		return d == time.Duration(value.Hour())*time.Hour+time.Duration(value.Minute())*time.Minute+
+			time.Duration(value.Second())*time.Second+time.Duration(value.Nanosecond())
+	case []uint8:
+		return isTimeEqual(d, string(value))
+	case string:
+		other, err := parseYashanInterval(value)
+		return err == nil && d == other
+	}
+	return false
+}
+
+// inTargetLocation YashanDB返回的时间按target_time_zone解释, 不受驱动使用的时区影响
+func inTargetLocation(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), confdef.GetTargetLocation())
+}
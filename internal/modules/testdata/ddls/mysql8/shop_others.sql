@@ -0,0 +1,30 @@
+ALTER TABLE SHOP.customers modify id NOT NULL;
+
+ALTER TABLE SHOP.customers modify name NOT NULL;
+
+ALTER TABLE SHOP.customers modify created NOT NULL;
+ALTER TABLE SHOP.customers ADD CONSTRAINT chk_email CHECK ("EMAIL" like '%@%');
+ALTER TABLE SHOP.orders modify id NOT NULL;
+
+ALTER TABLE SHOP.orders modify customer_id NOT NULL;
+
+ALTER TABLE SHOP.orders modify amount NOT NULL;
+
+ALTER TABLE SHOP.orders modify status NOT NULL;
+ALTER TABLE SHOP.orders ADD CHECK (status IN ('new', 'paid'));
+
+--再创建数据库内的索引
+ALTER TABLE SHOP.customers ADD PRIMARY KEY (id);
+CREATE UNIQUE INDEX SHOP.uk_email ON SHOP.customers (email);
+
+ALTER TABLE SHOP.customers ADD CONSTRAINT uk_email UNIQUE (email);
+CREATE INDEX SHOP.idx_email_lower ON SHOP.customers (lower("EMAIL"));
+
+CREATE INDEX SHOP.idx_name_created ON SHOP.customers (name, created);
+ALTER TABLE SHOP.orders ADD PRIMARY KEY (id);
+CREATE INDEX SHOP.idx_customer_status ON SHOP.orders (customer_id, status);
+
+CREATE INDEX SHOP.idx_note ON SHOP.orders (SUBSTR(note, 1, 20));
+
+--最后创建外键约束
+ALTER TABLE SHOP.orders ADD CONSTRAINT fk_orders_customer FOREIGN KEY (customer_id) REFERENCES SHOP.customers(id) ON DELETE CASCADE;
@@ -0,0 +1,35 @@
+SET DEFINE OFF;
+--先创建数据库内的表,列默认值,自增序列,列注释
+CREATE TABLE SHOP.customers (
+	id bigint,
+	name varchar(64 char),
+	email varchar(128 char),
+	created timestamp default CURRENT_TIMESTAMP
+);
+
+COMMENT ON COLUMN SHOP.customers.name IS '客户姓名';
+
+COMMENT ON COLUMN SHOP.customers.email IS '联系邮箱';
+
+CREATE SEQUENCE SHOP.SEQ_CUSTOMERS_ID START WITH 101 INCREMENT BY 1;
+
+ALTER TABLE SHOP.customers MODIFY id DEFAULT SHOP.SEQ_CUSTOMERS_ID.NEXTVAL;
+COMMENT ON TABLE SHOP.customers IS '客户表' ;
+CREATE TABLE SHOP.orders (
+	id bigint,
+	customer_id bigint,
+	amount number(10, 2) default '0.00',
+	amount_cents bigint GENERATED ALWAYS AS (("AMOUNT" * 100)) VIRTUAL,
+	status varchar(4 char) default 'new',
+	note clob
+);
+
+COMMENT ON COLUMN SHOP.orders.customer_id IS '客户ID';
+
+COMMENT ON COLUMN SHOP.orders.amount IS '订单金额';
+
+COMMENT ON COLUMN SHOP.orders.note IS '备注';
+
+CREATE SEQUENCE SHOP.SEQ_ORDERS_ID START WITH 5001 INCREMENT BY 1;
+
+ALTER TABLE SHOP.orders MODIFY id DEFAULT SHOP.SEQ_ORDERS_ID.NEXTVAL;
@@ -0,0 +1,134 @@
+package modules
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"m2y/defs/confdef"
+	"m2y/defs/sqldef"
+	"m2y/defs/typedef"
+)
+
+// getYashanColumnType 返回列在YashanDB中的类型, [type_mapping]中配置的类型优先于内置的映射,
+// 内置的映射先按column_type匹配, 以区分unsigned等属性, 再按data_type匹配
+func getYashanColumnType(mysqlSchema, tableName, columnName, dataType, columnType string) (string, error) {
+	if yasType, ok := confdef.GetColumnTypeMapping(mysqlSchema, tableName, columnName, dataType, columnType); ok {
+		return yasType, nil
+	}
+	if yasType, err := typedef.MySQLToYasType(columnType); err == nil {
+		return yasType, nil
+	}
+	return typedef.MySQLToYasType(dataType)
+}
+
+// getMySQLColumnTypeMappings 查询表中按[type_mapping]改变了类型的列, 返回列名到YashanDB类型的映射,
+// 同步数据时按映射后的类型转换这些列的值, 没有配置类型映射时不查询
+func getMySQLColumnTypeMappings(mysql *sql.DB, mysqlSchema, tableName string) (map[string]string, error) {
+	if !confdef.HasTypeMapping() {
+		return nil, nil
+	}
+	rows, err := mysql.Query(sqldef.M_SQL_QUERY_BINLOG_COLUMNS, mysqlSchema, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("查询表属性 information_schema.columns 出错: %v", err)
+	}
+	defer rows.Close()
+	mappings := make(map[string]string)
+	for rows.Next() {
+		var name, dataType, columnType, extra, charset string
+		if err := rows.Scan(&name, &dataType, &columnType, &extra, &charset); err != nil {
+			return nil, fmt.Errorf("查询表属性 information_schema.columns 出错: %v", err)
+		}
+		if yasType, ok := confdef.GetColumnTypeMapping(mysqlSchema, tableName, name, dataType, columnType); ok {
+			mappings[name] = yasType
+		}
+	}
+	return mappings, rows.Err()
+}
+
+// getYashanTypeBaseName 去掉类型的长度和精度, 如number(20,4)返回number
+func getYashanTypeBaseName(yasType string) string {
+	if i := strings.Index(yasType, "("); i >= 0 {
+		yasType = yasType[:i]
+	}
+	return strings.TrimSpace(strings.ToLower(yasType))
+}
+
+// convertValueWithTypeMapping 将值转换为YashanDB类型, mappedType为[type_mapping]配置的类型,
+// 为空时与convertValueFromMySQLToYashan相同
+func convertValueWithTypeMapping(value interface{}, columnType, mappedType string) (interface{}, error) {
+	if value == nil || len(mappedType) == 0 {
+		return convertValueFromMySQLToYashan(value, columnType)
+	}
+	if isYashanIntervalType(mappedType) && columnType == "TIME" {
+		// MySQL的TIME可以超过24小时, 按INTERVAL DAY TO SECOND的文本写入
+		if str, ok := value.([]uint8); ok {
+			d, err := parseMySQLTime(string(str))
+			if err != nil {
+				return nil, err
+			}
+			return formatIntervalDayToSecond(d), nil
+		}
+	}
+	switch getYashanTypeBaseName(mappedType) {
+	case typedef.Y_BOOLEAN:
+		return convertValueToBoolean(value, columnType)
+	case typedef.Y_CHAR, typedef.Y_VARCHAR, typedef.Y_NCHAR, typedef.Y_NVARCHAR, typedef.Y_CLOB:
+		// 字符类型按MySQL中的文本写入, 时间类型不转换为time.Time
+		switch v := value.(type) {
+		case []uint8:
+			if columnType == "BIT" {
+				return strconv.Itoa(uint8SliceToInt(v)), nil
+			}
+			if columnType == "GEOMETRY" {
+				if geometry, err := decodeMySQLGeometry(v); err == nil {
+					return geometry.WKT, nil
+				}
+			}
+			return string(v), nil
+		default:
+			return fmt.Sprint(v), nil
+		}
+	}
+	return convertValueFromMySQLToYashan(value, columnType)
+}
+
+// convertToMySQLTypeWithMapping 将MySQL的值转换为校验时比较的值, 映射为boolean和字符类型的列与同步时写入的值相同,
+// mappedType为空时与convertToMySQLType相同
+func convertToMySQLTypeWithMapping(value interface{}, columnType, mappedType string) interface{} {
+	if value == nil || len(mappedType) == 0 {
+		return convertToMySQLType(value, columnType)
+	}
+	switch getYashanTypeBaseName(mappedType) {
+	case typedef.Y_BOOLEAN, typedef.Y_CHAR, typedef.Y_VARCHAR, typedef.Y_NCHAR, typedef.Y_NVARCHAR, typedef.Y_CLOB:
+		if converted, err := convertValueWithTypeMapping(value, columnType, mappedType); err == nil {
+			return converted
+		}
+	}
+	return convertToMySQLType(value, columnType)
+}
+
+// convertValueToBoolean 非0的数值转换为true, 字符串按数字解析
+func convertValueToBoolean(value interface{}, columnType string) (interface{}, error) {
+	switch v := value.(type) {
+	case []uint8:
+		if columnType == "BIT" {
+			return uint8SliceToInt(v) != 0, nil
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(string(v)), 64)
+		if err != nil {
+			return strings.EqualFold(strings.TrimSpace(string(v)), "true"), nil
+		}
+		return f != 0, nil
+	case bool:
+		return v, nil
+	case int64:
+		return v != 0, nil
+	case uint64:
+		return v != 0, nil
+	case float64:
+		return v != 0, nil
+	}
+	return convertValueFromMySQLToYashan(value, columnType)
+}
@@ -0,0 +1,41 @@
+package modules
+
+import "testing"
+
+// TestConvertToMySQLTypeWithMapping 映射为boolean和字符类型的列, 校验时MySQL的值与YashanDB中同步写入的值相等
+func TestConvertToMySQLTypeWithMapping(t *testing.T) {
+	cases := []struct {
+		columnType string
+		mappedType string
+		value      interface{}
+		yashan     interface{} // YashanDB返回的值
+		equal      bool
+	}{
+		{columnType: "TINYINT", mappedType: "boolean", value: []byte("1"), yashan: true, equal: true},
+		{columnType: "TINYINT", mappedType: "boolean", value: []byte("0"), yashan: false, equal: true},
+		{columnType: "TINYINT", mappedType: "boolean", value: []byte("-1"), yashan: true, equal: true},
+		{columnType: "TINYINT", mappedType: "boolean", value: []byte("1"), yashan: false, equal: false},
+		{columnType: "TINYINT", mappedType: "boolean", value: []byte("1"), yashan: int64(1), equal: true},
+		{columnType: "TINYINT", mappedType: "boolean", value: []byte("0"), yashan: "false", equal: true},
+		{columnType: "BIT", mappedType: "boolean", value: []byte{0x01}, yashan: true, equal: true},
+		{columnType: "TINYINT", mappedType: "boolean", value: nil, yashan: nil, equal: true},
+		{columnType: "UNSIGNED INT", mappedType: "varchar(10)", value: []byte("0000000007"), yashan: "0000000007", equal: true},
+		{columnType: "UNSIGNED INT", mappedType: "varchar(10)", value: []byte("0000000007"), yashan: "7", equal: false},
+		{columnType: "TIME", mappedType: "varchar(20)", value: []byte("34:00:00"), yashan: "34:00:00", equal: true},
+		{columnType: "DATETIME", mappedType: "char(19)", value: []byte("2024-02-29 23:59:59"), yashan: "2024-02-29 23:59:59", equal: true},
+		{columnType: "BIT", mappedType: "varchar(8)", value: []byte{0x02, 0xAA}, yashan: "682", equal: true},
+		{columnType: "GEOMETRY", mappedType: "clob", value: mysqlPointValue(0, 1, 2), yashan: "POINT(1 2)", equal: true},
+		{columnType: "DECIMAL", mappedType: "nvarchar(20)", value: []byte("1.50"), yashan: "1.50", equal: true},
+		// 其他映射按MySQL的类型比较
+		{columnType: "INT", mappedType: "integer", value: []byte("42"), yashan: int64(42), equal: true},
+		{columnType: "TIME", mappedType: "interval day to second", value: []byte("34:00:00"), yashan: "+01 10:00:00.000000", equal: true},
+		{columnType: "TINYINT", mappedType: "", value: []byte("1"), yashan: int64(1), equal: true},
+	}
+	for _, c := range cases {
+		converted := convertToMySQLTypeWithMapping(c.value, c.columnType, c.mappedType)
+		if isDataEqual(converted, c.yashan) != c.equal {
+			t.Errorf("%s映射为%q, MySQL的值 %q 转换为 %s, 与YashanDB的值 %s 比较的结果不是 %v",
+				c.columnType, c.mappedType, c.value, showValue(converted), showValue(c.yashan), c.equal)
+		}
+	}
+}
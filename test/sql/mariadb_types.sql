@@ -0,0 +1,16 @@
+-- MariaDB特有的列类型, 在mysql_all_types.sql之后执行, MariaDB 10.10及以上版本支持inet4, 10.7及以上版本支持uuid
+
+USE m2y_types;
+
+DROP TABLE IF EXISTS mariadb_types;
+CREATE TABLE mariadb_types (
+  id      INT PRIMARY KEY,
+  c_inet4 INET4,
+  c_inet6 INET6,
+  c_uuid  UUID
+);
+
+INSERT INTO mariadb_types VALUES
+  (1, '192.168.0.1', '2001:db8::ff00:42:8329', '123e4567-e89b-12d3-a456-426655440000'),
+  (2, '0.0.0.0', '::', '00000000-0000-0000-0000-000000000000'),
+  (3, NULL, NULL, NULL);
@@ -0,0 +1,105 @@
+-- 覆盖MySQL 5.7/8.0所有列类型的测试表, 用于验证export、sync、check的类型转换
+-- 用法: 在MySQL中执行本脚本, 将schemas配置为m2y_types, 依次执行export、sync、check, check的结果应全部一致
+-- MySQL 5.7不支持geomcollection和表达式默认值, 可以删除对应的列后执行
+
+CREATE DATABASE IF NOT EXISTS m2y_types;
+USE m2y_types;
+
+DROP TABLE IF EXISTS all_types;
+CREATE TABLE all_types (
+  id                  SERIAL PRIMARY KEY,
+  c_bool              BOOL,
+  c_tinyint           TINYINT,
+  c_tinyint_u         TINYINT UNSIGNED,
+  c_smallint          SMALLINT,
+  c_smallint_u        SMALLINT UNSIGNED,
+  c_mediumint         MEDIUMINT,
+  c_mediumint_u       MEDIUMINT UNSIGNED,
+  c_mediumint_uz      MEDIUMINT(8) UNSIGNED ZEROFILL,
+  c_int               INT,
+  c_int_u             INT UNSIGNED,
+  c_integer_z         INTEGER(10) ZEROFILL,
+  c_bigint            BIGINT,
+  c_bigint_u          BIGINT UNSIGNED,
+  c_decimal           DECIMAL(18, 4),
+  c_decimal_u         DECIMAL(10, 2) UNSIGNED,
+  c_numeric           NUMERIC(12, 3),
+  c_float             FLOAT,
+  c_float_u           FLOAT UNSIGNED,
+  c_double            DOUBLE,
+  c_double_u          DOUBLE UNSIGNED,
+  c_real              REAL,
+  c_bit               BIT(10),
+  c_date              DATE,
+  c_datetime          DATETIME,
+  c_timestamp         TIMESTAMP NULL,
+  c_time              TIME,
+  c_year              YEAR,
+  c_char              CHAR(10),
+  c_varchar           VARCHAR(100),
+  c_nchar             NATIONAL CHAR(10),
+  c_nvarchar          NATIONAL VARCHAR(100),
+  c_binary            BINARY(8),
+  c_varbinary         VARBINARY(64),
+  c_tinyblob          TINYBLOB,
+  c_tinytext          TINYTEXT,
+  c_blob              BLOB,
+  c_text              TEXT,
+  c_mediumblob        MEDIUMBLOB,
+  c_mediumtext        MEDIUMTEXT,
+  c_longblob          LONGBLOB,
+  c_longtext          LONGTEXT,
+  c_json              JSON,
+  c_enum              ENUM('small', 'medium', 'large'),
+  c_set               SET('a', 'b', 'c'),
+  c_geometry          GEOMETRY,
+  c_point             POINT,
+  c_linestring        LINESTRING,
+  c_polygon           POLYGON,
+  c_multipoint        MULTIPOINT,
+  c_multilinestring   MULTILINESTRING,
+  c_multipolygon      MULTIPOLYGON,
+  c_geomcollection    GEOMETRYCOLLECTION
+);
+
+INSERT INTO all_types (
+  c_bool, c_tinyint, c_tinyint_u, c_smallint, c_smallint_u, c_mediumint, c_mediumint_u, c_mediumint_uz,
+  c_int, c_int_u, c_integer_z, c_bigint, c_bigint_u, c_decimal, c_decimal_u, c_numeric,
+  c_float, c_float_u, c_double, c_double_u, c_real, c_bit,
+  c_date, c_datetime, c_timestamp, c_time, c_year,
+  c_char, c_varchar, c_nchar, c_nvarchar, c_binary, c_varbinary,
+  c_tinyblob, c_tinytext, c_blob, c_text, c_mediumblob, c_mediumtext, c_longblob, c_longtext,
+  c_json, c_enum, c_set,
+  c_geometry, c_point, c_linestring, c_polygon, c_multipoint, c_multilinestring, c_multipolygon, c_geomcollection
+) VALUES (
+  TRUE, -128, 255, -32768, 65535, -8388608, 16777215, 42,
+  -2147483648, 4294967295, 7, -9223372036854775808, 18446744073709551615, 12345678901234.5678, 99999999.99, 123456789.123,
+  1.5, 2.5, 3.141592653589793, 2.718281828459045, 1.0E10, b'1010101010',
+  '2024-02-29', '2024-02-29 23:59:59', '2024-02-29 12:00:00', '12:34:56', 2024,
+  'char', 'varchar', 'nchar', 'nvarchar', 0x0102030405060708, 0xDEADBEEF,
+  0x01, 'tinytext', 0x0203, 'text', 0x0405, 'mediumtext', 0x0607, 'longtext',
+  '{"key": "value"}', 'medium', 'a,c',
+  ST_GeomFromText('POINT(1 1)'), ST_GeomFromText('POINT(1 2)'), ST_GeomFromText('LINESTRING(0 0, 1 1, 2 2)'),
+  ST_GeomFromText('POLYGON((0 0, 4 0, 4 4, 0 4, 0 0))'), ST_GeomFromText('MULTIPOINT((0 0), (1 1))'),
+  ST_GeomFromText('MULTILINESTRING((0 0, 1 1), (2 2, 3 3))'),
+  ST_GeomFromText('MULTIPOLYGON(((0 0, 1 0, 1 1, 0 0)), ((2 2, 3 2, 3 3, 2 2)))'),
+  ST_GeomFromText('GEOMETRYCOLLECTION(POINT(1 1), LINESTRING(0 0, 1 1))')
+), (
+  FALSE, 127, 0, 32767, 0, 8388607, 0, 0,
+  2147483647, 0, 0, 9223372036854775807, 0, -12345678901234.5678, 0, -123456789.123,
+  -1.5, 0, -3.141592653589793, 0, -1.0E-10, b'0',
+  '1000-01-01', '1000-01-01 00:00:00', '1970-01-01 08:00:01', '-838:59:59', 1901,
+  '', '', '', '', 0x00, 0x,
+  0x, '', 0x, '', 0x, '', 0x, '',
+  '[]', 'small', '',
+  NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL
+), (
+  NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL,
+  NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL,
+  NULL, NULL, NULL, NULL, NULL, NULL,
+  NULL, NULL, NULL, NULL, NULL,
+  NULL, NULL, NULL, NULL, NULL, NULL,
+  NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL,
+  NULL, NULL, NULL,
+  NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL
+);
@@ -25,3 +25,24 @@ func RewriteFile(str string, filePath string) error {
 	}
 	return nil
 }
+
+// WriteFileAtomic 先写临时文件并同步到磁盘, 再重命名为fname, 进程或系统中断时fname保持原内容或新内容
+func WriteFileAtomic(fname string, data []byte) error {
+	tmpName := fname + ".tmp"
+	f, err := os.OpenFile(tmpName, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, DEFAULT_FILE_MODE)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpName, fname)
+}